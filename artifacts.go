@@ -0,0 +1,229 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Artifact describes a downloadable output file of a job
+type Artifact struct {
+	Name        string    `json:"name"` // path relative to the job's output dir, e.g. "video/final_video.mp4"
+	Type        string    `json:"type"` // video, audio, image, document, text or data
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"` // sha256:<hex>
+	ModifiedAt  time.Time `json:"modified_at"`
+	URL         string    `json:"url"`
+}
+
+// artifactTypes lists the file extensions exposed as artifacts; build
+// intermediates (LaTeX aux files, ffmpeg list files, chunk WAVs) are hidden
+var artifactTypes = map[string]struct{ kind, contentType string }{
+	".mp4":  {"video", "video/mp4"},
+	".wav":  {"audio", "audio/wav"},
+	".png":  {"image", "image/png"},
+	".jpg":  {"image", "image/jpeg"},
+	".pdf":  {"document", "application/pdf"},
+	".tex":  {"document", "application/x-tex"},
+	".txt":  {"text", "text/plain; charset=utf-8"},
+	".srt":  {"text", "application/x-subrip"},
+	".vtt":  {"text", "text/vtt"},
+	".json": {"data", "application/json"},
+}
+
+var hiddenArtifactDirs = map[string]bool{
+	"temp_chunks": true,
+}
+
+func isHiddenArtifact(name string) bool {
+	base := path.Base(name)
+	return strings.HasSuffix(base, "_demux.txt") ||
+		strings.HasSuffix(base, "_list.txt") ||
+		base == "concat_list.txt"
+}
+
+type checksumEntry struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+// checksumCache avoids rehashing large videos on every listing
+var checksumCache = struct {
+	entries map[string]checksumEntry
+	mu      sync.Mutex
+}{entries: make(map[string]checksumEntry)}
+
+func fileChecksum(filePath string, info fs.FileInfo) (string, error) {
+	checksumCache.mu.Lock()
+	entry, ok := checksumCache.entries[filePath]
+	checksumCache.mu.Unlock()
+	if ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.sum, nil
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	sum := "sha256:" + hex.EncodeToString(h.Sum(nil))
+
+	checksumCache.mu.Lock()
+	checksumCache.entries[filePath] = checksumEntry{size: info.Size(), modTime: info.ModTime(), sum: sum}
+	checksumCache.mu.Unlock()
+	return sum, nil
+}
+
+// ListArtifacts walks a job's output directory and returns its deliverable files
+func ListArtifacts(jobID, outputDir string) ([]Artifact, error) {
+	var artifacts []Artifact
+
+	err := filepath.WalkDir(outputDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if hiddenArtifactDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(outputDir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		t, ok := artifactTypes[strings.ToLower(path.Ext(name))]
+		if !ok || isHiddenArtifact(name) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sum, err := fileChecksum(p, info)
+		if err != nil {
+			return err
+		}
+
+		artifacts = append(artifacts, Artifact{
+			Name:        name,
+			Type:        t.kind,
+			ContentType: t.contentType,
+			Size:        info.Size(),
+			Checksum:    sum,
+			ModifiedAt:  info.ModTime(),
+			URL:         fmt.Sprintf("/jobs/%s/artifacts/%s", jobID, name),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Name < artifacts[j].Name })
+	return artifacts, nil
+}
+
+// completedJob looks up a job and checks its artifacts are ready to serve
+func (s *Server) completedJob(w http.ResponseWriter, jobID string) (*JobStatus, bool) {
+	status, ok := s.pool.GetStatus(jobID)
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return nil, false
+	}
+	if status.Status != "completed" {
+		http.Error(w, "Job is "+status.Status+"; artifacts are available once it completes", http.StatusConflict)
+		return nil, false
+	}
+	return status, true
+}
+
+func (s *Server) handleListArtifacts(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	status, ok := s.completedJob(w, jobID)
+	if !ok {
+		return
+	}
+
+	artifacts, err := ListArtifacts(jobID, status.OutputDir)
+	if err != nil {
+		http.Error(w, "Failed to list artifacts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job_id":    jobID,
+		"artifacts": artifacts,
+	})
+}
+
+func (s *Server) handleDownloadArtifact(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	status, ok := s.completedJob(w, jobID)
+	if !ok {
+		return
+	}
+
+	// Reject anything that could escape the output directory
+	name := r.PathValue("name")
+	if name == "" || !fs.ValidPath(name) {
+		http.Error(w, "Invalid artifact name", http.StatusBadRequest)
+		return
+	}
+	t, ok := artifactTypes[strings.ToLower(path.Ext(name))]
+	if !ok || isHiddenArtifact(name) || hiddenArtifactDirs[path.Base(path.Dir(name))] {
+		http.Error(w, "Artifact not found", http.StatusNotFound)
+		return
+	}
+
+	filePath := filepath.Join(status.OutputDir, filepath.FromSlash(name))
+	f, err := os.Open(filePath)
+	if err != nil {
+		http.Error(w, "Artifact not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.Error(w, "Artifact not found", http.StatusNotFound)
+		return
+	}
+
+	if sum, err := fileChecksum(filePath, info); err == nil {
+		w.Header().Set("ETag", `"`+sum+`"`)
+	}
+	w.Header().Set("Content-Type", t.contentType)
+	if t.kind != "video" && t.kind != "audio" && t.kind != "image" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(name)))
+	}
+
+	// Large videos on slow links can outlast the server's WriteTimeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	// ServeContent handles Range and conditional requests, so browsers can seek in videos
+	http.ServeContent(w, r, path.Base(name), info.ModTime(), f)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArtifacts(t *testing.T) {
+	outputDir := t.TempDir()
+	for name, content := range map[string]string{
+		"video/final_video.mp4":                    "0123456789",
+		"video/concat_list.txt":                    "file 'a.mp4'",
+		"audio/intro.wav":                          "RIFF",
+		"audio/temp_chunks/intro_chunk_000.wav":    "RIFF",
+		"reel/clips_demux.txt":                     "file 'a.mp4'",
+		"reel/turns_list.txt":                      "file 'a.wav'",
+		"slides/main.aux":                          `\relax`,
+		"script.json":                              "{}",
+		"subtitles.srt":                            "1",
+		"../" + filepath.Base(outputDir) + ".json": "{}",
+	} {
+		path := filepath.Join(outputDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	store := NewMemoryJobStore()
+	store.Save(&JobStatus{ID: "done", Status: "completed", Mode: "video", OutputDir: outputDir})
+	store.Save(&JobStatus{ID: "running", Status: "processing", Mode: "video", OutputDir: outputDir})
+	s := &Server{pool: NewWorkerPool(0, 1, store)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jobs/{id}/artifacts", s.handleListArtifacts)
+	mux.HandleFunc("GET /jobs/{id}/artifacts/{name...}", s.handleDownloadArtifact)
+
+	get := func(path string, header http.Header, wantCode int) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != wantCode {
+			t.Fatalf("GET %s: got %d (%s), want %d", path, w.Code, w.Body, wantCode)
+		}
+		return w
+	}
+
+	// Only deliverables are listed, not build intermediates
+	var listing struct{ Artifacts []Artifact }
+	json.NewDecoder(get("/jobs/done/artifacts", nil, 200).Body).Decode(&listing)
+	var names []string
+	for _, a := range listing.Artifacts {
+		names = append(names, a.Name)
+	}
+	if got := strings.Join(names, ", "); got != "audio/intro.wav, script.json, subtitles.srt, video/final_video.mp4" {
+		t.Errorf("unexpected artifacts %s", got)
+	}
+	if a := listing.Artifacts[3]; a.Type != "video" || a.Size != 10 || !strings.HasPrefix(a.Checksum, "sha256:") || a.URL != "/jobs/done/artifacts/video/final_video.mp4" {
+		t.Errorf("unexpected video artifact %+v", a)
+	}
+	get("/jobs/running/artifacts", nil, 409)
+	get("/jobs/missing/artifacts", nil, 404)
+
+	// Range requests let players seek
+	w := get("/jobs/done/artifacts/video/final_video.mp4", http.Header{"Range": {"bytes=2-5"}}, 206)
+	if w.Body.String() != "2345" || w.Header().Get("Content-Range") != "bytes 2-5/10" || w.Header().Get("Content-Type") != "video/mp4" {
+		t.Errorf("unexpected range response %q %v", w.Body, w.Header())
+	}
+	etag := get("/jobs/done/artifacts/script.json", nil, 200).Header().Get("ETag")
+	get("/jobs/done/artifacts/script.json", http.Header{"If-None-Match": {etag}}, 304)
+
+	for _, hidden := range []string{"audio/temp_chunks/intro_chunk_000.wav", "video/concat_list.txt", "reel/clips_demux.txt", "reel/turns_list.txt", "slides/main.aux", "video"} {
+		get("/jobs/done/artifacts/"+hidden, nil, 404)
+	}
+
+	// Names that could leave the output dir are rejected outright
+	for _, name := range []string{"../" + filepath.Base(outputDir) + ".json", "video/../../x.json", "/etc/passwd.txt", "video//final_video.mp4"} {
+		r := httptest.NewRequest("GET", "/jobs/done/artifacts/x", nil)
+		r.SetPathValue("id", "done")
+		r.SetPathValue("name", name)
+		w := httptest.NewRecorder()
+		s.handleDownloadArtifact(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("artifact name %q: got %d, want 400", name, w.Code)
+		}
+	}
+}
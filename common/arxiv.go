@@ -0,0 +1,203 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultArxivBaseURL serves the arXiv API, PDFs and e-print sources.
+// arXiv asks automated clients to use the export mirror.
+const DefaultArxivBaseURL = "https://export.arxiv.org"
+
+// ArxivClient fetches papers and their metadata from arXiv
+type ArxivClient struct {
+	BaseURL string
+	client  *http.Client
+}
+
+// ArxivPaper is a fetched arXiv paper
+type ArxivPaper struct {
+	ID         string
+	PDFPath    string
+	SourcePath string // LaTeX source archive, empty unless requested
+	Metadata   *PaperMetadata
+}
+
+// NewArxivClient creates a client; an empty baseURL uses DefaultArxivBaseURL
+func NewArxivClient(baseURL string) *ArxivClient {
+	if baseURL == "" {
+		baseURL = DefaultArxivBaseURL
+	}
+	return &ArxivClient{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+var (
+	arxivNewIDRe = regexp.MustCompile(`^\d{4}\.\d{4,5}(v\d+)?$`)
+	arxivOldIDRe = regexp.MustCompile(`^[a-z-]+(\.[A-Z]{2})?/\d{7}(v\d+)?$`)
+)
+
+// ParseArxivID extracts the arXiv identifier from an ID such as
+// "2301.01234v2", "arXiv:2301.01234" or "hep-th/9901001", or from an
+// arxiv.org abs or pdf URL
+func ParseArxivID(input string) (string, bool) {
+	id := strings.TrimSpace(input)
+	if u, err := url.Parse(id); err == nil && u.Host != "" {
+		if !strings.HasSuffix(u.Hostname(), "arxiv.org") {
+			return "", false
+		}
+		id = strings.TrimPrefix(u.Path, "/")
+		for _, prefix := range []string{"abs/", "pdf/", "e-print/"} {
+			id = strings.TrimPrefix(id, prefix)
+		}
+		id = strings.TrimSuffix(id, ".pdf")
+	} else if len(id) > 6 && strings.EqualFold(id[:6], "arxiv:") {
+		id = id[6:]
+	}
+
+	if arxivNewIDRe.MatchString(id) || arxivOldIDRe.MatchString(id) {
+		return id, true
+	}
+	return "", false
+}
+
+// Fetch downloads the PDF (and the LaTeX source if withSource) of the paper
+// into dir, named with prefix, and fetches its metadata
+func (c *ArxivClient) Fetch(ctx context.Context, id, dir, prefix string, withSource bool) (*ArxivPaper, error) {
+	metadata, err := c.FetchMetadata(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Old-style IDs contain a slash
+	base := filepath.Join(dir, prefix+strings.ReplaceAll(id, "/", "_"))
+	paper := &ArxivPaper{ID: id, PDFPath: base + ".pdf", Metadata: metadata}
+
+	if err := c.download(ctx, "/pdf/"+id, paper.PDFPath, []byte("%PDF-")); err != nil {
+		return nil, fmt.Errorf("failed to download arXiv PDF: %w", err)
+	}
+	if withSource {
+		paper.SourcePath = base + ".tar.gz"
+		if err := c.download(ctx, "/e-print/"+id, paper.SourcePath, nil); err != nil {
+			return nil, fmt.Errorf("failed to download arXiv source: %w", err)
+		}
+	}
+	return paper, nil
+}
+
+type arxivFeed struct {
+	Entries []struct {
+		ID        string `xml:"id"`
+		Title     string `xml:"title"`
+		Published string `xml:"published"`
+		Journal   string `xml:"http://arxiv.org/schemas/atom journal_ref"`
+		Authors   []struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+	} `xml:"entry"`
+}
+
+// FetchMetadata returns the canonical title, authors and publication date
+func (c *ArxivClient) FetchMetadata(ctx context.Context, id string) (*PaperMetadata, error) {
+	body, err := c.get(ctx, "/api/query?id_list="+url.QueryEscape(id))
+	if err != nil {
+		return nil, fmt.Errorf("arXiv metadata request failed: %w", err)
+	}
+	defer body.Close()
+
+	var feed arxivFeed
+	if err := xml.NewDecoder(body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("invalid arXiv metadata: %w", err)
+	}
+	// Unknown IDs come back as an entry pointing at the API error page
+	if len(feed.Entries) == 0 || strings.Contains(feed.Entries[0].ID, "/api/errors") {
+		return nil, fmt.Errorf("arXiv paper %s not found", id)
+	}
+
+	entry := feed.Entries[0]
+	var authors []string
+	for _, a := range entry.Authors {
+		authors = append(authors, strings.TrimSpace(a.Name))
+	}
+	metadata := &PaperMetadata{
+		Title:   strings.Join(strings.Fields(entry.Title), " "),
+		Authors: strings.Join(authors, ", "),
+	}
+	if len(entry.Published) >= 10 {
+		metadata.Date = entry.Published[:10]
+	}
+	metadata.Venue = strings.Join(strings.Fields(entry.Journal), " ")
+	if err := metadata.Validate(); err != nil {
+		return nil, fmt.Errorf("incomplete arXiv metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// download saves path to dest, checking the body starts with magic if given
+func (c *ArxivClient) download(ctx context.Context, path, dest string, magic []byte) error {
+	body, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	tmp := dest + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	// arXiv answers with an HTML page while a PDF is still being generated
+	head := make([]byte, len(magic))
+	n, _ := io.ReadFull(body, head)
+	if !bytes.Equal(head[:n], magic) {
+		f.Close()
+		return fmt.Errorf("unexpected content from %s", path)
+	}
+	if _, err := f.Write(head[:n]); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+func (c *ArxivClient) get(ctx context.Context, path string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "saral_go_testing (paper to video/poster/reel)")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,76 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestParseArxivID(t *testing.T) {
+	for input, want := range map[string]string{
+		"2301.01234":                           "2301.01234",
+		"arXiv:2301.01234v2":                   "2301.01234v2",
+		"hep-th/9901001":                       "hep-th/9901001",
+		"https://arxiv.org/abs/2301.01234":     "2301.01234",
+		"https://arxiv.org/pdf/2301.01234.pdf": "2301.01234",
+		"paper.pdf":                            "",
+		"https://example.com/abs/2301.01234":   "",
+	} {
+		if got, _ := ParseArxivID(input); got != want {
+			t.Errorf("ParseArxivID(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestArxivFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/query":
+			if r.URL.Query().Get("id_list") != "2301.01234" {
+				w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>http://arxiv.org/api/errors#bad_id</id><title>Error</title></entry></feed>`))
+				return
+			}
+			w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom" xmlns:arxiv="http://arxiv.org/schemas/atom"><entry>
+  <id>http://arxiv.org/abs/2301.01234v1</id>
+  <published>2023-01-03T18:00:00Z</published>
+  <title>A Paper
+    Split Over Lines</title>
+  <author><name>Ada Lovelace</name></author>
+  <author><name>Alan Turing</name></author>
+  <arxiv:journal_ref>Proc. Analytical
+    Engines 2023</arxiv:journal_ref>
+</entry></feed>`))
+		case "/pdf/2301.01234":
+			w.Write([]byte("%PDF-1.4 fake"))
+		case "/e-print/2301.01234":
+			w.Write([]byte("source archive"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewArxivClient(srv.URL)
+	dir := t.TempDir()
+	paper, err := client.Fetch(context.Background(), "2301.01234", dir, "job_", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := PaperMetadata{Title: "A Paper Split Over Lines", Authors: "Ada Lovelace, Alan Turing", Date: "2023-01-03", Venue: "Proc. Analytical Engines 2023"}
+	if *paper.Metadata != want {
+		t.Errorf("unexpected metadata %+v", paper.Metadata)
+	}
+	if data, err := os.ReadFile(paper.PDFPath); err != nil || string(data) != "%PDF-1.4 fake" {
+		t.Errorf("unexpected PDF %q (err %v)", data, err)
+	}
+	if _, err := os.Stat(paper.SourcePath); err != nil {
+		t.Errorf("source not downloaded: %v", err)
+	}
+
+	if _, err := client.Fetch(context.Background(), "2301.99999", dir, "job_", false); err == nil {
+		t.Error("expected an error for an unknown ID")
+	}
+}
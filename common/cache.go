@@ -0,0 +1,228 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache is an on-disk, content-addressed store for expensive API responses
+// (LLM completions, TTS audio). Entries are keyed by a hash of everything that
+// influences the response, expire after TTL and are evicted least recently
+// used first once the cache grows past MaxBytes. The directory can be shared
+// by several processes. A nil *Cache is valid and caches nothing.
+type Cache struct {
+	Dir      string
+	MaxBytes int64         // 0 means unlimited
+	TTL      time.Duration // 0 means entries never expire
+
+	size int64 // approximate bytes on disk, refreshed on each eviction pass
+	mu   sync.Mutex
+}
+
+// cacheHeaderLen is the length of the creation timestamp stored before each
+// entry; the file mtime is bumped on reads and tracks last use instead
+const cacheHeaderLen = 8
+
+// NewCache opens (creating if needed) a cache rooted at dir
+func NewCache(dir string, maxBytes int64, ttl time.Duration) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	c := &Cache{Dir: dir, MaxBytes: maxBytes, TTL: ttl}
+
+	c.mu.Lock()
+	c.evictLocked()
+	c.mu.Unlock()
+	return c, nil
+}
+
+// CacheKey hashes the parts that determine a response, e.g. provider, model,
+// prompt, voice and sample rate. Parts are length-prefixed so ("ab","c") and
+// ("a","bc") produce different keys.
+func CacheKey(parts ...string) string {
+	h := sha256.New()
+	var n [8]byte
+	for _, p := range parts {
+		binary.BigEndian.PutUint64(n[:], uint64(len(p)))
+		h.Write(n[:])
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key[:2], key)
+}
+
+// Get returns the cached value for key, if present and not expired
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) < cacheHeaderLen {
+		return nil, false
+	}
+
+	created := time.Unix(0, int64(binary.BigEndian.Uint64(data[:cacheHeaderLen])))
+	if c.TTL > 0 && time.Since(created) > c.TTL {
+		os.Remove(path)
+		return nil, false
+	}
+
+	// Mark as recently used for LRU eviction
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data[cacheHeaderLen:], true
+}
+
+// Put stores value under key, evicting old entries if the cache is over its size limit
+func (c *Cache) Put(key string, value []byte) error {
+	if c == nil {
+		return nil
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data := make([]byte, cacheHeaderLen+len(value))
+	binary.BigEndian.PutUint64(data, uint64(time.Now().UnixNano()))
+	copy(data[cacheHeaderLen:], value)
+
+	// Write to a unique temp file and rename, so concurrent writers and
+	// readers in other processes never see a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	c.size += int64(len(data))
+	if c.MaxBytes > 0 && c.size > c.MaxBytes {
+		c.evictLocked()
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// GetFile copies a cached value to outputPath, returning false on a miss
+func (c *Cache) GetFile(key, outputPath string) bool {
+	data, ok := c.Get(key)
+	if !ok {
+		return false
+	}
+	return os.WriteFile(outputPath, data, 0644) == nil
+}
+
+// PutFile stores the contents of a file under key
+func (c *Cache) PutFile(key, path string) error {
+	if c == nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return c.Put(key, data)
+}
+
+type cacheEntry struct {
+	path    string
+	size    int64
+	lastUse time.Time
+}
+
+// evictLocked removes expired entries, then the least recently used ones until
+// the cache is back under 90% of MaxBytes
+func (c *Cache) evictLocked() {
+	var entries []cacheEntry
+	var total int64
+
+	filepath.WalkDir(c.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		// Leftovers from writers that crashed mid-write
+		if strings.HasSuffix(path, ".tmp") {
+			if time.Since(info.ModTime()) > time.Hour {
+				os.Remove(path)
+			}
+			return nil
+		}
+
+		if c.TTL > 0 && c.expired(path) {
+			os.Remove(path)
+			return nil
+		}
+
+		entries = append(entries, cacheEntry{path: path, size: info.Size(), lastUse: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+
+	if c.MaxBytes > 0 && total > c.MaxBytes {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].lastUse.Before(entries[j].lastUse) })
+
+		target := c.MaxBytes * 9 / 10
+		evicted := 0
+		for _, e := range entries {
+			if total <= target {
+				break
+			}
+			if os.Remove(e.path) == nil {
+				total -= e.size
+				evicted++
+			}
+		}
+		log.Printf("Cache: evicted %d entries, %d bytes in use", evicted, total)
+	}
+
+	c.size = total
+}
+
+func (c *Cache) expired(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	var header [cacheHeaderLen]byte
+	if _, err := f.Read(header[:]); err != nil {
+		return true
+	}
+	created := time.Unix(0, int64(binary.BigEndian.Uint64(header[:])))
+	return time.Since(created) > c.TTL
+}
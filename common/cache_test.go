@@ -0,0 +1,90 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheGetPut(t *testing.T) {
+	c, err := NewCache(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := CacheKey("gemini", "model", "prompt")
+	if _, ok := c.Get(key); ok {
+		t.Fatal("hit on an empty cache")
+	}
+	if err := c.Put(key, []byte("response")); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := c.Get(key); !ok || string(data) != "response" {
+		t.Errorf("got %q, %v", data, ok)
+	}
+	if CacheKey("ab", "c") == CacheKey("a", "bc") {
+		t.Error("keys of different parts collide")
+	}
+
+	out := filepath.Join(t.TempDir(), "audio.wav")
+	if c.GetFile(CacheKey("missing"), out) {
+		t.Error("GetFile hit for a missing key")
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("GetFile wrote a file on a miss")
+	}
+	if !c.GetFile(key, out) {
+		t.Fatal("GetFile missed a cached key")
+	}
+	if data, _ := os.ReadFile(out); string(data) != "response" {
+		t.Errorf("GetFile wrote %q", data)
+	}
+
+	var nilCache *Cache
+	if err := nilCache.Put(key, []byte("x")); err != nil || nilCache.GetFile(key, out) {
+		t.Error("nil cache should cache nothing")
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c, _ := NewCache(t.TempDir(), 0, 10*time.Millisecond)
+	key := CacheKey("tts", "hello")
+	c.Put(key, []byte("audio"))
+	if _, ok := c.Get(key); !ok {
+		t.Fatal("fresh entry missed")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get(key); ok {
+		t.Error("expired entry returned")
+	}
+	if _, err := os.Stat(c.path(key)); !os.IsNotExist(err) {
+		t.Error("expired entry left on disk")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	// Each entry is 8 header bytes and 100 of value, so two fit and a third
+	// evicts the least recently used
+	c, _ := NewCache(t.TempDir(), 250, 0)
+	value := make([]byte, 100)
+	a, b, d := CacheKey("a"), CacheKey("b"), CacheKey("d")
+	c.Put(a, value)
+	c.Put(b, value)
+	os.Chtimes(c.path(a), time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour))
+	os.Chtimes(c.path(b), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+
+	// Reading a makes b the oldest
+	if _, ok := c.Get(a); !ok {
+		t.Fatal("entry a missed")
+	}
+	c.Put(d, value)
+
+	if _, ok := c.Get(b); ok {
+		t.Error("least recently used entry b not evicted")
+	}
+	for name, key := range map[string]string{"a": a, "d": d} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("entry %s evicted", name)
+		}
+	}
+}
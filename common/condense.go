@@ -0,0 +1,181 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultMaxInputTokens is the paper size above which CondensePaper
+// summarizes the text before it is used in a prompt
+const DefaultMaxInputTokens = 16000
+
+const (
+	condenseChunkTokens = 4000 // size of each map step input
+	condenseParallelism = 3    // concurrent map step requests
+	condenseMaxRounds   = 3    // reduce rounds before falling back to truncation
+)
+
+// EstimateTokens approximates the token count of text without an API call:
+// about four bytes per token for ASCII and one token per other rune, which
+// errs on the high side for Indic scripts
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// TruncateUTF8 shortens text to at most maxBytes without splitting a rune
+func TruncateUTF8(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	for maxBytes > 0 && !utf8.RuneStart(text[maxBytes]) {
+		maxBytes--
+	}
+	return text[:maxBytes]
+}
+
+// ChunkText splits text into chunks of at most maxTokens as measured by
+// count, breaking at line boundaries where possible, then at sentences,
+// then between runes
+func ChunkText(text string, maxTokens int, count func(string) int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+		currentTokens = 0
+	}
+	add := func(piece string, tokens int) {
+		if currentTokens+tokens > maxTokens {
+			flush()
+		}
+		current.WriteString(piece)
+		currentTokens += tokens
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		tokens := count(line)
+		if tokens <= maxTokens {
+			add(line, tokens)
+			continue
+		}
+		for _, sentence := range splitOnSentences(line) {
+			sentence += " "
+			tokens := count(sentence)
+			if tokens <= maxTokens {
+				add(sentence, tokens)
+				continue
+			}
+			for _, r := range sentence {
+				add(string(r), count(string(r)))
+			}
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// CondensePaper returns text unchanged if it fits the input budget, and
+// otherwise map-reduces it: each chunk is condensed separately, in order,
+// and the joined summaries are condensed again until they fit.
+func (h llmHelpers) CondensePaper(ctx context.Context, text string) (string, error) {
+	budget := h.maxInputTokens
+	if budget <= 0 {
+		budget = DefaultMaxInputTokens
+	}
+
+	for round := 0; ; round++ {
+		total := h.tokens(ctx, text)
+		if total <= budget {
+			return text, nil
+		}
+		if round == condenseMaxRounds {
+			log.Printf("Warning: paper still has ~%d tokens after %d condense rounds, truncating", total, round)
+			return TruncateUTF8(text, len(text)*budget/total), nil
+		}
+
+		// Scale the local estimate to the provider's count so chunks match its tokenizer
+		ratio := float64(total) / float64(max(EstimateTokens(text), 1))
+		count := func(s string) int { return int(float64(EstimateTokens(s))*ratio) + 1 }
+		chunks := ChunkText(text, condenseChunkTokens, count)
+		log.Printf("Condensing ~%d tokens in %d chunks (round %d)", total, len(chunks), round+1)
+
+		// Each summary gets an equal share of the budget, in words (~0.75 per token)
+		words := budget * 3 / 4 / len(chunks)
+		summaries, err := h.condenseChunks(ctx, chunks, words)
+		if err != nil {
+			return "", err
+		}
+		text = strings.Join(summaries, "\n\n")
+	}
+}
+
+// condenseChunks runs the map step, keeping the summaries in chunk order
+func (h llmHelpers) condenseChunks(ctx context.Context, chunks []string, words int) ([]string, error) {
+	summaries := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, condenseParallelism)
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			prompt := fmt.Sprintf(`You are preparing a faithful condensed version of a long research paper.
+This is part %d of %d. Condense it to at most %d words.
+
+Keep section headings, the problem statement, method details, datasets, every
+reported number and result, and the conclusions. Do not add information that is
+not in the text and do not comment on the text. Write plain prose.
+
+Text:
+%s`, i+1, len(chunks), words, chunk)
+
+			summaries[i], errs[i] = h.generate(ctx, prompt)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("condensing part %d of %d: %w", i+1, len(chunks), err)
+		}
+	}
+	return summaries, nil
+}
+
+// tokens counts text with the provider's tokenizer when it has one
+func (h llmHelpers) tokens(ctx context.Context, text string) int {
+	if h.countTokens != nil {
+		n, err := h.countTokens(ctx, text)
+		if err == nil {
+			return n
+		}
+		log.Printf("Warning: token count failed, estimating: %v", err)
+	}
+	return EstimateTokens(text)
+}
@@ -0,0 +1,580 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// StructuredDocument is the reading-order content of a paper with page
+// furniture (headers, footers, page numbers, footnotes) removed
+type StructuredDocument struct {
+	Title    string       `json:"title"`
+	Authors  string       `json:"authors,omitempty"` // only known for LaTeX sources
+	Abstract string       `json:"abstract"`
+	Sections []DocSection `json:"sections"`
+	Figures  []DocFigure  `json:"figures"`
+}
+
+// DocSection is a heading and the body text that follows it. Text before
+// the first heading is kept in a section with an empty heading.
+type DocSection struct {
+	Heading string `json:"heading"`
+	Text    string `json:"text"`
+}
+
+// DocFigure is a detected picture or table with its caption
+type DocFigure struct {
+	Page    int     `json:"page"`
+	Kind    string  `json:"kind"` // LayoutPicture or LayoutTable
+	Box     PdfRect `json:"box"`  // in PDF points
+	Caption string  `json:"caption,omitempty"`
+	Section int     `json:"section"`        // index into Sections, -1 before the first section
+	Path    string  `json:"path,omitempty"` // image file, the original for LaTeX sources or a crop for PDFs
+
+	Label string `json:"label,omitempty"` // number in the paper, e.g. "Figure 3", see NumberFigures
+}
+
+// TextLine is one line of page text with its position in PDF points
+type TextLine struct {
+	Text     string
+	Box      PdfRect
+	FontSize float64
+	Bold     bool
+}
+
+// Text renders the document as plain text for prompts, with headings on
+// their own lines and figure captions where the figures appear
+func (d *StructuredDocument) Text() string {
+	var sb strings.Builder
+	if d.Title != "" {
+		sb.WriteString(d.Title + "\n\n")
+	}
+	if d.Abstract != "" {
+		sb.WriteString("Abstract\n" + d.Abstract + "\n\n")
+	}
+
+	writeFigures := func(section int) {
+		for _, f := range d.Figures {
+			if f.Section == section && f.Caption != "" {
+				sb.WriteString("[" + f.Kind + ": " + f.Caption + "]\n\n")
+			}
+		}
+	}
+	writeFigures(-1)
+	for i, s := range d.Sections {
+		if s.Heading != "" {
+			sb.WriteString(s.Heading + "\n")
+		}
+		if s.Text != "" {
+			sb.WriteString(s.Text + "\n\n")
+		}
+		writeFigures(i)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// Metadata returns the title and authors, or nil if either is unknown
+func (d *StructuredDocument) Metadata() *PaperMetadata {
+	m := &PaperMetadata{Title: d.Title, Authors: d.Authors}
+	if m.Validate() != nil {
+		return nil
+	}
+	return m
+}
+
+// Headings are matched in this order, so "Results and Discussion" is Results
+var standardSectionKeywords = []struct {
+	section  string
+	keywords []string
+}{
+	{SecIntro, []string{"introduction", "background", "motivation", "related work"}},
+	{SecConclusion, []string{"conclusion", "future work", "summary"}},
+	{SecResults, []string{"result", "experiment", "evaluation", "benchmark", "ablation"}},
+	{SecDiscussion, []string{"discussion", "analysis", "limitation"}},
+	{SecMethod, []string{"method", "approach", "model", "architecture", "framework", "algorithm", "design", "implementation"}},
+}
+
+// StandardSection maps a paper's section heading to the video section
+// (SectionOrder) it belongs to, or "" if it matches none
+func StandardSection(heading string) string {
+	heading = strings.ToLower(heading)
+	for _, s := range standardSectionKeywords {
+		for _, k := range s.keywords {
+			if strings.Contains(heading, k) {
+				return s.section
+			}
+		}
+	}
+	return ""
+}
+
+// Back matter ends the standard section before it, so appendix figures
+// aren't taken for the conclusion
+var backMatterKeywords = []string{"reference", "bibliography", "appendix", "acknowledg", "supplementa"}
+
+// SectionFigures picks the first figure with an image file in each standard
+// video section. Figures belong to the standard section of the heading they
+// appear under; subheadings that match none ("4.2 Training Details")
+// continue the section before them, and the remaining figures are matched
+// by their caption.
+func (d *StructuredDocument) SectionFigures() map[string]DocFigure {
+	standard := make([]string, len(d.Sections))
+	current := ""
+	for i, s := range d.Sections {
+		if name := StandardSection(s.Heading); name != "" {
+			current = name
+		} else if heading := strings.ToLower(s.Heading); slices.ContainsFunc(backMatterKeywords, func(k string) bool {
+			return strings.Contains(heading, k)
+		}) {
+			current = ""
+		}
+		standard[i] = current
+	}
+
+	figures := make(map[string]DocFigure)
+	for _, f := range d.Figures {
+		if f.Path == "" {
+			continue
+		}
+		name := ""
+		if f.Section >= 0 {
+			name = standard[f.Section]
+		}
+		if name == "" {
+			name = StandardSection(f.Caption)
+		}
+		if _, taken := figures[name]; name != "" && !taken {
+			figures[name] = f
+		}
+	}
+	return figures
+}
+
+// SectionImages returns the image file of SectionFigures' figure for each
+// standard video section
+func (d *StructuredDocument) SectionImages() map[string]string {
+	images := make(map[string]string)
+	for name, f := range d.SectionFigures() {
+		images[name] = f.Path
+	}
+	return images
+}
+
+// ExtractLayoutText returns the layout-aware text of the paper, found with
+// the layout model load gives if it exists, falling back to ExtractText if
+// structured extraction fails or finds nothing
+func (p *PDFProcessor) ExtractLayoutText(ctx context.Context, load LayoutModelLoader, layout LayoutSettings) (string, error) {
+	var detector LayoutDetector
+	if _, err := os.Stat(layout.ModelPath); err == nil && load != nil {
+		model, err := load(layout)
+		if err != nil {
+			log.Printf("Warning: layout model unavailable, using font heuristics: %v", err)
+		} else {
+			defer model.Close()
+			detector = model
+		}
+	}
+
+	doc, err := p.ExtractStructured(ctx, detector)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if err != nil || len(doc.Sections) == 0 {
+		log.Printf("Warning: structured extraction failed (%v), using raw text", err)
+		return p.ExtractText()
+	}
+	return doc.Text(), nil
+}
+
+// ExtractStructured builds a StructuredDocument from the positioned text of
+// each page. Lines are classified by the layout region they fall in; with a
+// nil detector, font size and position heuristics are used instead.
+func (p *PDFProcessor) ExtractStructured(ctx context.Context, detector LayoutDetector) (*StructuredDocument, error) {
+	pages := make([][]TextLine, p.NumPages)
+	widths := make([]float64, p.NumPages)
+	heights := make([]float64, p.NumPages)
+	var sizes []float64
+
+	for i := 0; i < p.NumPages; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		lines, width, height, err := p.extractTextLines(i)
+		if err != nil {
+			return nil, err
+		}
+		pages[i], widths[i], heights[i] = lines, width, height
+		for _, l := range lines {
+			sizes = append(sizes, l.FontSize)
+		}
+	}
+	bodySize := median(sizes)
+
+	doc := &StructuredDocument{}
+	b := &documentBuilder{doc: doc}
+	for i, lines := range pages {
+		var regions []pageRegion
+		if detector != nil {
+			img, err := p.ExtractPageImage(i, 0)
+			if err != nil {
+				return nil, err
+			}
+			boxes, err := detector.DetectLayout(ctx, img)
+			if err != nil {
+				return nil, fmt.Errorf("layout detection failed on page %d: %w", i, err)
+			}
+			scale := widths[i] / float64(img.Bounds().Dx())
+			regions = regionsFromBoxes(lines, boxes, scale, heights[i])
+		} else {
+			regions = heuristicRegions(lines, i, bodySize, widths[i], heights[i])
+		}
+		b.addPage(i, orderRegions(regions, widths[i]))
+	}
+	b.finish()
+	return doc, nil
+}
+
+var (
+	htmlLineRe = regexp.MustCompile(`<p style="top:([\d.]+)pt;left:([\d.]+)pt;line-height:([\d.]+)pt">(.*?)</p>`)
+	fontSizeRe = regexp.MustCompile(`font-size:([\d.]+)pt`)
+	htmlTagRe  = regexp.MustCompile(`<[^>]*>`)
+)
+
+// extractTextLines reads a page's lines from MuPDF's positioned HTML output
+func (p *PDFProcessor) extractTextLines(pageNum int) ([]TextLine, float64, float64, error) {
+	p.Doc.mu.Lock()
+	defer p.Doc.mu.Unlock()
+
+	bounds, err := p.Doc.doc.Bound(pageNum)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("error reading page %d bounds: %w", pageNum, err)
+	}
+	page, err := p.Doc.doc.HTML(pageNum, false)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("error extracting text from page %d: %w", pageNum, err)
+	}
+	return parseHTMLLines(page), float64(bounds.Dx()), float64(bounds.Dy()), nil
+}
+
+func parseHTMLLines(page string) []TextLine {
+	var lines []TextLine
+	for _, m := range htmlLineRe.FindAllStringSubmatch(page, -1) {
+		top, _ := strconv.ParseFloat(m[1], 64)
+		left, _ := strconv.ParseFloat(m[2], 64)
+		height, _ := strconv.ParseFloat(m[3], 64)
+
+		text := strings.TrimSpace(html.UnescapeString(htmlTagRe.ReplaceAllString(m[4], "")))
+		if text == "" {
+			continue
+		}
+
+		size := 0.0
+		for _, fm := range fontSizeRe.FindAllStringSubmatch(m[4], -1) {
+			if s, _ := strconv.ParseFloat(fm[1], 64); s > size {
+				size = s
+			}
+		}
+		if size == 0 {
+			size = height
+		}
+
+		// MuPDF reports no line width; half an em per character is close enough for column detection
+		width := size * 0.5 * float64(utf8.RuneCountInString(text))
+		lines = append(lines, TextLine{
+			Text:     text,
+			Box:      PdfRect{X0: left, Y0: top, X1: left + width, Y1: top + height},
+			FontSize: size,
+			Bold:     strings.Contains(m[4], "<b>"),
+		})
+	}
+	return lines
+}
+
+// pageRegion is a classified block of lines on one page, in PDF points
+type pageRegion struct {
+	Class string
+	Box   PdfRect
+	Lines []TextLine
+}
+
+// regionsFromBoxes converts detections to points and assigns each line to
+// the smallest region containing its start. Lines outside every region
+// become their own Text region unless they sit in the page margins.
+func regionsFromBoxes(lines []TextLine, boxes []LayoutBox, scale, height float64) []pageRegion {
+	regions := make([]pageRegion, len(boxes))
+	for i, b := range boxes {
+		regions[i] = pageRegion{Class: b.Class, Box: PdfRect{
+			X0: float64(b.Rect.Min.X) * scale, Y0: float64(b.Rect.Min.Y) * scale,
+			X1: float64(b.Rect.Max.X) * scale, Y1: float64(b.Rect.Max.Y) * scale,
+		}}
+	}
+
+	var loose []pageRegion
+	for _, l := range lines {
+		x, y := l.Box.X0+1, (l.Box.Y0+l.Box.Y1)/2
+		best := -1
+		for i, r := range regions {
+			if x >= r.Box.X0 && x <= r.Box.X1 && y >= r.Box.Y0 && y <= r.Box.Y1 &&
+				(best < 0 || area(r.Box) < area(regions[best].Box)) {
+				best = i
+			}
+		}
+		switch {
+		case best >= 0:
+			regions[best].Lines = append(regions[best].Lines, l)
+		case inMargin(l.Box, height):
+			// Undetected page furniture
+		default:
+			loose = append(loose, pageRegion{Class: LayoutText, Box: l.Box, Lines: []TextLine{l}})
+		}
+	}
+	return append(regions, loose...)
+}
+
+var pageNumberRe = regexp.MustCompile(`(?i)^(page\s*)?\d+(\s*(of|/)\s*\d+)?$`)
+
+// heuristicRegions classifies each line by font size and position when no
+// layout model is available
+func heuristicRegions(lines []TextLine, pageNum int, bodySize, width, height float64) []pageRegion {
+	largest := 0.0
+	for _, l := range lines {
+		largest = max(largest, l.FontSize)
+	}
+
+	var regions []pageRegion
+	last := map[int]int{} // column -> index of its latest region
+	for _, l := range lines {
+		words := len(strings.Fields(l.Text))
+		class := LayoutText
+		switch {
+		case pageNumberRe.MatchString(l.Text) || (inMargin(l.Box, height) && words < 15):
+			class = LayoutPageFooter
+		case pageNum == 0 && l.FontSize == largest && l.FontSize > bodySize*1.3:
+			class = LayoutTitle
+		case words <= 12 && (l.FontSize >= bodySize*1.15 || (l.Bold && !strings.HasSuffix(l.Text, "."))):
+			class = LayoutSectionHeader
+		}
+
+		// Lines of the same class directly below each other in a column form one paragraph
+		column := columnOf(l.Box, width)
+		if i, ok := last[column]; ok && regions[i].Class == class && l.Box.Y0-regions[i].Box.Y1 < l.FontSize*0.8 {
+			regions[i].Lines = append(regions[i].Lines, l)
+			regions[i].Box = union(regions[i].Box, l.Box)
+			continue
+		}
+		last[column] = len(regions)
+		regions = append(regions, pageRegion{Class: class, Box: l.Box, Lines: []TextLine{l}})
+	}
+	return regions
+}
+
+// orderRegions sorts regions into reading order: full-width regions split
+// the page into bands, and within a band the left column is read before the
+// right one
+func orderRegions(regions []pageRegion, width float64) []pageRegion {
+	sort.SliceStable(regions, func(i, j int) bool { return regions[i].Box.Y0 < regions[j].Box.Y0 })
+
+	var ordered, left, right []pageRegion
+	flush := func() {
+		ordered = append(ordered, left...)
+		ordered = append(ordered, right...)
+		left, right = nil, nil
+	}
+	for _, r := range regions {
+		switch columnOf(r.Box, width) {
+		case 0:
+			flush()
+			ordered = append(ordered, r)
+		case 1:
+			left = append(left, r)
+		default:
+			right = append(right, r)
+		}
+	}
+	flush()
+
+	for i := range ordered {
+		lines := ordered[i].Lines
+		sort.SliceStable(lines, func(a, b int) bool {
+			if lines[a].Box.Y0 != lines[b].Box.Y0 {
+				return lines[a].Box.Y0 < lines[b].Box.Y0
+			}
+			return lines[a].Box.X0 < lines[b].Box.X0
+		})
+	}
+	return ordered
+}
+
+// columnOf returns 0 for full-width boxes, 1 for the left column and 2 for the right
+func columnOf(box PdfRect, width float64) int {
+	mid := width / 2
+	switch {
+	case box.X0 < mid && box.X1 > mid+width*0.05:
+		return 0
+	case (box.X0+box.X1)/2 < mid:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// documentBuilder accumulates ordered regions into a StructuredDocument
+type documentBuilder struct {
+	doc        *StructuredDocument
+	inAbstract bool
+}
+
+func (b *documentBuilder) addPage(pageNum int, regions []pageRegion) {
+	var captions []pageRegion
+	firstFigure := len(b.doc.Figures)
+
+	for _, r := range regions {
+		text := joinLines(r.Lines)
+		switch r.Class {
+		case LayoutPageHeader, LayoutPageFooter, LayoutFootnote:
+			continue
+		case LayoutPicture, LayoutTable:
+			// Text inside figures (axis labels, table cells) is not prose
+			b.doc.Figures = append(b.doc.Figures, DocFigure{
+				Page: pageNum, Kind: r.Class, Box: r.Box, Section: len(b.doc.Sections) - 1,
+			})
+			continue
+		case LayoutCaption:
+			captions = append(captions, r)
+			continue
+		}
+		if text == "" {
+			continue
+		}
+
+		switch {
+		case r.Class == LayoutTitle && b.doc.Title == "":
+			b.doc.Title = text
+		case r.Class == LayoutTitle || r.Class == LayoutSectionHeader:
+			b.inAbstract = strings.EqualFold(strings.Trim(text, " .:0123456789"), "abstract")
+			if !b.inAbstract {
+				b.doc.Sections = append(b.doc.Sections, DocSection{Heading: text})
+			}
+		case b.inAbstract:
+			b.doc.Abstract = appendParagraph(b.doc.Abstract, text)
+		case len(b.doc.Sections) == 0 && hasPrefixFold(text, "abstract"):
+			b.doc.Abstract = appendParagraph(b.doc.Abstract, strings.TrimLeft(text[len("abstract"):], " .:—-"))
+		default:
+			if len(b.doc.Sections) == 0 {
+				b.doc.Sections = append(b.doc.Sections, DocSection{})
+			}
+			s := &b.doc.Sections[len(b.doc.Sections)-1]
+			s.Text = appendParagraph(s.Text, text)
+		}
+	}
+
+	// Attach captions to the closest figure on the same page
+	for _, c := range captions {
+		text := joinLines(c.Lines)
+		best := -1
+		bestGap := 0.0
+		for i := firstFigure; i < len(b.doc.Figures); i++ {
+			gap := verticalGap(c.Box, b.doc.Figures[i].Box)
+			if b.doc.Figures[i].Caption == "" && (best < 0 || gap < bestGap) {
+				best, bestGap = i, gap
+			}
+		}
+		if best >= 0 {
+			b.doc.Figures[best].Caption = text
+		} else if text != "" && len(b.doc.Sections) > 0 {
+			s := &b.doc.Sections[len(b.doc.Sections)-1]
+			s.Text = appendParagraph(s.Text, text)
+		}
+	}
+}
+
+// finish drops empty sections, remapping figure section indexes
+func (b *documentBuilder) finish() {
+	remap := make([]int, len(b.doc.Sections))
+	var kept []DocSection
+	for i, s := range b.doc.Sections {
+		remap[i] = len(kept) - 1
+		if s.Heading != "" || s.Text != "" {
+			remap[i] = len(kept)
+			kept = append(kept, s)
+		}
+	}
+	b.doc.Sections = kept
+	for i, f := range b.doc.Figures {
+		if f.Section >= 0 {
+			b.doc.Figures[i].Section = remap[f.Section]
+		}
+	}
+}
+
+// joinLines joins a region's lines into one paragraph, undoing end-of-line hyphenation
+func joinLines(lines []TextLine) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		text := l.Text
+		if sb.Len() > 0 {
+			prev := sb.String()
+			if strings.HasSuffix(prev, "-") && len(prev) > 1 && prev[len(prev)-2] != ' ' {
+				sb.Reset()
+				sb.WriteString(strings.TrimSuffix(prev, "-"))
+			} else {
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(text)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func appendParagraph(text, paragraph string) string {
+	if text == "" {
+		return paragraph
+	}
+	return text + "\n\n" + paragraph
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// inMargin reports whether a box lies in the top or bottom 6% of the page
+func inMargin(box PdfRect, height float64) bool {
+	return box.Y1 < height*0.06 || box.Y0 > height*0.94
+}
+
+func verticalGap(a, b PdfRect) float64 {
+	switch {
+	case a.Y0 >= b.Y1:
+		return a.Y0 - b.Y1
+	case b.Y0 >= a.Y1:
+		return b.Y0 - a.Y1
+	default:
+		return 0
+	}
+}
+
+func area(r PdfRect) float64 {
+	return (r.X1 - r.X0) * (r.Y1 - r.Y0)
+}
+
+func union(a, b PdfRect) PdfRect {
+	return PdfRect{X0: min(a.X0, b.X0), Y0: min(a.Y0, b.Y0), X1: max(a.X1, b.X1), Y1: max(a.Y1, b.Y1)}
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return sorted[len(sorted)/2]
+}
@@ -0,0 +1,106 @@
+package common
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+// A two-column page in MuPDF's HTML format, with a running header and a page number
+const testPageHTML = `<div id="page0" style="width:612.0pt;height:792.0pt">
+<p style="top:20.0pt;left:72.0pt;line-height:10.0pt"><span style="font-family:Times;font-size:10.0pt">Journal of Tests 2024</span></p>
+<p style="top:60.0pt;left:150.0pt;line-height:18.0pt"><b><span style="font-family:Arial;font-size:18.0pt">A Layout Aware Paper Title</span></b></p>
+<p style="top:120.0pt;left:72.0pt;line-height:10.0pt"><b><span style="font-family:Times;font-size:10.0pt">1 Introduction</span></b></p>
+<p style="top:120.0pt;left:320.0pt;line-height:10.0pt"><b><span style="font-family:Times;font-size:10.0pt">2 Method</span></b></p>
+<p style="top:140.0pt;left:72.0pt;line-height:10.0pt"><span style="font-family:Times;font-size:10.0pt">Left column first.</span></p>
+<p style="top:140.0pt;left:320.0pt;line-height:10.0pt"><span style="font-family:Times;font-size:10.0pt">Right &amp; column text.</span></p>
+<p style="top:152.0pt;left:72.0pt;line-height:10.0pt"><span style="font-family:Times;font-size:10.0pt">Left column sec-</span></p>
+<p style="top:164.0pt;left:72.0pt;line-height:10.0pt"><span style="font-family:Times;font-size:10.0pt">ond line.</span></p>
+<p style="top:250.0pt;left:330.0pt;line-height:8.0pt"><span style="font-family:Times;font-size:8.0pt">axis</span></p>
+<p style="top:310.0pt;left:320.0pt;line-height:10.0pt"><span style="font-family:Times;font-size:10.0pt">Figure 1: A plot.</span></p>
+<p style="top:770.0pt;left:300.0pt;line-height:10.0pt"><span style="font-family:Times;font-size:10.0pt">3</span></p>
+</div>`
+
+func TestStructuredDocument(t *testing.T) {
+	lines := parseHTMLLines(testPageHTML)
+	if len(lines) != 11 || lines[5].Text != "Right & column text." || !lines[1].Bold || lines[1].FontSize != 18 {
+		t.Fatalf("unexpected lines %+v", lines)
+	}
+
+	// Detections on a 300 DPI render, in pixels
+	const scale = 72.0 / 300
+	box := func(class string, x0, y0, x1, y1 float64) LayoutBox {
+		return LayoutBox{Class: class, Rect: image.Rect(int(x0/scale), int(y0/scale), int(x1/scale), int(y1/scale))}
+	}
+	boxes := []LayoutBox{
+		box(LayoutPageHeader, 70, 18, 200, 32),
+		box(LayoutTitle, 140, 55, 470, 80),
+		box(LayoutSectionHeader, 70, 118, 160, 132),
+		box(LayoutSectionHeader, 318, 118, 400, 132),
+		box(LayoutText, 70, 138, 290, 176),
+		box(LayoutText, 318, 138, 540, 152),
+		box(LayoutPicture, 318, 200, 540, 300),
+		box(LayoutCaption, 318, 308, 540, 322),
+		box(LayoutPageFooter, 295, 768, 320, 782),
+	}
+
+	for _, tc := range []struct {
+		name    string
+		regions []pageRegion
+		figures int
+	}{
+		{"detector", regionsFromBoxes(lines, boxes, scale, 792), 1},
+		{"heuristics", heuristicRegions(lines, 0, median([]float64{10, 10, 10, 18}), 612, 792), 0},
+	} {
+		doc := &StructuredDocument{}
+		b := &documentBuilder{doc: doc}
+		b.addPage(0, orderRegions(tc.regions, 612))
+		b.finish()
+
+		if doc.Title != "A Layout Aware Paper Title" || len(doc.Sections) != 2 || len(doc.Figures) != tc.figures {
+			t.Fatalf("%s: unexpected document %+v", tc.name, doc)
+		}
+		if s := doc.Sections[0]; s.Heading != "1 Introduction" || !strings.Contains(s.Text, "Left column first.") || !strings.Contains(s.Text, "second line.") {
+			t.Errorf("%s: unexpected first section %+v", tc.name, s)
+		}
+		text := doc.Text()
+		if strings.Contains(text, "Journal of Tests") || strings.HasSuffix(text, "3") {
+			t.Errorf("%s: page furniture kept in %q", tc.name, text)
+		}
+		if strings.Index(text, "Left column") > strings.Index(text, "2 Method") {
+			t.Errorf("%s: columns read out of order in %q", tc.name, text)
+		}
+	}
+}
+
+func TestSectionFigures(t *testing.T) {
+	doc := &StructuredDocument{
+		Sections: []DocSection{
+			{Heading: "1 Introduction"},
+			{Heading: "2 Our Model"},
+			{Heading: "2.1 Training Details"},
+			{Heading: "3 Conclusion"},
+			{Heading: "Appendix A"},
+		},
+		Figures: []DocFigure{
+			{Section: -1, Caption: "Figure 1: Overview of the results.", Path: "overview.png"},
+			{Section: 0, Caption: "Too small to crop."},
+			{Section: 2, Caption: "Figure 2: Loss curves.", Path: "loss.png"},
+			{Section: 4, Caption: "Figure 9: Extra samples.", Path: "samples.png"},
+		},
+	}
+
+	figures := doc.SectionFigures()
+	if len(figures) != 2 {
+		t.Fatalf("expected figures for Results and Methodology, got %+v", figures)
+	}
+	if f := figures[SecResults]; f.Path != "overview.png" {
+		t.Errorf("figure before any section should match by caption, got %+v", f)
+	}
+	if f := figures[SecMethod]; f.Path != "loss.png" || f.Caption != "Figure 2: Loss curves." {
+		t.Errorf("subsection figure should belong to Methodology, got %+v", f)
+	}
+	if _, ok := figures[SecConclusion]; ok {
+		t.Error("appendix figure taken for the conclusion")
+	}
+}
@@ -0,0 +1,110 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// espeakVariants maps the Sarvam voices used by the pipelines to espeak-ng
+// voice variants of the same gender, so dialogue keeps two distinct speakers
+var espeakVariants = map[string]string{
+	"vidya":    "f3",
+	"anushka":  "f2",
+	"manisha":  "f4",
+	"arya":     "f1",
+	"karun":    "m3",
+	"abhilash": "m2",
+	"hitesh":   "m4",
+}
+
+// EspeakTTS is an offline TTSProvider that runs a local espeak-ng binary.
+// Quality is robotic but it needs no API key, which suits CI and local runs.
+type EspeakTTS struct {
+	Binary  string
+	Cache   *Cache        // Optional, reuses audio for identical chunks
+	Timeout time.Duration // Optional, bounds each chunk
+}
+
+// NewEspeakTTS finds espeak-ng (or espeak) on PATH
+func NewEspeakTTS() (*EspeakTTS, error) {
+	for _, name := range []string{"espeak-ng", "espeak"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return &EspeakTTS{Binary: path}, nil
+		}
+	}
+	return nil, fmt.Errorf("espeak-ng not found on PATH")
+}
+
+// Synthesize generates a WAV file for text
+func (e *EspeakTTS) Synthesize(ctx context.Context, text, outputPath string, opts TTSOptions) error {
+	if opts.SampleRate == 0 {
+		opts.SampleRate = DefaultTTSSampleRate
+	}
+
+	// espeak-ng copes with long input; chunking only bounds the cache entry size
+	return synthesizeChunked(ctx, text, outputPath, 2000, func(chunk, chunkPath string) error {
+		return WithTimeout(ctx, StageTTS, e.Timeout, func(ctx context.Context) error {
+			return e.synthesizeChunk(ctx, chunk, chunkPath, opts)
+		})
+	})
+}
+
+func (e *EspeakTTS) synthesizeChunk(ctx context.Context, text, outputPath string, opts TTSOptions) error {
+	voice := espeakVoice(opts)
+	cacheKey := CacheKey("espeak", text, voice, fmt.Sprint(opts.SampleRate))
+	if e.Cache.GetFile(cacheKey, outputPath) {
+		return nil
+	}
+
+	// espeak-ng always writes 22050 Hz; resample with ffmpeg if asked for another rate
+	rawPath := outputPath
+	if opts.SampleRate != DefaultTTSSampleRate {
+		rawPath = outputPath + ".raw.wav"
+		defer os.Remove(rawPath)
+	}
+
+	cmd := exec.CommandContext(ctx, e.Binary, "-v", voice, "-w", rawPath, "--stdin")
+	cmd.Stdin = strings.NewReader(text)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("espeak-ng error: %v, output: %s", err, string(output))
+	}
+
+	if rawPath != outputPath {
+		cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-i", rawPath, "-ar", fmt.Sprint(opts.SampleRate), outputPath)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("ffmpeg resample error: %v, output: %s", err, string(output))
+		}
+	}
+
+	if err := e.Cache.PutFile(cacheKey, outputPath); err != nil {
+		log.Printf("[TTS] Warning: failed to cache audio chunk: %v", err)
+	}
+	return nil
+}
+
+// espeakVoice builds an espeak-ng voice name such as "hi+f3" from the
+// language code and the requested voice
+func espeakVoice(opts TTSOptions) string {
+	lang := "en"
+	if opts.Language != "" {
+		lang = strings.ToLower(strings.SplitN(opts.Language, "-", 2)[0])
+	}
+	if lang == "od" {
+		lang = "or" // Sarvam's code for Odia differs from ISO 639-1
+	}
+	if variant, ok := espeakVariants[strings.ToLower(opts.Voice)]; ok {
+		return lang + "+" + variant
+	}
+	return lang
+}
@@ -0,0 +1,106 @@
+package common
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FakeLLM is a deterministic LLM for tests and offline runs. It answers the
+// built-in prompts with canned text or JSON in the shapes the pipelines
+// expect, and records every prompt it receives.
+type FakeLLM struct {
+	llmHelpers
+
+	// Responses maps a prompt substring to the reply for matching prompts;
+	// checked before the built-in replies, longest key first
+	Responses map[string]string
+
+	prompts []string
+	mu      sync.Mutex
+}
+
+func NewFakeLLM() *FakeLLM {
+	f := &FakeLLM{Responses: make(map[string]string)}
+	f.llmHelpers = llmHelpers{generate: f.GenerateText, generateJSON: f.GenerateJSON}
+	return f
+}
+
+func (f *FakeLLM) Close() {}
+
+// Prompts returns the prompts received so far
+func (f *FakeLLM) Prompts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.prompts...)
+}
+
+var fakeReplies = []struct{ match, reply string }{
+	{"faithful condensed version", "A condensed excerpt of the paper."},
+	{"Extract the title and authors", `{"title": "A Fake Paper for Testing", "authors": "Ada Lovelace, Alan Turing"}`},
+	{"expert scriptwriter", `Introduction
+This paper studies a problem that matters.
+Methodology
+The authors propose a simple method.
+Results
+The method beats the baselines.
+Discussion
+The results suggest further work is worthwhile.
+Conclusion
+The approach is effective and simple.`},
+	{"bullet points suitable for a presentation slide", `{"bullets": ["First key point", "Second key point", "Third key point"]}`},
+	{"academic research posters", `{
+  "title": "A Fake Paper for Testing",
+  "authors": "Ada Lovelace, Alan Turing",
+  "abstract": "This paper studies a problem. It proposes a method. The method works.",
+  "introduction": ["The problem matters.", "Existing work falls short.", "We propose a fix."],
+  "methodology": ["We collect data.", "We train a model.", "We evaluate it."],
+  "results": ["Accuracy improves by 10%.", "Latency drops by half.", "Results hold across datasets."],
+  "conclusion": ["The method is effective.", "Future work will scale it up."],
+  "references": ["Reference One (2020)", "Reference Two (2021)"]
+}`},
+	{"illustrate its main results on a poster", `{"ranking": [{"figure": 1, "score": 5}]}`},
+	{"Person1 and Person2", `{"turns": [
+  {"character": "Person1", "dialogue": "Did you know this paper makes a hard problem simple?"},
+  {"character": "Person2", "dialogue": "Really? How does it do that?"},
+  {"character": "Person1", "dialogue": "It uses a clever new method that beats every baseline."},
+  {"character": "Person2", "dialogue": "That sounds like it could change a lot of things!"}
+]}`},
+}
+
+// GenerateText returns the configured or built-in reply for prompt
+func (f *FakeLLM) GenerateText(ctx context.Context, prompt string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	f.prompts = append(f.prompts, prompt)
+	keys := make([]string, 0, len(f.Responses))
+	for k := range f.Responses {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	for _, k := range keys {
+		if strings.Contains(prompt, k) {
+			reply := f.Responses[k]
+			f.mu.Unlock()
+			return reply, nil
+		}
+	}
+	f.mu.Unlock()
+
+	for _, r := range fakeReplies {
+		if strings.Contains(prompt, r.match) {
+			return r.reply, nil
+		}
+	}
+	return "This is a fake response.", nil
+}
+
+// GenerateJSON returns the same reply as GenerateText; the canned replies
+// for structured prompts are already JSON
+func (f *FakeLLM) GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	return f.GenerateText(ctx, prompt)
+}
@@ -0,0 +1,178 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// figureLabelRe matches the figure number a caption starts with, e.g.
+// "Figure 3:", "Fig. 3." or "Table A1"
+var figureLabelRe = regexp.MustCompile(`(?i)^(fig(?:ure)?|tab(?:le)?)\.?\s*([A-Z]?\d+)\s*[:.]?\s*`)
+
+// NumberFigures sets the Label each figure has in the paper: the number its
+// caption starts with, which is removed from the caption, or else its
+// position among the figures of its kind, as LaTeX numbers them. Figures
+// that already have a Label are kept.
+func NumberFigures(figures []DocFigure) {
+	counts := make(map[string]int)
+	for i := range figures {
+		f := &figures[i]
+		name := "Figure"
+		if f.Kind == LayoutTable {
+			name = "Table"
+		}
+		counts[name]++
+		if f.Label != "" {
+			continue
+		}
+
+		if m := figureLabelRe.FindStringSubmatch(f.Caption); m != nil {
+			name = "Figure"
+			if strings.HasPrefix(strings.ToLower(m[1]), "tab") {
+				name = "Table"
+			}
+			f.Label = name + " " + m[2]
+			f.Caption = strings.TrimSpace(f.Caption[len(m[0]):])
+			continue
+		}
+		f.Label = fmt.Sprintf("%s %d", name, counts[name])
+	}
+}
+
+// ImageLLM is implemented by LLMs that can look at images, such as Gemini
+type ImageLLM interface {
+	// GenerateJSONWithImages is GenerateJSON with PNG or JPEG files
+	// attached after the prompt, in order
+	GenerateJSONWithImages(ctx context.Context, prompt string, images []string, schema *Schema) (string, error)
+}
+
+// maxRankedFigures bounds the figures sent to the LLM; later ones follow
+// the ranked ones in paper order
+const maxRankedFigures = 12
+
+// figureRanking is the JSON shape of RankFigures responses
+type figureRanking struct {
+	Ranking []figureScore `json:"ranking"`
+}
+
+type figureScore struct {
+	Figure int     `json:"figure"` // 1-based index in the prompt
+	Score  float64 `json:"score"`
+}
+
+var figureRankingSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"ranking": {
+			Type:        "array",
+			Description: "one score per figure",
+			Items: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"figure": {Type: "integer", Description: "figure number from the list"},
+					"score":  {Type: "number", Description: "0 (irrelevant) to 10 (the key result)"},
+				},
+				Required: []string{"figure", "score"},
+			},
+		},
+	},
+	Required: []string{"ranking"},
+}
+
+func (r *figureRanking) Validate() error {
+	if len(r.Ranking) == 0 {
+		return fmt.Errorf("ranking must not be empty")
+	}
+	for i, s := range r.Ranking {
+		if s.Figure < 1 {
+			return fmt.Errorf("ranking[%d]: figure must be a number from the list", i)
+		}
+		if s.Score < 0 || s.Score > 10 {
+			return fmt.Errorf("ranking[%d]: score must be between 0 and 10", i)
+		}
+	}
+	return nil
+}
+
+// RankFigures orders figures by how well they illustrate the paper's main
+// results, most relevant first. The LLM scores each figure from its caption,
+// and also sees the images if it is an ImageLLM and all of them are PNG or
+// JPEG files. Ties and unscored figures keep their paper order.
+func RankFigures(ctx context.Context, llm LLM, figures []DocFigure, results []string) ([]DocFigure, error) {
+	if len(figures) < 2 {
+		return figures, nil
+	}
+	candidates := figures[:min(len(figures), maxRankedFigures)]
+
+	var list strings.Builder
+	images := make([]string, 0, len(candidates))
+	for i, f := range candidates {
+		caption := f.Caption
+		if caption == "" {
+			caption = "(no caption)"
+		}
+		fmt.Fprintf(&list, "%d. %s: %s\n", i+1, nonEmptyLabel(f), caption)
+
+		switch strings.ToLower(filepath.Ext(f.Path)) {
+		case ".png", ".jpg", ".jpeg":
+			images = append(images, f.Path)
+		}
+	}
+
+	generate := llm.GenerateJSON
+	imageNote := ""
+	if imageLLM, ok := llm.(ImageLLM); ok && len(images) == len(candidates) {
+		generate = func(ctx context.Context, prompt string, schema *Schema) (string, error) {
+			return imageLLM.GenerateJSONWithImages(ctx, prompt, images, schema)
+		}
+		imageNote = "The figure images are attached in the same order as the list.\n"
+	}
+
+	prompt := fmt.Sprintf(`
+Rate how well each figure from a research paper would illustrate its main results on a poster, from 0 (irrelevant) to 10 (the key result).
+Prefer plots and tables of the main results over diagrams, examples and logos.
+%sReturn a JSON object with a "ranking" array of {"figure": number, "score": number} objects, one per figure.
+
+Main results:
+- %s
+
+Figures:
+%s`, imageNote, strings.Join(results, "\n- "), list.String())
+
+	var ranking figureRanking
+	if err := generateStructured(ctx, generate, prompt, figureRankingSchema, &ranking); err != nil {
+		return nil, err
+	}
+
+	scores := make([]float64, len(candidates))
+	for _, s := range ranking.Ranking {
+		if s.Figure <= len(candidates) {
+			scores[s.Figure-1] = s.Score
+		}
+	}
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	ranked := make([]DocFigure, 0, len(figures))
+	for _, i := range order {
+		ranked = append(ranked, candidates[i])
+	}
+	return append(ranked, figures[len(candidates):]...), nil
+}
+
+func nonEmptyLabel(f DocFigure) string {
+	if f.Label != "" {
+		return f.Label
+	}
+	if f.Kind == LayoutTable {
+		return "Table"
+	}
+	return "Figure"
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNumberFigures(t *testing.T) {
+	figures := []DocFigure{
+		{Kind: LayoutPicture, Caption: "Figure 1: Overview."},
+		{Kind: LayoutTable, Caption: "Tab. 2. Accuracy on ImageNet"},
+		{Kind: LayoutPicture},
+		{Kind: LayoutPicture, Caption: "Loss curves.", Label: "Figure 7"},
+	}
+	NumberFigures(figures)
+	NumberFigures(figures)
+
+	for i, want := range []struct{ label, caption string }{
+		{"Figure 1", "Overview."},
+		{"Table 2", "Accuracy on ImageNet"},
+		{"Figure 2", ""},
+		{"Figure 7", "Loss curves."},
+	} {
+		if figures[i].Label != want.label || figures[i].Caption != want.caption {
+			t.Errorf("figure %d: got %q %q, want %q %q", i, figures[i].Label, figures[i].Caption, want.label, want.caption)
+		}
+	}
+}
+
+func TestRankFigures(t *testing.T) {
+	figures := []DocFigure{
+		{Label: "Figure 1", Caption: "System overview.", Path: "overview.pdf"},
+		{Label: "Figure 2", Caption: "Accuracy against baselines.", Path: "accuracy.pdf"},
+		{Label: "Table 1", Caption: "Hyperparameters.", Path: "params.pdf"},
+	}
+
+	llm := NewFakeLLM()
+	llm.Responses["illustrate its main results"] = `{"ranking": [{"figure": 2, "score": 9}, {"figure": 3, "score": 4}, {"figure": 7, "score": 10}]}`
+	ranked, err := RankFigures(context.Background(), llm, figures, []string{"Accuracy improves by 10%."})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var labels []string
+	for _, f := range ranked {
+		labels = append(labels, f.Label)
+	}
+	if got := strings.Join(labels, ", "); got != "Figure 2, Table 1, Figure 1" {
+		t.Errorf("unexpected ranking %s", got)
+	}
+	if prompt := llm.Prompts()[0]; !strings.Contains(prompt, "2. Figure 2: Accuracy against baselines.") || strings.Contains(prompt, "attached") {
+		t.Errorf("unexpected prompt %q", prompt)
+	}
+}
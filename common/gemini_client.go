@@ -2,213 +2,172 @@ package common
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
 	"google.golang.org/api/option"
 )
 
+// DefaultGeminiModel is used when no model is configured
+const DefaultGeminiModel = "gemini-3-flash-preview"
+
+// GeminiClient is the Gemini implementation of LLM
 type GeminiClient struct {
-	client *genai.Client
-	model  *genai.GenerativeModel
+	llmHelpers
+	Cache *Cache // Optional, reuses responses to identical prompts
+
+	client      *genai.Client
+	model       *genai.GenerativeModel
+	modelName   string
+	temperature float32
 }
 
-func NewGeminiClient(apiKey string) (*GeminiClient, error) {
+// NewGeminiClient creates a Gemini client; an empty modelName uses DefaultGeminiModel
+func NewGeminiClient(apiKey, modelName string) (*GeminiClient, error) {
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create gemini client: %w", err)
 	}
 
-	model := client.GenerativeModel("gemini-3-flash-preview")
-	model.SetTemperature(0.7)
+	if modelName == "" {
+		modelName = DefaultGeminiModel
+	}
+	temperature := float32(0.7)
+	model := client.GenerativeModel(modelName)
+	model.SetTemperature(temperature)
 
-	return &GeminiClient{
-		client: client,
-		model:  model,
-	}, nil
+	g := &GeminiClient{
+		client:      client,
+		model:       model,
+		modelName:   modelName,
+		temperature: temperature,
+	}
+	g.llmHelpers = llmHelpers{generate: g.GenerateText, generateJSON: g.GenerateJSON, countTokens: g.CountTokens}
+	return g, nil
 }
 
-func (g *GeminiClient) Close() {
-	g.client.Close()
+// SetTemperature sets the sampling temperature of later calls
+func (g *GeminiClient) SetTemperature(temperature float32) {
+	g.temperature = temperature
+	g.model.SetTemperature(temperature)
 }
 
-// GenerateText generates text from a prompt (generic method for custom prompts)
-func (g *GeminiClient) GenerateText(prompt string) (string, error) {
-	ctx := context.Background()
-	resp, err := g.model.GenerateContent(ctx, genai.Text(prompt))
-	if err != nil {
-		return "", fmt.Errorf("gemini generation error: %w", err)
-	}
-	return g.extractTextFromResponse(resp)
+func (g *GeminiClient) Close() {
+	g.client.Close()
 }
 
-// PaperMetadata holds extracted paper information
-type PaperMetadata struct {
-	Title   string `json:"title"`
-	Authors string `json:"authors"`
+// GenerateText generates text from a prompt, serving repeated prompts from the cache
+func (g *GeminiClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	key := CacheKey("gemini", g.modelName, fmt.Sprint(g.temperature), prompt)
+	return cachedGenerate(g.Cache, key, func() (string, error) {
+		return g.generateContent(ctx, g.model, genai.Text(prompt))
+	})
 }
 
-// ExtractMetadata extracts title and authors from paper text using Gemini
-func (g *GeminiClient) ExtractMetadata(text string) (*PaperMetadata, error) {
-	ctx := context.Background()
-
-	// Limit text to first 2000 chars (metadata is usually at the start)
-	if len(text) > 2000 {
-		text = text[:2000]
-	}
-
-	prompt := fmt.Sprintf(`Extract the title and authors from this research paper text.
-
-Return in exactly this format (no extra text):
-TITLE: <paper title>
-AUTHORS: <author names separated by commas>
-
-If you cannot find the title, use "Research Paper".
-If you cannot find authors, use "Authors".
-
-Text:
-%s`, text)
-
-	resp, err := g.model.GenerateContent(ctx, genai.Text(prompt))
+// GenerateJSON generates a JSON document using Gemini's JSON response mode,
+// constrained by schema
+func (g *GeminiClient) GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	schemaJSON, err := json.Marshal(schema)
 	if err != nil {
-		return &PaperMetadata{Title: "Research Paper", Authors: "Authors"}, err
+		return "", err
 	}
 
-	response, err := g.extractTextFromResponse(resp)
+	key := CacheKey("gemini-json", g.modelName, fmt.Sprint(g.temperature), string(schemaJSON), prompt)
+	return cachedGenerate(g.Cache, key, func() (string, error) {
+		return g.generateContent(ctx, g.jsonModel(schema), genai.Text(prompt))
+	})
+}
+
+// GenerateJSONWithImages is GenerateJSON with PNG or JPEG files attached
+// after the prompt, in order (ImageLLM)
+func (g *GeminiClient) GenerateJSONWithImages(ctx context.Context, prompt string, images []string, schema *Schema) (string, error) {
+	schemaJSON, err := json.Marshal(schema)
 	if err != nil {
-		return &PaperMetadata{Title: "Research Paper", Authors: "Authors"}, err
+		return "", err
 	}
 
-	// Parse the response
-	metadata := &PaperMetadata{Title: "Research Paper", Authors: "Authors"}
-	lines := strings.Split(response, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(strings.ToUpper(line), "TITLE:") {
-			metadata.Title = strings.TrimSpace(strings.TrimPrefix(line, "TITLE:"))
-			metadata.Title = strings.TrimPrefix(metadata.Title, ":")
-			metadata.Title = strings.TrimSpace(metadata.Title)
-		} else if strings.HasPrefix(strings.ToUpper(line), "AUTHORS:") {
-			metadata.Authors = strings.TrimSpace(strings.TrimPrefix(line, "AUTHORS:"))
-			metadata.Authors = strings.TrimPrefix(metadata.Authors, ":")
-			metadata.Authors = strings.TrimSpace(metadata.Authors)
+	parts := []genai.Part{genai.Text(prompt)}
+	keyParts := []string{"gemini-json-images", g.modelName, fmt.Sprint(g.temperature), string(schemaJSON), prompt}
+	for _, path := range images {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read image: %w", err)
+		}
+		format := "png"
+		if ext := strings.ToLower(filepath.Ext(path)); ext == ".jpg" || ext == ".jpeg" {
+			format = "jpeg"
 		}
+		parts = append(parts, genai.ImageData(format, data))
+		keyParts = append(keyParts, string(data))
 	}
 
-	return metadata, nil
+	return cachedGenerate(g.Cache, CacheKey(keyParts...), func() (string, error) {
+		return g.generateContent(ctx, g.jsonModel(schema), parts...)
+	})
 }
 
-// GenerateScript generates a video script from text (for video pipeline)
-func (g *GeminiClient) GenerateScript(text string) (string, error) {
-	ctx := context.Background()
-	prompt := fmt.Sprintf(`
-You are an expert scriptwriter for educational videos. 
-Convert the following research paper text into an engaging video script.
-The script should be divided into clear sections: Introduction, Methodology, Results, Discussion, Conclusion.
-Write in a conversational, easy-to-understand tone.
-Do not include any visual cues or camera directions, just the spoken narration.
-Make it engaging and flow well.
+// generateContent makes one generation call under the LLM call timeout
+func (g *GeminiClient) generateContent(ctx context.Context, model *genai.GenerativeModel, parts ...genai.Part) (string, error) {
+	return g.withCallTimeout(ctx, func(ctx context.Context) (string, error) {
+		resp, err := model.GenerateContent(ctx, parts...)
+		if err != nil {
+			return "", fmt.Errorf("gemini generation error: %w", err)
+		}
+		return g.extractTextFromResponse(resp)
+	})
+}
 
-Text:
-%s
-	`, text)
+// jsonModel returns a model in JSON response mode, constrained by schema
+func (g *GeminiClient) jsonModel(schema *Schema) *genai.GenerativeModel {
+	model := g.client.GenerativeModel(g.modelName)
+	model.SetTemperature(g.temperature)
+	model.ResponseMIMEType = "application/json"
+	model.ResponseSchema = toGenaiSchema(schema)
+	return model
+}
 
-	resp, err := g.model.GenerateContent(ctx, genai.Text(prompt))
+// CountTokens counts text with the model's tokenizer
+func (g *GeminiClient) CountTokens(ctx context.Context, text string) (int, error) {
+	resp, err := g.model.CountTokens(ctx, genai.Text(text))
 	if err != nil {
-		return "", fmt.Errorf("gemini generation error: %w", err)
+		return 0, fmt.Errorf("gemini token count error: %w", err)
 	}
-
-	return g.extractTextFromResponse(resp)
+	return int(resp.TotalTokens), nil
 }
 
-// GenerateBulletPoints generates bullet points for slides
-func (g *GeminiClient) GenerateBulletPoints(sectionText string) ([]string, error) {
-	ctx := context.Background()
-	prompt := fmt.Sprintf(`
-Summarize the following text into 3-5 concise bullet points suitable for a presentation slide.
-Return ONLY the bullet points, one per line, starting with "- ".
-
-Text:
-%s
-	`, sectionText)
+var genaiTypes = map[string]genai.Type{
+	"string":  genai.TypeString,
+	"number":  genai.TypeNumber,
+	"integer": genai.TypeInteger,
+	"boolean": genai.TypeBoolean,
+	"array":   genai.TypeArray,
+	"object":  genai.TypeObject,
+}
 
-	resp, err := g.model.GenerateContent(ctx, genai.Text(prompt))
-	if err != nil {
-		return nil, fmt.Errorf("gemini generation error: %w", err)
+func toGenaiSchema(s *Schema) *genai.Schema {
+	if s == nil {
+		return nil
 	}
 
-	text, err := g.extractTextFromResponse(resp)
-	if err != nil {
-		return nil, err
+	out := &genai.Schema{
+		Type:        genaiTypes[s.Type],
+		Description: s.Description,
+		Enum:        s.Enum,
+		Items:       toGenaiSchema(s.Items),
+		Required:    s.Required,
 	}
-
-	lines := strings.Split(text, "\n")
-	var bullets []string
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") {
-			bullets = append(bullets, strings.TrimPrefix(strings.TrimPrefix(trimmed, "- "), "* "))
-		} else if len(trimmed) > 0 {
-			bullets = append(bullets, trimmed)
+	if len(s.Properties) > 0 {
+		out.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			out.Properties[name] = toGenaiSchema(prop)
 		}
 	}
-	return bullets, nil
-}
-
-// GeneratePosterContent generates structured content for a poster
-func (g *GeminiClient) GeneratePosterContent(text string) (*PosterContent, error) {
-	ctx := context.Background()
-	prompt := fmt.Sprintf(`
-You are an expert at creating academic research posters. 
-Analyze the following research paper text and generate content suitable for a large 3-column academic poster (120cm x 72cm).
-
-IMPORTANT: The poster has significant space to fill. Generate DETAILED and COMPREHENSIVE content.
-
-Return the content in the following format (use exactly these section headers):
-
-TITLE: [Generate a concise, impactful title]
-
-AUTHORS: [Extract or generate appropriate author names/affiliations]
-
-ABSTRACT:
-[Write a concise 3-4 sentence abstract summarizing the key problem, approach, and main result.]
-
-INTRODUCTION:
-[Write exactly 3 concise bullet points (one sentence each) introducing the problem and motivation.]
-
-METHODOLOGY:
-[Write exactly 3 concise bullet points (one sentence each) describing the key methods used.]
-
-RESULTS:
-[Write exactly 3 concise bullet points (one sentence each) highlighting the main findings with specific metrics.]
-
-CONCLUSION:
-[Write exactly 2-3 concise bullet points (one sentence each) summarizing key takeaways and future work.]
-
-REFERENCES:
-[List 4-5 key references if identifiable from the text]
-
-Each bullet point must be exactly ONE sentence - concise and focused.
-Start each bullet point with "- ".
-Prioritize clarity and brevity to fit poster space constraints.
-
-Text:
-%s
-	`, text)
-
-	resp, err := g.model.GenerateContent(ctx, genai.Text(prompt))
-	if err != nil {
-		return nil, fmt.Errorf("gemini generation error: %w", err)
-	}
-
-	text, err = g.extractTextFromResponse(resp)
-	if err != nil {
-		return nil, err
-	}
-
-	return parsePosterContent(text), nil
+	return out
 }
 
 func (g *GeminiClient) extractTextFromResponse(resp *genai.GenerateContentResponse) (string, error) {
@@ -225,97 +184,3 @@ func (g *GeminiClient) extractTextFromResponse(resp *genai.GenerateContentRespon
 
 	return sb.String(), nil
 }
-
-// PosterContent holds structured poster content
-type PosterContent struct {
-	Title        string
-	Authors      string
-	Abstract     string
-	Introduction []string
-	Methodology  []string
-	Results      []string
-	Conclusion   []string
-	References   []string
-}
-
-// parsePosterContent parses the AI response into structured content
-func parsePosterContent(text string) *PosterContent {
-	content := &PosterContent{}
-	lines := strings.Split(text, "\n")
-
-	currentSection := ""
-	var currentBuffer strings.Builder
-
-	extractBullets := func(text string) []string {
-		var bullets []string
-		for _, line := range strings.Split(text, "\n") {
-			trimmed := strings.TrimSpace(line)
-			if strings.HasPrefix(trimmed, "- ") {
-				bullets = append(bullets, strings.TrimPrefix(trimmed, "- "))
-			} else if strings.HasPrefix(trimmed, "* ") {
-				bullets = append(bullets, strings.TrimPrefix(trimmed, "* "))
-			} else if len(trimmed) > 0 && !strings.Contains(strings.ToUpper(trimmed), ":") {
-				bullets = append(bullets, trimmed)
-			}
-		}
-		return bullets
-	}
-
-	saveSection := func() {
-		bufText := strings.TrimSpace(currentBuffer.String())
-		switch currentSection {
-		case "TITLE":
-			content.Title = bufText
-		case "AUTHORS":
-			content.Authors = bufText
-		case "ABSTRACT":
-			content.Abstract = bufText
-		case "INTRODUCTION":
-			content.Introduction = extractBullets(bufText)
-		case "METHODOLOGY":
-			content.Methodology = extractBullets(bufText)
-		case "RESULTS":
-			content.Results = extractBullets(bufText)
-		case "CONCLUSION":
-			content.Conclusion = extractBullets(bufText)
-		case "REFERENCES":
-			content.References = extractBullets(bufText)
-		}
-	}
-
-	sectionHeaders := []string{"TITLE:", "AUTHORS:", "ABSTRACT:", "INTRODUCTION:", "METHODOLOGY:", "RESULTS:", "CONCLUSION:", "REFERENCES:"}
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		foundHeader := false
-
-		for _, header := range sectionHeaders {
-			if strings.HasPrefix(strings.ToUpper(trimmed), header) {
-				saveSection()
-				currentSection = strings.TrimSuffix(header, ":")
-				currentBuffer.Reset()
-				// Check if there's content after the header on the same line
-				remainder := strings.TrimSpace(strings.TrimPrefix(strings.ToUpper(trimmed), header))
-				if remainder != "" {
-					// Get the original case remainder
-					idx := strings.Index(strings.ToUpper(trimmed), header)
-					if idx >= 0 {
-						actualRemainder := strings.TrimSpace(trimmed[idx+len(header):])
-						currentBuffer.WriteString(actualRemainder)
-						currentBuffer.WriteString("\n")
-					}
-				}
-				foundHeader = true
-				break
-			}
-		}
-
-		if !foundHeader && currentSection != "" {
-			currentBuffer.WriteString(line)
-			currentBuffer.WriteString("\n")
-		}
-	}
-	saveSection()
-
-	return content
-}
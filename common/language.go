@@ -0,0 +1,82 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLanguage is used when PipelineConfig.Language is empty
+const DefaultLanguage = "english"
+
+// LanguageCodes maps language names to Sarvam TTS codes
+var LanguageCodes = map[string]string{
+	"english":   "en-IN",
+	"hindi":     "hi-IN",
+	"tamil":     "ta-IN",
+	"bengali":   "bn-IN",
+	"telugu":    "te-IN",
+	"kannada":   "kn-IN",
+	"malayalam": "ml-IN",
+	"marathi":   "mr-IN",
+	"gujarati":  "gu-IN",
+	"punjabi":   "pa-IN",
+	"odia":      "od-IN",
+}
+
+// LanguageFonts maps languages written in non-Latin scripts to the Noto
+// font slides are set in. The Noto Indic fonts include Latin letters, so
+// English terms and numbers in the text still render.
+var LanguageFonts = map[string]string{
+	"hindi":     "Noto Sans Devanagari",
+	"marathi":   "Noto Sans Devanagari",
+	"tamil":     "Noto Sans Tamil",
+	"bengali":   "Noto Sans Bengali",
+	"telugu":    "Noto Sans Telugu",
+	"kannada":   "Noto Sans Kannada",
+	"malayalam": "Noto Sans Malayalam",
+	"gujarati":  "Noto Sans Gujarati",
+	"punjabi":   "Noto Sans Gurmukhi",
+	"odia":      "Noto Sans Oriya",
+}
+
+// NormalizeLanguage lower-cases a language name, mapping "" to DefaultLanguage
+func NormalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		return DefaultLanguage
+	}
+	return language
+}
+
+// ValidateLanguage checks that language is one of LanguageCodes
+func ValidateLanguage(language string) error {
+	if _, ok := LanguageCodes[NormalizeLanguage(language)]; !ok {
+		names := make([]string, 0, len(LanguageCodes))
+		for name := range LanguageCodes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unsupported language %q, use one of %s", language, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// GetLanguageCode returns the Sarvam TTS language code
+func GetLanguageCode(language string) string {
+	if code, ok := LanguageCodes[NormalizeLanguage(language)]; ok {
+		return code
+	}
+	return "en-IN" // Default to English
+}
+
+// LanguageInstruction returns a prompt line asking for what (e.g. "the
+// narration") to be written in language, or "" for English
+func LanguageInstruction(language, what string) string {
+	language = NormalizeLanguage(language)
+	if language == DefaultLanguage {
+		return ""
+	}
+	name := strings.ToUpper(language[:1]) + language[1:]
+	return fmt.Sprintf("Write %s in %s, in its native script. Keep names, numbers and technical terms without a common %s equivalent as they are.\n", what, name, name)
+}
@@ -0,0 +1,763 @@
+package common
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Paper source types for PipelineConfig.SourceType
+const (
+	SourcePDF   = "pdf"
+	SourceLatex = "latex"
+)
+
+const (
+	maxLatexProjectBytes = 256 << 20 // extracted size limit for uploaded archives
+	maxLatexInputDepth   = 8         // nesting limit for \input and \include
+)
+
+// Graphics formats pdflatex can include, which the slides and poster are built with
+var latexGraphicExts = []string{".pdf", ".png", ".jpg", ".jpeg"}
+
+// IsLatexSource reports whether path names a .tex file or a .zip, .tar.gz
+// or .tgz project archive
+func IsLatexSource(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range []string{".tex", ".zip", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadPaper returns the text of the paper for prompting. LaTeX sources are
+// parsed directly and also return the document, whose figures are the
+// original image files; PDFs go through layout-aware extraction and return
+// a nil document. A LaTeX source that cannot be parsed falls back to the
+// PDF when there is one.
+func LoadPaper(ctx context.Context, config PipelineConfig) (string, *StructuredDocument, error) {
+	if config.SourceType == SourceLatex {
+		doc, err := ParseLatexProject(config.SourcePath, filepath.Join(config.OutputDir, "latex"))
+		if err == nil {
+			return doc.Text(), doc, nil
+		}
+		if config.PDFPath == "" {
+			return "", nil, fmt.Errorf("failed to parse LaTeX source: %w", err)
+		}
+		log.Printf("Warning: failed to parse LaTeX source (%v), using the PDF", err)
+	}
+
+	pdfProc, err := NewPDFProcessor(config.PDFPath, config.OutputDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+	defer pdfProc.Close()
+
+	text, err := pdfProc.ExtractLayoutText(ctx, config.LayoutModel, config.Settings.OrDefault().Layout)
+	if err != nil {
+		return "", nil, fmt.Errorf("text extraction failed: %w", err)
+	}
+	return text, nil, nil
+}
+
+// ParseLatexProject reads a .tex file or a .zip/.tar.gz project into a
+// StructuredDocument. Archives are extracted under workDir/src, and the
+// figures' image files are copied to workDir/figures so they live with the
+// rest of the job's output.
+func ParseLatexProject(path, workDir string) (*StructuredDocument, error) {
+	root, mainFile, err := prepareLatexProject(path, filepath.Join(workDir, "src"))
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := readLatexFile(root, mainFile, 0)
+	if err != nil {
+		return nil, err
+	}
+	doc := parseLatex(source)
+	if len(doc.Sections) == 0 {
+		return nil, fmt.Errorf("no sections found in %s", filepath.Base(mainFile))
+	}
+
+	figuresDir := filepath.Join(workDir, "figures")
+	if err := os.MkdirAll(figuresDir, 0755); err != nil {
+		return nil, err
+	}
+	graphicsDirs := parseGraphicsPath(source)
+	for i := range doc.Figures {
+		f := &doc.Figures[i]
+		if f.Path == "" {
+			continue
+		}
+		src, ok := resolveLatexGraphic(root, graphicsDirs, f.Path)
+		if !ok {
+			log.Printf("Warning: figure %s not found or not PDF, PNG or JPEG, skipping its image", f.Path)
+			f.Path = ""
+			continue
+		}
+		dst := filepath.Join(figuresDir, fmt.Sprintf("figure_%02d%s", i+1, strings.ToLower(filepath.Ext(src))))
+		if err := copyFile(src, dst); err != nil {
+			return nil, fmt.Errorf("failed to copy figure %s: %w", f.Path, err)
+		}
+		f.Path = dst
+	}
+	return doc, nil
+}
+
+// prepareLatexProject extracts archives into dest and returns the project
+// root (the directory of the main file, which paths in it are relative to)
+// and the main file
+func prepareLatexProject(path, dest string) (string, string, error) {
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".tex") {
+		return filepath.Dir(path), path, nil
+	}
+
+	// Start from a clean directory so a resumed job does not mix projects
+	if err := os.RemoveAll(dest); err != nil {
+		return "", "", err
+	}
+	var err error
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		err = extractZip(path, dest)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		err = extractTarGz(path, dest)
+	default:
+		err = fmt.Errorf("unsupported LaTeX source %s, expected .tex, .zip or .tar.gz", filepath.Base(path))
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	mainFile, err := findMainTex(dest)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Dir(mainFile), mainFile, nil
+}
+
+func extractZip(path, dest string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+	defer zr.Close()
+
+	budget := int64(maxLatexProjectBytes)
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = extractFile(dest, f.Name, rc, &budget)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractTarGz extracts a gzipped tarball. arXiv serves single-file
+// submissions as a gzipped .tex without the tar wrapper, which is saved as
+// main.tex.
+func extractTarGz(path, dest string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("invalid gzip archive: %w", err)
+	}
+	defer gz.Close()
+
+	budget := int64(maxLatexProjectBytes)
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := gz.Reset(f); err != nil {
+			return err
+		}
+		return extractFile(dest, "main.tex", gz, &budget)
+	}
+
+	for ; err == nil; hdr, err = tr.Next() {
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := extractFile(dest, hdr.Name, tr, &budget); err != nil {
+			return err
+		}
+	}
+	if err != io.EOF {
+		return fmt.Errorf("invalid tar archive: %w", err)
+	}
+	return nil
+}
+
+// extractFile writes one archive member below dest, skipping names that
+// would escape it and charging its size to budget
+func extractFile(dest, name string, r io.Reader, budget *int64) error {
+	name = filepath.FromSlash(name)
+	if !filepath.IsLocal(name) {
+		log.Printf("Warning: skipping archive entry %s outside the project", name)
+		return nil
+	}
+	path := filepath.Join(dest, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, io.LimitReader(r, *budget+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	*budget -= n
+	if *budget < 0 {
+		return fmt.Errorf("LaTeX project larger than %d MB", maxLatexProjectBytes>>20)
+	}
+	return nil
+}
+
+// findMainTex picks the file with \documentclass and \begin{document},
+// preferring the shallowest and the conventional names for ties
+func findMainTex(dir string) (string, error) {
+	var candidates []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".tex") {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		src := stripLatexComments(string(data))
+		if strings.Contains(src, `\documentclass`) && strings.Contains(src, `\begin{document}`) {
+			candidates = append(candidates, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no main .tex file with \\documentclass found")
+	}
+
+	conventional := func(path string) bool {
+		switch strings.ToLower(filepath.Base(path)) {
+		case "main.tex", "paper.tex", "ms.tex":
+			return true
+		}
+		return false
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if da, db := strings.Count(a, string(filepath.Separator)), strings.Count(b, string(filepath.Separator)); da != db {
+			return da < db
+		}
+		if conventional(a) != conventional(b) {
+			return conventional(a)
+		}
+		return a < b
+	})
+	return candidates[0], nil
+}
+
+var latexInputRe = regexp.MustCompile(`\\(?:input|include|subfile)\s*\{([^{}]+)\}`)
+
+// readLatexFile returns the file without comments and with \input and
+// \include expanded. Paths are relative to root, as in LaTeX.
+func readLatexFile(root, path string, depth int) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	src := stripLatexComments(string(data))
+	if depth == maxLatexInputDepth {
+		return src, nil
+	}
+
+	return latexInputRe.ReplaceAllStringFunc(src, func(m string) string {
+		name := filepath.FromSlash(strings.TrimSpace(latexInputRe.FindStringSubmatch(m)[1]))
+		if filepath.Ext(name) == "" {
+			name += ".tex"
+		}
+		if !filepath.IsLocal(name) {
+			log.Printf("Warning: skipping LaTeX input %s outside the project", name)
+			return ""
+		}
+		sub, err := readLatexFile(root, filepath.Join(root, name), depth+1)
+		if err != nil {
+			log.Printf("Warning: skipping LaTeX input %s: %v", name, err)
+			return ""
+		}
+		return sub
+	}), nil
+}
+
+var latexCommentEnvRe = regexp.MustCompile(`(?s)\\begin\{comment\}.*?\\end\{comment\}|\\iffalse\b.*?\\fi\b`)
+
+// stripLatexComments removes % comments, which also swallow their line
+// break as in TeX, and comment environments
+func stripLatexComments(src string) string {
+	var sb strings.Builder
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		comment := false
+		for j := 0; j < len(line); j++ {
+			if line[j] == '\\' {
+				j++
+			} else if line[j] == '%' {
+				line, comment = line[:j], true
+				break
+			}
+		}
+		sb.WriteString(line)
+		if !comment && i < len(lines)-1 {
+			sb.WriteByte('\n')
+		}
+	}
+	return latexCommentEnvRe.ReplaceAllString(sb.String(), "")
+}
+
+var (
+	latexAbstractRe  = regexp.MustCompile(`(?s)\\begin\{abstract\}(.*?)\\end\{abstract\}`)
+	latexFloatRe     = regexp.MustCompile(`(?s)\\begin\{(figure\*?|table\*?|wrapfigure|wraptable)\}(.*?)\\end\{(?:figure\*?|table\*?|wrapfigure|wraptable)\}`)
+	latexAndRe       = regexp.MustCompile(`\\and\b|\\And\b|\\AND\b`)
+	latexBraceRe     = regexp.MustCompile(`\{([^{}]*)\}`)
+	latexParagraphRe = regexp.MustCompile(`\n[ \t]*\n\s*`)
+	latexItemRe      = regexp.MustCompile(`\s*\x01\s*`)
+	latexMathRe      = regexp.MustCompile(`(?s)\$\$.*?\$\$|\$(?:\\.|[^$\\])*\$|\\\[.*?\\\]|\\\(.*?\\\)|\\begin\{(?:equation|align|gather|multline|eqnarray)\*?\}.*?\\end\{(?:equation|align|gather|multline|eqnarray)\*?\}`)
+)
+
+// parseLatex builds the document from the expanded source. Figures and
+// tables become DocFigures, with Path set to the first \includegraphics
+// argument as written; everything from the bibliography on is dropped.
+func parseLatex(src string) *StructuredDocument {
+	doc := &StructuredDocument{}
+	if cmds := findLatexCommands(src, "title"); len(cmds) > 0 {
+		doc.Title = flattenText(latexToText(cmds[0].Arg))
+	}
+	var authors []string
+	for _, cmd := range findLatexCommands(src, "author") {
+		authors = append(authors, parseLatexAuthors(cmd.Arg)...)
+	}
+	doc.Authors = strings.Join(authors, ", ")
+
+	body := src
+	if i := strings.Index(body, `\begin{document}`); i >= 0 {
+		body = body[i+len(`\begin{document}`):]
+	}
+	for _, end := range []string{`\end{document}`, `\begin{thebibliography}`, `\bibliography{`, `\printbibliography`} {
+		if i := strings.Index(body, end); i >= 0 {
+			body = body[:i]
+		}
+	}
+
+	if m := latexAbstractRe.FindStringSubmatch(src); m != nil {
+		doc.Abstract = latexToText(m[1])
+		body = strings.Replace(body, m[0], "", 1)
+	}
+
+	// Theses and books use chapters as their top-level headings
+	headings := findLatexCommands(body, "section")
+	if len(headings) == 0 {
+		headings = findLatexCommands(body, "chapter")
+	}
+
+	var starts []int
+	addSection := func(start int, heading, text string) {
+		text = latexToText(latexFloatRe.ReplaceAllString(text, ""))
+		if heading == "" && text == "" {
+			return
+		}
+		doc.Sections = append(doc.Sections, DocSection{Heading: heading, Text: text})
+		starts = append(starts, start)
+	}
+	if len(headings) > 0 {
+		addSection(0, "", body[:headings[0].Start])
+	}
+	for i, h := range headings {
+		end := len(body)
+		if i+1 < len(headings) {
+			end = headings[i+1].Start
+		}
+		addSection(h.Start, flattenText(latexToText(h.Arg)), body[h.End:end])
+	}
+
+	for _, loc := range latexFloatRe.FindAllStringSubmatchIndex(body, -1) {
+		env, content := body[loc[2]:loc[3]], body[loc[4]:loc[5]]
+		fig := DocFigure{Kind: LayoutPicture, Section: -1}
+		if strings.HasPrefix(env, "table") || env == "wraptable" {
+			fig.Kind = LayoutTable
+		}
+		// Subfigures have captions of their own; the figure's caption comes last
+		if caps := findLatexCommands(content, "caption"); len(caps) > 0 {
+			fig.Caption = flattenText(latexToText(caps[len(caps)-1].Arg))
+		}
+		if graphics := findLatexCommands(content, "includegraphics"); len(graphics) > 0 {
+			fig.Path = strings.TrimSpace(graphics[0].Arg)
+		}
+		for i, start := range starts {
+			if start <= loc[0] {
+				fig.Section = i
+			}
+		}
+		doc.Figures = append(doc.Figures, fig)
+	}
+	return doc
+}
+
+// parseLatexAuthors returns the names in an \author argument, dropping
+// affiliations (after \\), footnote marks and \thanks
+func parseLatexAuthors(arg string) []string {
+	var names []string
+	for _, part := range latexAndRe.Split(arg, -1) {
+		part = cutLatexLineBreak(latexMathRe.ReplaceAllString(part, ""))
+		if name := strings.Trim(flattenText(latexToText(part)), " ,"); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// cutLatexLineBreak returns src up to its first \\ outside braces
+func cutLatexLineBreak(src string) string {
+	depth := 0
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '\\':
+			if depth == 0 && i+1 < len(src) && src[i+1] == '\\' {
+				return src[:i]
+			}
+			i++
+		}
+	}
+	return src
+}
+
+// parseGraphicsPath returns the directories listed in \graphicspath
+func parseGraphicsPath(src string) []string {
+	var dirs []string
+	for _, cmd := range findLatexCommands(src, "graphicspath") {
+		for _, m := range latexBraceRe.FindAllStringSubmatch(cmd.Arg, -1) {
+			dirs = append(dirs, strings.TrimSpace(m[1]))
+		}
+	}
+	return dirs
+}
+
+// resolveLatexGraphic finds an \includegraphics file the way graphicx does,
+// trying the known extensions when the name has none
+func resolveLatexGraphic(root string, dirs []string, name string) (string, bool) {
+	for _, dir := range append([]string{""}, dirs...) {
+		base := filepath.Join(filepath.FromSlash(dir), filepath.FromSlash(name))
+		if !filepath.IsLocal(base) {
+			continue
+		}
+		var candidates []string
+		if slices.Contains(latexGraphicExts, strings.ToLower(filepath.Ext(base))) {
+			candidates = append(candidates, base)
+		}
+		for _, ext := range latexGraphicExts {
+			candidates = append(candidates, base+ext)
+		}
+		for _, c := range candidates {
+			path := filepath.Join(root, c)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// latexCommand is one use of a command: its span in the source and its
+// first mandatory argument
+type latexCommand struct {
+	Start, End int
+	Arg        string
+}
+
+// findLatexCommands finds \name and \name* with a braced argument,
+// skipping optional arguments
+func findLatexCommands(src, name string) []latexCommand {
+	var cmds []latexCommand
+	needle := `\` + name
+	for i := 0; ; {
+		j := strings.Index(src[i:], needle)
+		if j < 0 {
+			return cmds
+		}
+		start := i + j
+		i = start + len(needle)
+		if i < len(src) && isLatexLetter(src[i]) {
+			continue // a longer command such as \sectionmark
+		}
+		if i < len(src) && src[i] == '*' {
+			i++
+		}
+		i = skipLatexOptions(src, i)
+		arg, end, ok := latexGroup(src, i)
+		if !ok {
+			continue
+		}
+		cmds = append(cmds, latexCommand{Start: start, End: end, Arg: arg})
+		i = end
+	}
+}
+
+// skipLatexOptions skips whitespace and [optional] arguments from i
+func skipLatexOptions(src string, i int) int {
+	for {
+		for i < len(src) && (src[i] == ' ' || src[i] == '\t' || src[i] == '\n') {
+			i++
+		}
+		if i >= len(src) || src[i] != '[' {
+			return i
+		}
+		depth := 0
+		for ; i < len(src); i++ {
+			if src[i] == '[' {
+				depth++
+			} else if src[i] == ']' {
+				depth--
+				if depth == 0 {
+					i++
+					break
+				}
+			}
+		}
+	}
+}
+
+// latexGroup returns the contents of the brace group opening at src[i] and
+// the index just after it
+func latexGroup(src string, i int) (string, int, bool) {
+	if i >= len(src) || src[i] != '{' {
+		return "", i, false
+	}
+	depth := 0
+	for j := i; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return src[i+1 : j], j + 1, true
+			}
+		}
+	}
+	return "", i, false
+}
+
+func isLatexLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '@'
+}
+
+// latexTextCommand says what latexToText does with a command: how many
+// mandatory arguments it takes, which one is kept in the text (-1 for none)
+// and what surrounds it
+type latexTextCommand struct {
+	args   int
+	keep   int
+	before string
+	after  string
+}
+
+var latexTextCommands = map[string]latexTextCommand{
+	"textbf": {1, 0, "", ""}, "textit": {1, 0, "", ""}, "emph": {1, 0, "", ""},
+	"texttt": {1, 0, "", ""}, "textsc": {1, 0, "", ""}, "underline": {1, 0, "", ""},
+	"textrm": {1, 0, "", ""}, "textsf": {1, 0, "", ""}, "mbox": {1, 0, "", ""},
+	"url": {1, 0, "", ""}, "href": {2, 1, "", ""}, "textcolor": {2, 1, "", ""},
+	"IEEEauthorblockN": {1, 0, "", ""}, "IEEEauthorblockA": {1, -1, "", ""},
+
+	"subsection": {1, 0, "\n\n", "\n\n"}, "subsubsection": {1, 0, "\n\n", "\n\n"},
+	"paragraph": {1, 0, "\n\n", ". "}, "item": {0, -1, "\x01", ""},
+	"par": {0, -1, "\n\n", ""}, "newline": {0, -1, "\n", ""},
+	"begin": {1, -1, "\n", ""}, "end": {1, -1, "\n", ""},
+
+	"cite": {1, -1, "", ""}, "citep": {1, -1, "", ""}, "citet": {1, -1, "", ""},
+	"ref": {1, -1, "", ""}, "eqref": {1, -1, "", ""}, "autoref": {1, -1, "", ""},
+	"cref": {1, -1, "", ""}, "Cref": {1, -1, "", ""}, "label": {1, -1, "", ""},
+	"footnote": {1, -1, "", ""}, "thanks": {1, -1, "", ""}, "inst": {1, -1, "", ""},
+	"affiliation": {1, -1, "", ""}, "affil": {1, -1, "", ""}, "institute": {1, -1, "", ""},
+	"address": {1, -1, "", ""}, "email": {1, -1, "", ""}, "orcid": {1, -1, "", ""},
+	"title": {1, -1, "", ""}, "author": {1, -1, "", ""}, "date": {1, -1, "", ""},
+	"keywords": {1, -1, "", ""}, "vspace": {1, -1, "", ""}, "hspace": {1, -1, "", ""},
+	"includegraphics": {1, -1, "", ""}, "caption": {1, -1, "", ""},
+	"IEEEauthorrefmark": {1, -1, "", ""}, "bibliographystyle": {1, -1, "", ""},
+
+	"LaTeX": {0, -1, "LaTeX", ""}, "TeX": {0, -1, "TeX", ""},
+	"ldots": {0, -1, "...", ""}, "dots": {0, -1, "...", ""},
+}
+
+// latexToText converts LaTeX markup to plain text for prompts. Math is kept
+// as written, which LLMs read well; formatting commands keep their text and
+// references, citations and layout commands are dropped.
+func latexToText(src string) string {
+	var math []string
+	src = latexMathRe.ReplaceAllStringFunc(src, func(m string) string {
+		math = append(math, m)
+		return "\x00" + strconv.Itoa(len(math)-1) + "\x00"
+	})
+
+	text := stripLatex(src)
+	for i, m := range math {
+		text = strings.Replace(text, "\x00"+strconv.Itoa(i)+"\x00", m, 1)
+	}
+	return normalizeLatexText(text)
+}
+
+// stripLatex removes commands and braces outside math
+func stripLatex(src string) string {
+	var sb strings.Builder
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\\' && i+1 < len(src) && !isLatexLetter(src[i+1]):
+			switch src[i+1] {
+			case '\\':
+				sb.WriteByte('\n')
+			case ',', ';', ' ', '!':
+				sb.WriteByte(' ')
+			default: // \& \% \_ \# \$ \{ \}
+				sb.WriteByte(src[i+1])
+			}
+			i += 2
+		case c == '\\':
+			j := i + 1
+			for j < len(src) && isLatexLetter(src[j]) {
+				j++
+			}
+			name := src[i+1 : j]
+			if j < len(src) && src[j] == '*' {
+				j++
+			}
+			spec, known := latexTextCommands[name]
+			if !known {
+				// Unknown commands are dropped and their arguments read as text
+				i = j
+				continue
+			}
+			j = skipLatexOptions(src, j)
+			var args []string
+			for k := 0; k < spec.args; k++ {
+				arg, end, ok := latexGroup(src, j)
+				if !ok {
+					break
+				}
+				args = append(args, arg)
+				j = skipLatexOptions(src, end)
+			}
+			sb.WriteString(spec.before)
+			if spec.keep >= 0 && spec.keep < len(args) {
+				sb.WriteString(stripLatex(args[spec.keep]))
+				sb.WriteString(spec.after)
+			}
+			i = j
+		case c == '{' || c == '}':
+			i++
+		case c == '~':
+			sb.WriteByte(' ')
+			i++
+		case strings.HasPrefix(src[i:], "``") || strings.HasPrefix(src[i:], "''"):
+			sb.WriteByte('"')
+			i += 2
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// normalizeLatexText joins source lines into paragraphs, keeping list
+// items (marked \x01 by stripLatex) on lines of their own
+func normalizeLatexText(text string) string {
+	text = latexItemRe.ReplaceAllString(text, "\n- ")
+	var paragraphs []string
+	for _, para := range latexParagraphRe.Split(text, -1) {
+		var sb strings.Builder
+		for _, line := range strings.Split(para, "\n") {
+			line = strings.Join(strings.Fields(line), " ")
+			if line == "" {
+				continue
+			}
+			if sb.Len() > 0 {
+				if strings.HasPrefix(line, "- ") {
+					sb.WriteByte('\n')
+				} else {
+					sb.WriteByte(' ')
+				}
+			}
+			sb.WriteString(line)
+		}
+		if sb.Len() > 0 {
+			paragraphs = append(paragraphs, sb.String())
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// flattenText puts text on a single line
+func flattenText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,101 @@
+package common
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testMainTex = `\documentclass{article}
+\usepackage{graphicx}
+\graphicspath{{figures/}}
+\title{Exact \textbf{Titles} \\ from Source}
+\author{Ada Lovelace\thanks{Analytical Engine Co.} \\ London \and Alan Turing$^{2}$}
+\begin{document}
+\maketitle
+\begin{abstract}
+We parse \emph{sources}. % not this
+\end{abstract}
+\section{Introduction}\label{sec:intro}
+Papers cite things~\cite{knuth} and use $E = mc^2$, costs of 5\%.
+\input{sections/method}
+\section{Experimental Results}
+\begin{figure}[t]
+  \centering
+  \includegraphics[width=\linewidth]{plot}
+  \caption{Accuracy over time.}
+\end{figure}
+\begin{itemize}
+  \item First finding
+  \item Second finding
+\end{itemize}
+\bibliography{refs}
+\end{document}
+`
+
+const testMethodTex = "\\section{Our Approach}\n\\subsection{Setup}\nWe train ``carefully''.\n"
+
+func TestParseLatexProject(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "paper.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"paper/main.tex":            testMainTex,
+		"paper/sections/method.tex": testMethodTex,
+		"paper/figures/plot.png":    "png",
+		"../escape.tex":             "outside",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	f.Close()
+
+	workDir := filepath.Join(dir, "work")
+	doc, err := ParseLatexProject(archive, workDir)
+	if err != nil {
+		t.Fatalf("ParseLatexProject: %v", err)
+	}
+
+	if doc.Title != "Exact Titles from Source" || doc.Authors != "Ada Lovelace, Alan Turing" || doc.Abstract != "We parse sources." {
+		t.Errorf("unexpected front matter %q / %q / %q", doc.Title, doc.Authors, doc.Abstract)
+	}
+	if len(doc.Sections) != 3 || doc.Sections[1].Heading != "Our Approach" {
+		t.Fatalf("unexpected sections %+v", doc.Sections)
+	}
+	if s := doc.Sections[0].Text; s != "Papers cite things and use $E = mc^2$, costs of 5%." {
+		t.Errorf("unexpected introduction %q", s)
+	}
+	if s := doc.Sections[1].Text; s != "Setup\n\nWe train \"carefully\"." {
+		t.Errorf("unexpected method %q", s)
+	}
+	if s := doc.Sections[2].Text; s != "- First finding\n- Second finding" {
+		t.Errorf("unexpected results %q", s)
+	}
+
+	if len(doc.Figures) != 1 {
+		t.Fatalf("unexpected figures %+v", doc.Figures)
+	}
+	fig := doc.Figures[0]
+	if fig.Caption != "Accuracy over time." || fig.Section != 2 || fig.Path != filepath.Join(workDir, "figures", "figure_01.png") {
+		t.Errorf("unexpected figure %+v", fig)
+	}
+	if images := doc.SectionImages(); len(images) != 1 || images[SecResults] != fig.Path {
+		t.Errorf("unexpected section images %v", images)
+	}
+	if strings.Contains(doc.Text(), "not this") {
+		t.Errorf("comment kept in %q", doc.Text())
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "escape.tex")); err == nil {
+		t.Errorf("archive entry extracted outside the project")
+	}
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"context"
+	"image"
+)
+
+// DefaultLayoutModelPath is the DocLayNet YOLOv8 model the pipelines look for
+const DefaultLayoutModelPath = "yolov8n-doclaynet.onnx"
+
+// DocLayNet layout classes, as named in ClassNames
+const (
+	LayoutCaption       = "Caption"
+	LayoutFootnote      = "Footnote"
+	LayoutFormula       = "Formula"
+	LayoutListItem      = "List-item"
+	LayoutPageFooter    = "Page-footer"
+	LayoutPageHeader    = "Page-header"
+	LayoutPicture       = "Picture"
+	LayoutSectionHeader = "Section-header"
+	LayoutTable         = "Table"
+	LayoutText          = "Text"
+	LayoutTitle         = "Title"
+)
+
+// LayoutBox is one detected region, in pixels of the image it was detected on
+type LayoutBox struct {
+	Class      string
+	Rect       image.Rectangle
+	Confidence float32
+}
+
+// LayoutDetector finds layout regions on a rendered page
+type LayoutDetector interface {
+	DetectLayout(ctx context.Context, img image.Image) ([]LayoutBox, error)
+}
+
+// LayoutModel is a LayoutDetector that holds a loaded model until closed
+type LayoutModel interface {
+	LayoutDetector
+	Close()
+}
+
+// LayoutModelLoader loads the layout model of settings. The DocLayNet model
+// lives in common/layout, as it needs OpenCV and ONNX Runtime; with a nil
+// loader PDFs fall back to font heuristics and have no cropped figures.
+type LayoutModelLoader func(settings LayoutSettings) (LayoutModel, error)
@@ -0,0 +1,185 @@
+package layout
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+	"gocv.io/x/gocv"
+
+	"saral_go_testing/common"
+)
+
+// Model is a common.LayoutDetector backed by the DocLayNet YOLOv8 ONNX model.
+// It lives outside common, which builds without OpenCV and ONNX Runtime.
+type Model struct {
+	ModelPath     string
+	ConfThreshold float32
+	NMSThreshold  float32
+	session       *ort.DynamicAdvancedSession
+}
+
+// The ONNX Runtime environment is process-wide, so models share it and the
+// last one closed tears it down
+var onnxEnv struct {
+	sync.Mutex
+	refs int
+}
+
+// acquireONNXRuntime loads the runtime from libPath, unless it is loaded
+func acquireONNXRuntime(libPath string) error {
+	onnxEnv.Lock()
+	defer onnxEnv.Unlock()
+
+	if onnxEnv.refs == 0 {
+		ort.SetSharedLibraryPath(libPath)
+		if err := ort.InitializeEnvironment(); err != nil {
+			return fmt.Errorf("failed to initialize ONNX Runtime: %w", err)
+		}
+	}
+	onnxEnv.refs++
+	return nil
+}
+
+func releaseONNXRuntime() {
+	onnxEnv.Lock()
+	defer onnxEnv.Unlock()
+
+	onnxEnv.refs--
+	if onnxEnv.refs == 0 {
+		ort.DestroyEnvironment()
+	}
+}
+
+// NewModel loads the DocLayNet model of settings
+func NewModel(settings common.LayoutSettings) (*Model, error) {
+	if err := acquireONNXRuntime(settings.ONNXLibrary); err != nil {
+		return nil, err
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(settings.ModelPath,
+		[]string{"images"}, []string{"output0"}, nil)
+	if err != nil {
+		releaseONNXRuntime()
+		return nil, fmt.Errorf("failed to create ONNX session: %w", err)
+	}
+
+	return &Model{
+		ModelPath:     settings.ModelPath,
+		ConfThreshold: float32(settings.ConfThreshold),
+		NMSThreshold:  float32(settings.NMSThreshold),
+		session:       session,
+	}, nil
+}
+
+// Load is a common.LayoutModelLoader for NewModel
+func Load(settings common.LayoutSettings) (common.LayoutModel, error) {
+	model, err := NewModel(settings)
+	if err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// Close releases the model
+func (m *Model) Close() {
+	if m.session != nil {
+		m.session.Destroy()
+		m.session = nil
+		releaseONNXRuntime()
+	}
+}
+
+// DetectLayout runs the model on a rendered page and returns the regions
+// left after non-maximum suppression
+func (m *Model) DetectLayout(ctx context.Context, img image.Image) ([]common.LayoutBox, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Preprocess with GoCV
+	mat, err := gocv.ImageToMatRGB(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert page image: %w", err)
+	}
+	defer mat.Close()
+
+	// Letterbox Resize
+	originalW, originalH := mat.Cols(), mat.Rows()
+	inputSize := 1024
+
+	scale := float64(inputSize) / float64(common.Max(originalW, originalH))
+	newW := int(float64(originalW) * scale)
+	newH := int(float64(originalH) * scale)
+
+	resized := gocv.NewMat()
+	defer resized.Close()
+	gocv.Resize(mat, &resized, image.Pt(newW, newH), 0, 0, gocv.InterpolationLinear)
+
+	canvas := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(114, 114, 114, 0), inputSize, inputSize, gocv.MatTypeCV8UC3)
+	defer canvas.Close()
+
+	dx := (inputSize - newW) / 2
+	dy := (inputSize - newH) / 2
+
+	roi := canvas.Region(image.Rect(dx, dy, dx+newW, dy+newH))
+	resized.CopyTo(&roi)
+	roi.Close()
+
+	// Prepare Tensor Data
+	bgr := gocv.Split(canvas)
+	defer bgr[0].Close()
+	defer bgr[1].Close()
+	defer bgr[2].Close()
+
+	inputData := make([]float32, 1*3*1024*1024)
+
+	for c := 0; c < 3; c++ {
+		fMat := gocv.NewMat()
+		bgr[c].ConvertTo(&fMat, gocv.MatTypeCV32F)
+		fMat.MultiplyFloat(1.0 / 255.0)
+
+		data, _ := fMat.DataPtrFloat32()
+		offset := c * 1024 * 1024
+		copy(inputData[offset:], data)
+		fMat.Close()
+	}
+
+	// Inference
+	inputTensor, err := ort.NewTensor(ort.NewShape(1, 3, 1024, 1024), inputData)
+	if err != nil {
+		return nil, err
+	}
+	defer inputTensor.Destroy()
+
+	outputData := make([]float32, 1*15*21504)
+	outputTensor, err := ort.NewTensor(ort.NewShape(1, 15, 21504), outputData)
+	if err != nil {
+		return nil, err
+	}
+	defer outputTensor.Destroy()
+
+	if err := m.session.Run([]ort.Value{inputTensor}, []ort.Value{outputTensor}); err != nil {
+		return nil, fmt.Errorf("layout inference failed: %w", err)
+	}
+
+	// Post-processing
+	boxes, classIds, confidences := common.ParseYOLOOutput(outputTensor.GetData(), originalW, originalH, dx, dy, scale, m.ConfThreshold)
+
+	var indices []int
+	if len(boxes) > 0 {
+		indices = gocv.NMSBoxes(boxes, confidences, m.ConfThreshold, m.NMSThreshold)
+	}
+
+	regions := make([]common.LayoutBox, 0, len(indices))
+	for _, idx := range indices {
+		regions = append(regions, common.LayoutBox{
+			Class:      common.ClassNames[classIds[idx]],
+			Rect:       boxes[idx],
+			Confidence: confidences[idx],
+		})
+	}
+	return regions, nil
+}
@@ -0,0 +1,337 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// LLM is a text generation backend. The pipelines only depend on this
+// interface, so the provider can be chosen per job.
+type LLM interface {
+	// GenerateText generates text from a prompt (generic method for custom prompts)
+	GenerateText(ctx context.Context, prompt string) (string, error)
+	// GenerateJSON generates a JSON document matching schema using the
+	// provider's JSON response mode
+	GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error)
+	ExtractMetadata(ctx context.Context, text string) (*PaperMetadata, error)
+	// CondensePaper shrinks papers longer than the input token budget with a
+	// map-reduce summarization, and returns shorter text unchanged
+	CondensePaper(ctx context.Context, text string) (string, error)
+	GenerateScript(ctx context.Context, text string) (string, error)
+	GenerateBulletPoints(ctx context.Context, sectionText string) ([]string, error)
+	GeneratePosterContent(ctx context.Context, text string) (*PosterContent, error)
+	Close()
+}
+
+// LLM providers selectable through PipelineConfig.LLMProvider
+const (
+	LLMGemini = "gemini"
+	LLMOpenAI = "openai" // any OpenAI-compatible endpoint, e.g. llama.cpp or Ollama
+	LLMFake   = "fake"   // deterministic canned responses, for tests
+)
+
+// NewLLM creates the LLM selected by config, defaulting to Gemini
+func NewLLM(config PipelineConfig) (LLM, error) {
+	settings := config.Settings.OrDefault()
+	switch config.LLMProvider {
+	case "", LLMGemini:
+		client, err := NewGeminiClient(config.GeminiKey, config.LLMModel)
+		if err != nil {
+			return nil, err
+		}
+		client.Cache = config.Cache
+		client.SetTemperature(float32(settings.LLM.Temperature))
+		client.maxInputTokens = config.MaxInputTokens
+		client.language = config.Language
+		client.callTimeout = settings.Timeouts.LLMCall
+		client.targetDuration = config.TargetDuration
+		client.poster = settings.Poster
+		return client, nil
+	case LLMOpenAI:
+		client := NewOpenAIClient(config.OpenAIBaseURL, config.OpenAIKey, config.LLMModel)
+		client.Cache = config.Cache
+		client.Temperature = settings.LLM.Temperature
+		client.maxInputTokens = config.MaxInputTokens
+		client.language = config.Language
+		client.callTimeout = settings.Timeouts.LLMCall
+		client.targetDuration = config.TargetDuration
+		client.poster = settings.Poster
+		return client, nil
+	case LLMFake:
+		client := NewFakeLLM()
+		client.maxInputTokens = config.MaxInputTokens
+		client.language = config.Language
+		client.targetDuration = config.TargetDuration
+		client.poster = settings.Poster
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider: %s", config.LLMProvider)
+	}
+}
+
+// llmHelpers implements the structured LLM methods on top of a provider's
+// GenerateText and GenerateJSON, so every provider shares the same prompts
+// and validation
+type llmHelpers struct {
+	generate     func(ctx context.Context, prompt string) (string, error)
+	generateJSON func(ctx context.Context, prompt string, schema *Schema) (string, error)
+	countTokens  func(ctx context.Context, text string) (int, error) // Optional, EstimateTokens if nil
+
+	maxInputTokens int            // CondensePaper budget, DefaultMaxInputTokens if zero
+	language       string         // script and bullet language, DefaultLanguage if empty
+	callTimeout    time.Duration  // bounds each provider call, no limit if zero
+	targetDuration time.Duration  // aimed narration length of GenerateScript, unset if zero
+	poster         PosterSettings // size and columns GeneratePosterContent fills, the defaults if zero
+}
+
+// withCallTimeout runs one provider call under the LLM call timeout
+func (h llmHelpers) withCallTimeout(ctx context.Context, call func(ctx context.Context) (string, error)) (string, error) {
+	var text string
+	err := WithTimeout(ctx, StageLLM, h.callTimeout, func(ctx context.Context) error {
+		var err error
+		text, err = call(ctx)
+		return err
+	})
+	return text, err
+}
+
+// cachedGenerate serves a response from the cache, or calls generate and caches its result
+func cachedGenerate(cache *Cache, key string, generate func() (string, error)) (string, error) {
+	if data, ok := cache.Get(key); ok {
+		return string(data), nil
+	}
+
+	text, err := generate()
+	if err != nil {
+		return "", err
+	}
+
+	if err := cache.Put(key, []byte(text)); err != nil {
+		log.Printf("Warning: failed to cache LLM response: %v", err)
+	}
+	return text, nil
+}
+
+// PaperMetadata holds extracted paper information
+type PaperMetadata struct {
+	Title   string `json:"title"`
+	Authors string `json:"authors"`
+	Date    string `json:"date,omitempty"`  // YYYY-MM-DD, only known for fetched papers
+	Venue   string `json:"venue,omitempty"` // Journal or conference, only known for fetched papers
+}
+
+var paperMetadataSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"title":   {Type: "string", Description: "Paper title"},
+		"authors": {Type: "string", Description: "Author names separated by commas"},
+	},
+	Required: []string{"title", "authors"},
+}
+
+func (m *PaperMetadata) Validate() error {
+	if strings.TrimSpace(m.Title) == "" {
+		return fmt.Errorf("title must not be empty")
+	}
+	if strings.TrimSpace(m.Authors) == "" {
+		return fmt.Errorf("authors must not be empty")
+	}
+	return nil
+}
+
+// ExtractMetadata extracts title and authors from paper text
+func (h llmHelpers) ExtractMetadata(ctx context.Context, text string) (*PaperMetadata, error) {
+	// Limit text to first 2000 chars (metadata is usually at the start)
+	text = TruncateUTF8(text, 2000)
+
+	prompt := fmt.Sprintf(`Extract the title and authors from this research paper text.
+
+Return a JSON object with "title" and "authors" (author names separated by commas).
+If you cannot find the title, use "Research Paper".
+If you cannot find authors, use "Authors".
+
+Text:
+%s`, text)
+
+	metadata := &PaperMetadata{}
+	if err := generateStructured(ctx, h.generateJSON, prompt, paperMetadataSchema, metadata); err != nil {
+		return &PaperMetadata{Title: "Research Paper", Authors: "Authors"}, err
+	}
+	return metadata, nil
+}
+
+// GenerateScript generates a video script from text (for video pipeline)
+func (h llmHelpers) GenerateScript(ctx context.Context, text string) (string, error) {
+	prompt := fmt.Sprintf(`
+You are an expert scriptwriter for educational videos. 
+Convert the following research paper text into an engaging video script.
+The script should be divided into clear sections: Introduction, Methodology, Results, Discussion, Conclusion.
+Write in a conversational, easy-to-understand tone.
+Do not include any visual cues or camera directions, just the spoken narration.
+Make it engaging and flow well.
+%s%s
+Text:
+%s
+	`, scriptLanguageInstruction(h.language), ScriptLengthInstruction(h.targetDuration), text)
+
+	return h.generate(ctx, prompt)
+}
+
+// scriptLanguageInstruction asks for the narration in language while keeping
+// the English section headings ParseScriptToSections looks for
+func scriptLanguageInstruction(language string) string {
+	instruction := LanguageInstruction(language, "the narration")
+	if instruction == "" {
+		return ""
+	}
+	return instruction + "Keep the section headings (" + strings.Join(SectionOrder(), ", ") + ") in English, each on its own line.\n"
+}
+
+// NarrationWordsPerMinute is the speaking rate used to turn a target
+// duration into a word budget
+const NarrationWordsPerMinute = 150
+
+// ScriptLengthInstruction asks for narration that takes about d to speak,
+// or nothing if d is zero
+func ScriptLengthInstruction(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	length := fmt.Sprintf("%d seconds", int(d.Seconds()))
+	if d >= 2*time.Minute {
+		length = fmt.Sprintf("%d minutes", int(d.Round(time.Minute).Minutes()))
+	}
+	words := int(d.Minutes() * NarrationWordsPerMinute)
+	return fmt.Sprintf("The narration should take about %s to speak, roughly %d words in total.\n", length, words)
+}
+
+// bulletList is the JSON shape of GenerateBulletPoints responses
+type bulletList struct {
+	Bullets []string `json:"bullets"`
+}
+
+var bulletListSchema = &Schema{
+	Type:       "object",
+	Properties: map[string]*Schema{"bullets": stringList("3-5 concise slide bullet points")},
+	Required:   []string{"bullets"},
+}
+
+func (b *bulletList) Validate() error {
+	return requireItems("bullets", b.Bullets, 1, 8)
+}
+
+// GenerateBulletPoints generates bullet points for slides
+func (h llmHelpers) GenerateBulletPoints(ctx context.Context, sectionText string) ([]string, error) {
+	prompt := fmt.Sprintf(`
+Summarize the following text into 3-5 concise bullet points suitable for a presentation slide.
+Return a JSON object with a "bullets" array of strings, without leading dashes.
+%s
+Text:
+%s
+	`, LanguageInstruction(h.language, "the bullet points"), sectionText)
+
+	var result bulletList
+	if err := generateStructured(ctx, h.generateJSON, prompt, bulletListSchema, &result); err != nil {
+		return nil, err
+	}
+	return result.Bullets, nil
+}
+
+// GeneratePosterContent generates structured content for a poster
+func (h llmHelpers) GeneratePosterContent(ctx context.Context, text string) (*PosterContent, error) {
+	prompt := fmt.Sprintf(`
+You are an expert at creating academic research posters. 
+Analyze the following research paper text and generate content suitable for %s
+
+Return a JSON object with these fields:
+
+title: Generate a concise, impactful title
+authors: Extract or generate appropriate author names/affiliations
+abstract: Write a concise 3-4 sentence abstract summarizing the key problem, approach, and main result.
+introduction: Write exactly 3 concise bullet points (one sentence each) introducing the problem and motivation.
+methodology: Write exactly 3 concise bullet points (one sentence each) describing the key methods used.
+results: Write exactly 3 concise bullet points (one sentence each) highlighting the main findings with specific metrics.
+conclusion: Write exactly 2-3 concise bullet points (one sentence each) summarizing key takeaways and future work.
+references: List 4-5 key references if identifiable from the text
+
+Each bullet point must be exactly ONE sentence - concise and focused, without a leading dash.
+Prioritize clarity and brevity to fit poster space constraints.
+
+Text:
+%s
+	`, posterLayoutInstruction(h.poster), text)
+
+	content := &PosterContent{}
+	if err := generateStructured(ctx, h.generateJSON, prompt, posterContentSchema, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// posterLayoutInstruction describes the poster GeneratePosterContent fills,
+// so the amount of content suits its size and columns
+func posterLayoutInstruction(poster PosterSettings) string {
+	if poster.Columns == 0 {
+		poster = DefaultSettings().Poster
+	}
+	layout := fmt.Sprintf("%d-column academic poster (%dcm x %dcm).", poster.Columns, poster.Width, poster.Height)
+	if poster.Width*poster.Height < 5000 {
+		return "a compact " + layout + "\n\nIMPORTANT: The poster has little space. Keep the content SHORT and focused.\n"
+	}
+	return "a large " + layout + "\n\nIMPORTANT: The poster has significant space to fill. Generate DETAILED and COMPREHENSIVE content.\n"
+}
+
+// PosterContent holds structured poster content
+type PosterContent struct {
+	Title        string   `json:"title"`
+	Authors      string   `json:"authors"`
+	Abstract     string   `json:"abstract"`
+	Introduction []string `json:"introduction"`
+	Methodology  []string `json:"methodology"`
+	Results      []string `json:"results"`
+	Conclusion   []string `json:"conclusion"`
+	References   []string `json:"references"`
+}
+
+var posterContentSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"title":        {Type: "string"},
+		"authors":      {Type: "string"},
+		"abstract":     {Type: "string"},
+		"introduction": stringList("3 one-sentence bullet points"),
+		"methodology":  stringList("3 one-sentence bullet points"),
+		"results":      stringList("3 one-sentence bullet points"),
+		"conclusion":   stringList("2-3 one-sentence bullet points"),
+		"references":   stringList("4-5 key references"),
+	},
+	Required: []string{"title", "authors", "abstract", "introduction", "methodology", "results", "conclusion", "references"},
+}
+
+// Validate checks the fields the poster template needs are present. References
+// may be empty when none are identifiable.
+func (c *PosterContent) Validate() error {
+	if strings.TrimSpace(c.Title) == "" {
+		return fmt.Errorf("title must not be empty")
+	}
+	if strings.TrimSpace(c.Abstract) == "" {
+		return fmt.Errorf("abstract must not be empty")
+	}
+	for _, section := range []struct {
+		name  string
+		items []string
+	}{
+		{"introduction", c.Introduction},
+		{"methodology", c.Methodology},
+		{"results", c.Results},
+		{"conclusion", c.Conclusion},
+	} {
+		if err := requireItems(section.name, section.items, 1, 6); err != nil {
+			return err
+		}
+	}
+	return requireItems("references", c.References, 0, 10)
+}
@@ -0,0 +1,112 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+)
+
+// Schema describes the JSON document an LLM must return. It is a subset of
+// JSON Schema that both Gemini's response schema and OpenAI's json_schema
+// response format accept.
+type Schema struct {
+	Type        string             `json:"type"` // "object", "array", "string", "number", "integer" or "boolean"
+	Description string             `json:"description,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+}
+
+// Validator is implemented by structured LLM results that can check
+// themselves after decoding
+type Validator interface {
+	Validate() error
+}
+
+// maxRepairAttempts bounds how often an invalid JSON response is sent back
+// to the model for repair
+const maxRepairAttempts = 2
+
+// GenerateStructured asks llm for JSON matching schema, decodes it into out
+// and validates it. Responses that fail to decode or validate are sent back
+// with a repair prompt describing the problem.
+func GenerateStructured(ctx context.Context, llm LLM, prompt string, schema *Schema, out Validator) error {
+	return generateStructured(ctx, llm.GenerateJSON, prompt, schema, out)
+}
+
+func generateStructured(ctx context.Context, generate func(context.Context, string, *Schema) (string, error), prompt string, schema *Schema, out Validator) error {
+	request := prompt
+	for attempt := 0; ; attempt++ {
+		response, err := generate(ctx, request, schema)
+		if err != nil {
+			return err
+		}
+
+		err = decodeStructured(response, out)
+		if err == nil {
+			return nil
+		}
+		if attempt == maxRepairAttempts {
+			return fmt.Errorf("invalid structured response after %d attempts: %w", attempt+1, err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Printf("Warning: LLM returned invalid JSON (%v), asking for a repair", err)
+		request = repairPrompt(prompt, response, err)
+	}
+}
+
+// decodeStructured resets out, decodes response into it and validates it
+func decodeStructured(response string, out Validator) error {
+	reflect.ValueOf(out).Elem().SetZero()
+	if err := json.Unmarshal([]byte(stripCodeFence(response)), out); err != nil {
+		return fmt.Errorf("malformed JSON: %w", err)
+	}
+	return out.Validate()
+}
+
+func repairPrompt(prompt, response string, problem error) string {
+	return fmt.Sprintf(`%s
+
+Your previous response was rejected: %v
+
+Previous response:
+%s
+
+Return only the corrected JSON document.`, prompt, problem, response)
+}
+
+// stripCodeFence removes a markdown code fence some models wrap around JSON
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimPrefix(text, "json")
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// requireItems checks a string list has between minItems and maxItems non-empty items
+func requireItems(name string, items []string, minItems, maxItems int) error {
+	if len(items) < minItems || len(items) > maxItems {
+		return fmt.Errorf("%s must have %d-%d items, got %d", name, minItems, maxItems, len(items))
+	}
+	for i, item := range items {
+		if strings.TrimSpace(item) == "" {
+			return fmt.Errorf("%s[%d] must not be empty", name, i)
+		}
+	}
+	return nil
+}
+
+// stringList is a schema for an array of strings
+func stringList(description string) *Schema {
+	return &Schema{Type: "array", Description: description, Items: &Schema{Type: "string"}}
+}
@@ -0,0 +1,188 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFakeLLM(t *testing.T) {
+	ctx := context.Background()
+	llm, err := NewLLM(PipelineConfig{LLMProvider: LLMFake})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer llm.Close()
+
+	metadata, err := llm.ExtractMetadata(ctx, "paper text")
+	if err != nil || metadata.Title != "A Fake Paper for Testing" {
+		t.Errorf("unexpected metadata %+v, err %v", metadata, err)
+	}
+
+	script, err := llm.GenerateScript(ctx, "paper text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sections := ParseScriptToSections(script); len(sections) != len(SectionOrder()) {
+		t.Errorf("expected %d sections, got %d", len(SectionOrder()), len(sections))
+	}
+
+	bullets, err := llm.GenerateBulletPoints(ctx, "section text")
+	if err != nil || len(bullets) != 3 {
+		t.Errorf("expected 3 bullets, got %v (err %v)", bullets, err)
+	}
+
+	content, err := llm.GeneratePosterContent(ctx, "paper text")
+	if err != nil || len(content.Results) != 3 || content.Abstract == "" {
+		t.Errorf("unexpected poster content %+v, err %v", content, err)
+	}
+
+	fake := llm.(*FakeLLM)
+	fake.Responses["custom"] = "custom reply"
+	if reply, _ := fake.GenerateText(ctx, "a custom prompt"); reply != "custom reply" {
+		t.Errorf("expected configured reply, got %q", reply)
+	}
+	if n := len(fake.Prompts()); n != 5 {
+		t.Errorf("expected 5 recorded prompts, got %d", n)
+	}
+}
+
+func TestLLMLanguage(t *testing.T) {
+	ctx := context.Background()
+	llm, err := NewLLM(PipelineConfig{LLMProvider: LLMFake, Language: "tamil"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer llm.Close()
+
+	llm.GenerateScript(ctx, "paper text")
+	llm.GenerateBulletPoints(ctx, "section text")
+	prompts := llm.(*FakeLLM).Prompts()
+	if len(prompts) != 2 || !strings.Contains(prompts[0], "narration in Tamil") || !strings.Contains(prompts[0], "headings (Introduction,") || !strings.Contains(prompts[1], "bullet points in Tamil") {
+		t.Errorf("expected Tamil instructions in %q", prompts)
+	}
+
+	if GetLanguageCode("Tamil") != "ta-IN" || ValidateLanguage("klingon") == nil || LanguageInstruction("", "x") != "" {
+		t.Error("unexpected language lookup")
+	}
+}
+
+func TestLLMPosterLayout(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Poster = PosterSettings{Width: 60, Height: 40, Columns: 2}
+	llm, err := NewLLM(PipelineConfig{LLMProvider: LLMFake, Settings: &settings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer llm.Close()
+
+	llm.GeneratePosterContent(context.Background(), "paper text")
+	if prompt := llm.(*FakeLLM).Prompts()[0]; !strings.Contains(prompt, "a compact 2-column academic poster (60cm x 40cm)") {
+		t.Errorf("poster size missing from %q", prompt)
+	}
+}
+
+func TestGenerateStructuredRepair(t *testing.T) {
+	fake := NewFakeLLM()
+	fake.Responses["Extract the title"] = `{"title": "", "authors": "Someone"}`
+	fake.Responses["Your previous response was rejected"] = "```json\n{\"title\": \"Fixed\", \"authors\": \"Someone\"}\n```"
+
+	metadata, err := fake.ExtractMetadata(context.Background(), "paper text")
+	if err != nil || metadata.Title != "Fixed" {
+		t.Fatalf("unexpected metadata %+v, err %v", metadata, err)
+	}
+	prompts := fake.Prompts()
+	if len(prompts) != 2 || !strings.Contains(prompts[1], "title must not be empty") {
+		t.Errorf("expected one repair prompt naming the violation, got %q", prompts)
+	}
+
+	fake.Responses["Your previous response was rejected"] = "not json"
+	if _, err := fake.ExtractMetadata(context.Background(), "other text"); err == nil {
+		t.Error("expected an error once repairs are exhausted")
+	}
+}
+
+func TestOpenAIClient(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+
+		var req openAIChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "local-model" || len(req.Messages) != 1 || req.ResponseFormat == nil || req.ResponseFormat.Type != "json_schema" {
+			t.Errorf("unexpected request body %+v", req)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"title\":\"Local\",\"authors\":\"Someone\"}"}}]}`))
+	}))
+	defer srv.Close()
+
+	cache, err := NewCache(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	llm, err := NewLLM(PipelineConfig{
+		LLMProvider:   LLMOpenAI,
+		LLMModel:      "local-model",
+		OpenAIKey:     "key",
+		OpenAIBaseURL: srv.URL + "/v1/",
+		Cache:         cache,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		metadata, err := llm.ExtractMetadata(context.Background(), "paper text")
+		if err != nil || metadata.Title != "Local" || metadata.Authors != "Someone" {
+			t.Fatalf("unexpected metadata %+v, err %v", metadata, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the repeated prompt to be served from cache, got %d calls", calls)
+	}
+}
+
+func TestCondensePaper(t *testing.T) {
+	ctx := context.Background()
+	llm, err := NewLLM(PipelineConfig{LLMProvider: LLMFake, MaxInputTokens: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := llm.(*FakeLLM)
+
+	short := "A short paper."
+	if text, err := llm.CondensePaper(ctx, short); err != nil || text != short {
+		t.Errorf("expected short text unchanged, got %q (err %v)", text, err)
+	}
+
+	// ~15600 tokens of Devanagari lines: five map chunks, none split mid-rune
+	long := strings.Repeat(strings.Repeat("परिणाम ", 50)+"\n", 50)
+	text, err := llm.CondensePaper(ctx, long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fake.Prompts()); n != 5 {
+		t.Errorf("expected 5 condense prompts, got %d", n)
+	}
+	for _, prompt := range fake.Prompts() {
+		if !utf8.ValidString(prompt) {
+			t.Errorf("chunk was split mid-rune")
+		}
+	}
+	if !strings.Contains(text, "A condensed excerpt") || EstimateTokens(text) > 5000 {
+		t.Errorf("unexpected condensed text %q", text)
+	}
+
+	if got := TruncateUTF8("नमस्ते", 4); got != "न" {
+		t.Errorf("expected truncation at a rune boundary, got %q", got)
+	}
+}
@@ -0,0 +1,226 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ManifestFile is the name of the checkpoint manifest inside a pipeline's OutputDir
+const ManifestFile = "manifest.json"
+
+// Manifest records which pipeline stages have completed and the artifacts they
+// produced, so a rerun over the same OutputDir can skip finished work.
+// Done, Checkpoint and Invalidate are safe to call on a nil manifest.
+type Manifest struct {
+	PDFPath    string `json:"pdf_path"`
+	SourceType string `json:"source_type,omitempty"`
+	SourcePath string `json:"source_path,omitempty"`
+	Mode       string `json:"mode"`
+	LLM        string `json:"llm,omitempty"`
+	LLMModel   string `json:"llm_model,omitempty"`
+	Language   string `json:"language,omitempty"`
+	AvatarPair string `json:"avatar_pair,omitempty"` // reel only
+
+	BurnSubtitles bool           `json:"burn_subtitles,omitempty"` // video and reel
+	SubtitleStyle *SubtitleStyle `json:"subtitle_style,omitempty"` // video and reel, if not the default
+
+	Stages map[string]StageRecord `json:"stages"`
+
+	outputDir string
+	mu        sync.Mutex
+}
+
+// StageRecord describes one completed stage
+type StageRecord struct {
+	CompletedAt time.Time        `json:"completed_at"`
+	Artifacts   []ArtifactRecord `json:"artifacts,omitempty"`
+}
+
+// ArtifactRecord identifies a file produced by a stage, relative to OutputDir
+type ArtifactRecord struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// LoadManifest reads the manifest in outputDir, or returns an empty one if none exists
+func LoadManifest(outputDir string) (*Manifest, error) {
+	m := &Manifest{Stages: make(map[string]StageRecord), outputDir: outputDir}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, ManifestFile))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("corrupt manifest: %w", err)
+	}
+	if m.Stages == nil {
+		m.Stages = make(map[string]StageRecord)
+	}
+	return m, nil
+}
+
+// Done reports whether a stage completed and all of its artifacts are still
+// present with the recorded size and checksum
+func (m *Manifest) Done(stage string) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	record, ok := m.Stages[stage]
+	m.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	for _, a := range record.Artifacts {
+		path := filepath.Join(m.outputDir, filepath.FromSlash(a.Path))
+		info, err := os.Stat(path)
+		if err != nil || info.Size() != a.Size {
+			return false
+		}
+		sum, err := fileSHA256(path)
+		if err != nil || sum != a.SHA256 {
+			return false
+		}
+	}
+	return true
+}
+
+// Complete records a stage as finished along with the artifacts it produced.
+// Artifacts must live inside OutputDir.
+func (m *Manifest) Complete(stage string, artifacts ...string) error {
+	record := StageRecord{CompletedAt: time.Now()}
+
+	absOut, err := filepath.Abs(m.outputDir)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range artifacts {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(absOut, absPath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("artifact %s is outside the output dir", path)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("artifact %s missing: %w", path, err)
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		record.Artifacts = append(record.Artifacts, ArtifactRecord{Path: filepath.ToSlash(rel), Size: info.Size(), SHA256: sum})
+	}
+
+	m.mu.Lock()
+	m.Stages[stage] = record
+	m.mu.Unlock()
+	return m.Save()
+}
+
+// Checkpoint is Complete for callers that should carry on if the manifest
+// cannot be written; the stage will simply be redone on resume
+func (m *Manifest) Checkpoint(stage string, artifacts ...string) {
+	if m == nil {
+		return
+	}
+	if err := m.Complete(stage, artifacts...); err != nil {
+		log.Printf("Warning: failed to checkpoint stage %s: %v", stage, err)
+	}
+}
+
+// Invalidate forgets every stage whose name starts with one of the prefixes.
+// Used when an upstream stage is rerun and its dependents are stale.
+func (m *Manifest) Invalidate(prefixes ...string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	changed := false
+	for stage := range m.Stages {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(stage, prefix) {
+				delete(m.Stages, stage)
+				changed = true
+				break
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	if changed {
+		if err := m.Save(); err != nil {
+			log.Printf("Warning: failed to save manifest: %v", err)
+		}
+	}
+}
+
+// Save writes the manifest to OutputDir
+func (m *Manifest) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(m.outputDir, ManifestFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// WriteJSON writes v as indented JSON, for stages whose output is structured data
+func WriteJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadJSON reads a file written by WriteJSON into v
+func ReadJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestDone(t *testing.T) {
+	dir := t.TempDir()
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "script.json")
+	os.WriteFile(script, []byte(`{"intro": "hello"}`), 0644)
+	if err := m.Complete("script", script); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Done("script") || m.Done("audio") {
+		t.Fatalf("unexpected stages done: %v", m.Stages)
+	}
+
+	// An artifact changed behind the manifest's back, even at the same size,
+	// redoes its stage
+	os.WriteFile(script, []byte(`{"intro": "world"}`), 0644)
+	if m.Done("script") {
+		t.Error("stage done with a modified artifact")
+	}
+	os.Remove(script)
+	if m.Done("script") {
+		t.Error("stage done with a missing artifact")
+	}
+
+	outside := filepath.Join(t.TempDir(), "other.json")
+	os.WriteFile(outside, nil, 0644)
+	if err := m.Complete("other", outside); err == nil {
+		t.Error("artifact outside the output dir accepted")
+	}
+
+	var nilManifest *Manifest
+	nilManifest.Checkpoint("script")
+	nilManifest.Invalidate("script")
+	if nilManifest.Done("script") {
+		t.Error("nil manifest has stages done")
+	}
+}
+
+func TestManifestInvalidate(t *testing.T) {
+	m, _ := LoadManifest(t.TempDir())
+	for _, stage := range []string{"script", "audio:0", "audio:1", "audiobook", "final"} {
+		m.Checkpoint(stage)
+	}
+	m.Invalidate("audio:", "final")
+	for stage, want := range map[string]bool{"script": true, "audio:0": false, "audio:1": false, "audiobook": true, "final": false} {
+		if m.Done(stage) != want {
+			t.Errorf("stage %s done = %v, want %v", stage, !want, want)
+		}
+	}
+}
+
+func TestManifestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	m, _ := LoadManifest(dir)
+	m.PDFPath = "paper.pdf"
+	m.Mode = "reel"
+	m.LLM, m.LLMModel = LLMOpenAI, "llama3"
+	m.Language = "hindi"
+	m.AvatarPair = "scientists"
+	m.BurnSubtitles = true
+	m.SubtitleStyle = &SubtitleStyle{Size: 20, Position: "top"}
+	dialogue := filepath.Join(dir, "reel", "dialogue.json")
+	WriteJSON(dialogue, []string{"hi"})
+	m.Checkpoint("dialogue", dialogue)
+
+	loaded, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.PDFPath != m.PDFPath || loaded.Mode != m.Mode || loaded.LLM != m.LLM || loaded.LLMModel != m.LLMModel ||
+		loaded.Language != m.Language || loaded.AvatarPair != m.AvatarPair || !loaded.BurnSubtitles ||
+		loaded.SubtitleStyle == nil || *loaded.SubtitleStyle != *m.SubtitleStyle {
+		t.Errorf("manifest not restored: %+v", loaded)
+	}
+	if record := loaded.Stages["dialogue"]; !loaded.Done("dialogue") || len(record.Artifacts) != 1 || record.Artifacts[0].Path != "reel/dialogue.json" {
+		t.Errorf("dialogue stage not restored: %+v", record)
+	}
+
+	os.WriteFile(filepath.Join(dir, ManifestFile), []byte("{"), 0644)
+	if _, err := LoadManifest(dir); err == nil {
+		t.Error("corrupt manifest loaded")
+	}
+}
@@ -0,0 +1,172 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Defaults for the OpenAI-compatible client
+const (
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+	DefaultOpenAIModel   = "gpt-4o-mini"
+)
+
+// OpenAIClient is an LLM backed by any OpenAI-compatible chat completions
+// endpoint, including local servers such as llama.cpp and Ollama
+type OpenAIClient struct {
+	llmHelpers
+	Cache *Cache // Optional, reuses responses to identical prompts
+
+	BaseURL     string
+	APIKey      string // Optional for local servers
+	Model       string
+	Temperature float64
+	client      *http.Client
+}
+
+// NewOpenAIClient creates a client; empty baseURL and model use the OpenAI defaults
+func NewOpenAIClient(baseURL, apiKey, model string) *OpenAIClient {
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+
+	c := &OpenAIClient{
+		BaseURL:     strings.TrimSuffix(baseURL, "/"),
+		APIKey:      apiKey,
+		Model:       model,
+		Temperature: 0.7,
+		client:      &http.Client{Timeout: 5 * time.Minute},
+	}
+	c.llmHelpers = llmHelpers{generate: c.GenerateText, generateJSON: c.GenerateJSON}
+	return c
+}
+
+func (c *OpenAIClient) Close() {}
+
+type openAIChatRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIChatMessage   `json:"messages"`
+	Temperature    float64               `json:"temperature"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string `json:"type"` // "json_schema"
+	JSONSchema struct {
+		Name   string  `json:"name"`
+		Schema *Schema `json:"schema"`
+	} `json:"json_schema"`
+}
+
+type openAIChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// GenerateText generates text from a prompt, serving repeated prompts from the cache
+func (c *OpenAIClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	key := CacheKey("openai", c.BaseURL, c.Model, fmt.Sprint(c.Temperature), prompt)
+	return cachedGenerate(c.Cache, key, func() (string, error) {
+		return c.chat(ctx, prompt, nil)
+	})
+}
+
+// GenerateJSON generates a JSON document using the json_schema response format
+func (c *OpenAIClient) GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	format := &openAIResponseFormat{Type: "json_schema"}
+	format.JSONSchema.Name = "response"
+	format.JSONSchema.Schema = schema
+	schemaJSON, err := json.Marshal(format)
+	if err != nil {
+		return "", err
+	}
+
+	key := CacheKey("openai-json", c.BaseURL, c.Model, fmt.Sprint(c.Temperature), string(schemaJSON), prompt)
+	return cachedGenerate(c.Cache, key, func() (string, error) {
+		return c.chat(ctx, prompt, format)
+	})
+}
+
+// chat makes one chat completion call, retrying within the LLM call timeout
+func (c *OpenAIClient) chat(ctx context.Context, prompt string, format *openAIResponseFormat) (string, error) {
+	return c.withCallTimeout(ctx, func(ctx context.Context) (string, error) {
+		return c.complete(ctx, prompt, format)
+	})
+}
+
+func (c *OpenAIClient) complete(ctx context.Context, prompt string, format *openAIResponseFormat) (string, error) {
+	payload, err := json.Marshal(openAIChatRequest{
+		Model:          c.Model,
+		Messages:       []openAIChatMessage{{Role: "user", Content: prompt}},
+		Temperature:    c.Temperature,
+		ResponseFormat: format,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var resp *http.Response
+
+	// Retry rate limits, server errors and dropped connections
+	for attempts := 0; ; attempts++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/chat/completions", bytes.NewReader(payload))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.APIKey)
+		}
+
+		resp, err = c.client.Do(req)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		retryable := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retryable || attempts == 2 {
+			if err != nil {
+				return "", fmt.Errorf("openai request failed: %w", err)
+			}
+			break
+		}
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(time.Duration(attempts+1) * 2 * time.Second):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("openai API error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var result openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid openai response: %w", err)
+	}
+	if len(result.Choices) == 0 || result.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("empty response from %s", c.Model)
+	}
+	return result.Choices[0].Message.Content, nil
+}
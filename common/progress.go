@@ -0,0 +1,69 @@
+package common
+
+import "sync"
+
+// ProgressEvent describes where a pipeline is in its work
+type ProgressEvent struct {
+	Stage      string  `json:"stage"`           // e.g. "script", "segments"
+	Step       int     `json:"step"`            // 1-based index of the stage
+	TotalSteps int     `json:"total_steps"`     // number of stages in the pipeline
+	Item       string  `json:"item,omitempty"`  // what is being counted, e.g. "audio"
+	Done       int     `json:"done,omitempty"`  // items finished in this stage
+	Total      int     `json:"total,omitempty"` // items expected in this stage
+	Percent    float64 `json:"percent"`         // overall completion, 0-100
+}
+
+// ProgressFunc receives progress events from a pipeline
+type ProgressFunc func(ProgressEvent)
+
+// ProgressReporter tracks the current stage of a pipeline and emits events.
+// A nil reporter or nil callback is valid and reports nothing.
+type ProgressReporter struct {
+	fn         ProgressFunc
+	totalSteps int
+	current    ProgressEvent
+	mu         sync.Mutex
+}
+
+// NewProgressReporter creates a reporter for a pipeline with totalSteps stages
+func NewProgressReporter(fn ProgressFunc, totalSteps int) *ProgressReporter {
+	return &ProgressReporter{fn: fn, totalSteps: totalSteps}
+}
+
+// Stage marks the start of a new stage
+func (r *ProgressReporter) Stage(step int, name string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.current = ProgressEvent{Stage: name, Step: step, TotalSteps: r.totalSteps}
+	r.emitLocked()
+	r.mu.Unlock()
+}
+
+// Item reports per-item progress within the current stage, e.g. "audio" 3/5
+func (r *ProgressReporter) Item(item string, done, total int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.current.Item = item
+	r.current.Done = done
+	r.current.Total = total
+	r.emitLocked()
+	r.mu.Unlock()
+}
+
+func (r *ProgressReporter) emitLocked() {
+	e := r.current
+	if e.TotalSteps > 0 && e.Step > 0 {
+		fraction := 0.0
+		if e.Total > 0 {
+			fraction = float64(e.Done) / float64(e.Total)
+		}
+		e.Percent = (float64(e.Step-1) + fraction) / float64(e.TotalSteps) * 100
+	}
+	if r.fn != nil {
+		r.fn(e)
+	}
+}
@@ -0,0 +1,31 @@
+package common
+
+import "testing"
+
+func TestProgressReporter(t *testing.T) {
+	var events []ProgressEvent
+	r := NewProgressReporter(func(e ProgressEvent) { events = append(events, e) }, 4)
+	r.Stage(1, "script")
+	r.Stage(2, "audio")
+	r.Item("audio", 1, 4)
+	r.Item("audio", 4, 4)
+	r.Stage(4, "final")
+
+	for i, want := range []float64{0, 25, 31.25, 50, 75} {
+		if i >= len(events) || events[i].Percent != want {
+			t.Fatalf("event %d: got %+v, want %v%%", i, events, want)
+		}
+	}
+	if e := events[2]; e.Stage != "audio" || e.Step != 2 || e.TotalSteps != 4 || e.Item != "audio" || e.Done != 1 || e.Total != 4 {
+		t.Errorf("unexpected item event %+v", e)
+	}
+	// A new stage starts without the previous stage's item count
+	if e := events[4]; e.Item != "" || e.Done != 0 || e.Total != 0 {
+		t.Errorf("item count carried into the next stage: %+v", e)
+	}
+
+	var nilReporter *ProgressReporter
+	nilReporter.Stage(1, "script")
+	nilReporter.Item("audio", 1, 2)
+	NewProgressReporter(nil, 2).Stage(1, "script")
+}
@@ -0,0 +1,168 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Global semaphore to limit concurrent Sarvam API requests across all pipelines.
+// This prevents GOAWAY errors from the server due to too many concurrent HTTP/2 streams.
+var sarvamSem = make(chan struct{}, DefaultSettings().TTS.Concurrency)
+
+// SetTTSConcurrency sets how many Sarvam requests may run at once across all
+// pipelines. Call it at startup, before any job runs.
+func SetTTSConcurrency(n int) {
+	sarvamSem = make(chan struct{}, n)
+}
+
+// SarvamTTS is the Sarvam AI implementation of TTSProvider
+type SarvamTTS struct {
+	APIKey string
+	Model  string
+	URL    string
+	Cache  *Cache // Optional, reuses audio for identical chunks
+	client *http.Client
+
+	ChunkSize int           // Longest text per request in bytes, 500 if zero
+	Timeout   time.Duration // Optional, bounds each chunk's requests and retries
+}
+
+func NewSarvamTTS(apiKey string) *SarvamTTS {
+	return &SarvamTTS{
+		APIKey: apiKey,
+		Model:  "bulbul:v2",
+		URL:    "https://api.sarvam.ai/text-to-speech",
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Synthesize generates a WAV file for text, in chunks of at most ChunkSize bytes
+func (s *SarvamTTS) Synthesize(ctx context.Context, text, outputPath string, opts TTSOptions) error {
+	if opts.Voice == "" {
+		opts.Voice = "vidya"
+	}
+	if opts.Language == "" {
+		opts.Language = "en-IN"
+	}
+	if opts.SampleRate == 0 {
+		opts.SampleRate = DefaultTTSSampleRate
+	}
+
+	chunkSize := s.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultSettings().TTS.ChunkSize
+	}
+	return synthesizeChunked(ctx, text, outputPath, chunkSize, func(chunk, chunkPath string) error {
+		return s.synthesizeChunk(ctx, chunk, chunkPath, opts)
+	})
+}
+
+// synthesizeChunk makes the API call to generate audio for a text chunk
+func (s *SarvamTTS) synthesizeChunk(ctx context.Context, text, outputPath string, opts TTSOptions) error {
+	cacheKey := CacheKey("sarvam", s.Model, text, opts.Voice, opts.Language, fmt.Sprint(opts.SampleRate))
+	if s.Cache.GetFile(cacheKey, outputPath) {
+		return nil
+	}
+
+	// Acquire semaphore to limit concurrent API calls
+	select {
+	case sarvamSem <- struct{}{}:
+		defer func() { <-sarvamSem }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// The timeout starts once the chunk has a slot, so queueing doesn't count
+	return WithTimeout(ctx, StageTTS, s.Timeout, func(ctx context.Context) error {
+		return s.request(ctx, text, outputPath, cacheKey, opts)
+	})
+}
+
+// request calls the API for a chunk, with retries, and caches the audio
+func (s *SarvamTTS) request(ctx context.Context, text, outputPath, cacheKey string, opts TTSOptions) error {
+	payload := map[string]interface{}{
+		"inputs":               []string{text},
+		"target_language_code": opts.Language,
+		"speaker":              opts.Voice,
+		"speech_sample_rate":   opts.SampleRate,
+		"enable_preprocessing": true,
+		"model":                s.Model,
+	}
+
+	jsonPayload, _ := json.Marshal(payload)
+
+	var resp *http.Response
+	var err error
+
+	// Retry loop
+	for attempts := 0; attempts < 3; attempts++ {
+		req, _ := http.NewRequestWithContext(ctx, "POST", s.URL, bytes.NewBuffer(jsonPayload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("api-subscription-key", s.APIKey)
+
+		resp, err = s.client.Do(req)
+		if err == nil && resp.StatusCode == 200 {
+			break
+		}
+		if attempts == 2 {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(time.Duration(attempts+1) * 2 * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	audios, ok := result["audios"].([]interface{})
+	if !ok || len(audios) == 0 {
+		return fmt.Errorf("no audio in response")
+	}
+
+	audioStr, ok := audios[0].(string)
+	if !ok {
+		return fmt.Errorf("invalid audio format")
+	}
+
+	// Strip header if present
+	if idx := strings.Index(audioStr, ","); idx != -1 {
+		audioStr = audioStr[idx+1:]
+	}
+
+	audioBytes, err := base64.StdEncoding.DecodeString(audioStr)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Cache.Put(cacheKey, audioBytes); err != nil {
+		log.Printf("[TTS] Warning: failed to cache audio chunk: %v", err)
+	}
+	return os.WriteFile(outputPath, audioBytes, 0644)
+}
@@ -0,0 +1,394 @@
+package common
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Settings are the tunable knobs of the pipelines: model parameters,
+// concurrency limits, media sizes, layout detection and time limits. They
+// are loaded once at startup by LoadSettings and reach the pipelines
+// through PipelineConfig.Settings.
+//
+// Each field is named by its TOML table and key, e.g. "video.resolution",
+// which is also its SARAL_VIDEO_RESOLUTION environment variable; the flag
+// tag names its command-line flag.
+type Settings struct {
+	LLM      LLMSettings    `toml:"llm"`
+	TTS      TTSSettings    `toml:"tts"`
+	Video    VideoSettings  `toml:"video"`
+	Reel     ReelSettings   `toml:"reel"`
+	Poster   PosterSettings `toml:"poster"`
+	Layout   LayoutSettings `toml:"layout"`
+	Timeouts Timeouts       `toml:"timeouts"`
+}
+
+// LLMSettings tune text generation
+type LLMSettings struct {
+	Model       string  `toml:"model" flag:"llm-model" help:"LLM model name, empty for the provider default (gemini-3-flash-preview, gpt-4o-mini)"`
+	Temperature float64 `toml:"temperature" flag:"llm-temperature" help:"LLM sampling temperature, 0 to 2"`
+}
+
+// TTSSettings tune Sarvam speech synthesis
+type TTSSettings struct {
+	ChunkSize   int `toml:"chunk_size" flag:"tts-chunk-size" help:"Longest text sent to Sarvam in one request, in bytes"`
+	Concurrency int `toml:"concurrency" flag:"tts-concurrency" help:"Concurrent Sarvam requests across all jobs"`
+}
+
+// VideoSettings shape the narrated slide videos
+type VideoSettings struct {
+	Resolution        Resolution `toml:"resolution" flag:"video-resolution" help:"Video frame size, WIDTHxHEIGHT with even sides"`
+	Voice             string     `toml:"voice" flag:"video-voice" help:"TTS voice of the video narrator"`
+	FFmpegConcurrency int        `toml:"ffmpeg_concurrency" flag:"ffmpeg-concurrency" help:"Concurrent ffmpeg segment encodes across all jobs"`
+}
+
+// ReelSettings shape the two-speaker reels
+type ReelSettings struct {
+	FemaleVoice string `toml:"female_voice" flag:"reel-female-voice" help:"TTS voice of Person1 for avatar pairs that don't set one"`
+	MaleVoice   string `toml:"male_voice" flag:"reel-male-voice" help:"TTS voice of Person2 for avatar pairs that don't set one"`
+}
+
+// PosterSettings shape the LaTeX posters
+type PosterSettings struct {
+	Width   int `toml:"width" flag:"poster-width" help:"Poster width in cm"`
+	Height  int `toml:"height" flag:"poster-height" help:"Poster height in cm"`
+	Columns int `toml:"columns" flag:"poster-columns" help:"Poster columns, 2 or 3"`
+}
+
+// LayoutSettings configure the DocLayNet layout model
+type LayoutSettings struct {
+	ModelPath     string  `toml:"model_path" flag:"layout-model" help:"DocLayNet YOLOv8 ONNX model used to find figures and layout"`
+	ONNXLibrary   string  `toml:"onnx_library" flag:"onnx-library" help:"ONNX Runtime shared library"`
+	ConfThreshold float64 `toml:"conf_threshold" flag:"layout-conf-threshold" help:"Minimum confidence of a layout box, 0 to 1"`
+	NMSThreshold  float64 `toml:"nms_threshold" flag:"layout-nms-threshold" help:"Overlap above which the weaker of two layout boxes is dropped, 0 to 1"`
+}
+
+// DefaultSettings returns the settings used when nothing overrides them
+func DefaultSettings() Settings {
+	onnxLibrary := "/opt/homebrew/lib/libonnxruntime.dylib"
+	if runtime.GOOS == "linux" {
+		onnxLibrary = "/usr/lib/libonnxruntime.so"
+	}
+	return Settings{
+		LLM:    LLMSettings{Temperature: 0.7},
+		TTS:    TTSSettings{ChunkSize: 500, Concurrency: 2},
+		Video:  VideoSettings{Resolution: Resolution{Width: 1920, Height: 1080}, Voice: "vidya", FFmpegConcurrency: 4},
+		Reel:   ReelSettings{FemaleVoice: "vidya", MaleVoice: "karun"},
+		Poster: PosterSettings{Width: 120, Height: 72, Columns: 3},
+		Layout: LayoutSettings{
+			ModelPath:     DefaultLayoutModelPath,
+			ONNXLibrary:   onnxLibrary,
+			ConfThreshold: ConfThreshold,
+			NMSThreshold:  NMSThreshold,
+		},
+		Timeouts: DefaultTimeouts,
+	}
+}
+
+// OrDefault returns s, or DefaultSettings if s is nil
+func (s *Settings) OrDefault() *Settings {
+	if s == nil {
+		d := DefaultSettings()
+		return &d
+	}
+	return s
+}
+
+// Validate checks every setting and reports all problems at once
+func (s *Settings) Validate() error {
+	var errs []error
+	check := func(ok bool, key, problem string) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, problem))
+		}
+	}
+
+	check(s.LLM.Temperature >= 0 && s.LLM.Temperature <= 2, "llm.temperature", "must be between 0 and 2")
+	check(s.TTS.ChunkSize >= 50, "tts.chunk_size", "must be at least 50")
+	check(s.TTS.Concurrency >= 1, "tts.concurrency", "must be at least 1")
+	check(s.Video.Resolution.Width >= 16 && s.Video.Resolution.Height >= 16, "video.resolution", "must be at least 16x16")
+	check(s.Video.Resolution.Width%2 == 0 && s.Video.Resolution.Height%2 == 0, "video.resolution", "sides must be even for H.264")
+	check(s.Video.Voice != "", "video.voice", "must not be empty")
+	check(s.Video.FFmpegConcurrency >= 1, "video.ffmpeg_concurrency", "must be at least 1")
+	check(s.Reel.FemaleVoice != "", "reel.female_voice", "must not be empty")
+	check(s.Reel.MaleVoice != "", "reel.male_voice", "must not be empty")
+	check(s.Poster.Width >= 20 && s.Poster.Width <= 500, "poster.width", "must be between 20 and 500 cm")
+	check(s.Poster.Height >= 20 && s.Poster.Height <= 500, "poster.height", "must be between 20 and 500 cm")
+	check(s.Poster.Columns == 2 || s.Poster.Columns == 3, "poster.columns", "must be 2 or 3")
+	check(s.Layout.ModelPath != "", "layout.model_path", "must not be empty")
+	check(s.Layout.ONNXLibrary != "", "layout.onnx_library", "must not be empty")
+	check(s.Layout.ConfThreshold > 0 && s.Layout.ConfThreshold < 1, "layout.conf_threshold", "must be between 0 and 1")
+	check(s.Layout.NMSThreshold > 0 && s.Layout.NMSThreshold < 1, "layout.nms_threshold", "must be between 0 and 1")
+	for _, f := range s.fields() {
+		if d, ok := f.value.Interface().(time.Duration); ok {
+			check(d >= 0, f.key, "must not be negative")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// settingField is one leaf of Settings, addressable for Set
+type settingField struct {
+	key   string // "table.key"
+	flag  string
+	help  string
+	value reflect.Value
+}
+
+// fields lists the settings in declaration order
+func (s *Settings) fields() []settingField {
+	var fields []settingField
+	tables := reflect.ValueOf(s).Elem()
+	for i := 0; i < tables.NumField(); i++ {
+		table := tables.Type().Field(i).Tag.Get("toml")
+		values := tables.Field(i)
+		for j := 0; j < values.NumField(); j++ {
+			tag := values.Type().Field(j).Tag
+			fields = append(fields, settingField{
+				key:   table + "." + tag.Get("toml"),
+				flag:  tag.Get("flag"),
+				help:  tag.Get("help"),
+				value: values.Field(j),
+			})
+		}
+	}
+	return fields
+}
+
+func (s *Settings) field(key string) (settingField, bool) {
+	for _, f := range s.fields() {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return settingField{}, false
+}
+
+// Set parses value into the setting named key, e.g. "poster.width"
+func (s *Settings) Set(key, value string) error {
+	f, ok := s.field(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	if err := setValue(f.value, value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, e.g. 90s or 5m", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// LoadFile reads settings from a TOML file over the current values.
+// Durations are strings such as "5m" and resolutions strings such as
+// "1920x1080"; keys that aren't settings are rejected.
+func (s *Settings) LoadFile(path string) error {
+	meta, err := toml.DecodeFile(path, s)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = strconv.Quote(key.String())
+		}
+		return fmt.Errorf("%s: unknown setting %s", path, strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// EnvName is the environment variable that overrides a setting, e.g.
+// SARAL_POSTER_WIDTH for "poster.width"
+func EnvName(key string) string {
+	return "SARAL_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// ApplyEnv applies the settings given in SARAL_* environment variables
+func (s *Settings) ApplyEnv() error {
+	for _, f := range s.fields() {
+		if value, ok := os.LookupEnv(EnvName(f.key)); ok {
+			if err := setValue(f.value, value); err != nil {
+				return fmt.Errorf("%s: %w", EnvName(f.key), err)
+			}
+		}
+	}
+	return nil
+}
+
+// SettingsFlags holds the settings given on the command line, which are
+// parsed before the file they override is known
+type SettingsFlags struct {
+	values map[string]string // by setting key
+}
+
+// RegisterSettingsFlags adds a flag for every setting to fs
+func RegisterSettingsFlags(fs *flag.FlagSet) *SettingsFlags {
+	flags := &SettingsFlags{values: make(map[string]string)}
+	defaults := DefaultSettings()
+	for _, f := range defaults.fields() {
+		key := f.key
+		usage := fmt.Sprintf("%s (default %s; env %s)", f.help, formatValue(f.value), EnvName(key))
+		fs.Func(f.flag, usage, func(value string) error {
+			probe := DefaultSettings()
+			if err := probe.Set(key, value); err != nil {
+				return err
+			}
+			flags.values[key] = value
+			return nil
+		})
+	}
+	return flags
+}
+
+// Apply sets the settings given on the command line
+func (f *SettingsFlags) Apply(s *Settings) error {
+	for key, value := range f.values {
+		if err := s.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadSettings builds the settings from their defaults, the TOML file at
+// path (if not empty), SARAL_* environment variables and flags, in
+// increasing precedence, and validates them
+func LoadSettings(path string, flags *SettingsFlags) (Settings, error) {
+	s := DefaultSettings()
+	if path != "" {
+		if err := s.LoadFile(path); err != nil {
+			return s, fmt.Errorf("config file: %w", err)
+		}
+	}
+	if err := s.ApplyEnv(); err != nil {
+		return s, err
+	}
+	if flags != nil {
+		if err := flags.Apply(&s); err != nil {
+			return s, err
+		}
+	}
+	if err := s.Validate(); err != nil {
+		return s, fmt.Errorf("invalid settings:\n%w", err)
+	}
+	return s, nil
+}
+
+// WriteTOML writes the settings as a TOML file LoadFile can read back, each
+// key preceded by its description
+func (s *Settings) WriteTOML(w io.Writer) error {
+	table := ""
+	var sb strings.Builder
+	for _, f := range s.fields() {
+		name, key, _ := strings.Cut(f.key, ".")
+		if name != table {
+			if table != "" {
+				sb.WriteString("\n")
+			}
+			fmt.Fprintf(&sb, "[%s]\n", name)
+			table = name
+		}
+		fmt.Fprintf(&sb, "# %s\n%s = %s\n", f.help, key, formatValue(f.value))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// formatValue writes a setting as a TOML value
+func formatValue(v reflect.Value) string {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, _ := m.MarshalText()
+		return strconv.Quote(string(text))
+	}
+	if d, ok := v.Interface().(time.Duration); ok {
+		return strconv.Quote(d.String())
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Float64:
+		// A TOML float needs a fraction or exponent, or it reads as an integer
+		text := strconv.FormatFloat(v.Float(), 'g', -1, 64)
+		if !strings.ContainsAny(text, ".e") {
+			text += ".0"
+		}
+		return text
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// Resolution is a video frame size, written WIDTHxHEIGHT
+type Resolution struct {
+	Width  int
+	Height int
+}
+
+func (r Resolution) String() string {
+	return fmt.Sprintf("%dx%d", r.Width, r.Height)
+}
+
+func (r Resolution) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Resolution) UnmarshalText(text []byte) error {
+	w, h, ok := strings.Cut(strings.ToLower(strings.TrimSpace(string(text))), "x")
+	width, errW := strconv.Atoi(w)
+	height, errH := strconv.Atoi(h)
+	if !ok || errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid resolution %q, e.g. 1920x1080", text)
+	}
+	r.Width, r.Height = width, height
+	return nil
+}
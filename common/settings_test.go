@@ -0,0 +1,82 @@
+package common
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "saral.toml")
+	os.WriteFile(path, []byte(`
+# Smaller, faster videos
+video.resolution = '1280x720' # 720p
+video.ffmpeg_concurrency = 2
+timeouts = { job = "45m" }
+
+[poster]
+width = 90
+columns = 2
+
+[reel]
+female_voice = """
+anushka"""
+`), 0644)
+	t.Setenv("SARAL_POSTER_WIDTH", "100")
+	t.Setenv("SARAL_LLM_TEMPERATURE", "0.2")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterSettingsFlags(fs)
+	if err := fs.Parse([]string{"--llm-temperature=0", "--job-timeout=1h"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSettings(path, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Video.Resolution != (Resolution{1280, 720}) || s.Video.FFmpegConcurrency != 2 || s.Video.Voice != "vidya" {
+		t.Errorf("file not applied over defaults: %+v", s.Video)
+	}
+	if s.Reel.FemaleVoice != "anushka" || s.Reel.MaleVoice != "karun" {
+		t.Errorf("file not applied over defaults: %+v", s.Reel)
+	}
+	if s.Poster.Width != 100 || s.Poster.Height != 72 || s.Poster.Columns != 2 {
+		t.Errorf("environment not applied over file: %+v", s.Poster)
+	}
+	if s.LLM.Temperature != 0 || s.Timeouts.Job != time.Hour {
+		t.Errorf("flags not applied over environment: %+v %+v", s.LLM, s.Timeouts)
+	}
+
+	// --print-config output reads back to the same settings
+	var buf bytes.Buffer
+	s.WriteTOML(&buf)
+	os.WriteFile(path, buf.Bytes(), 0644)
+	again := DefaultSettings()
+	if err := again.LoadFile(path); err != nil || again != s {
+		t.Errorf("round trip changed settings (%v):\n%s", err, buf.String())
+	}
+
+	for _, bad := range []string{
+		"[video]\nresolution = 1280x720\n",
+		"[poster]\nwidth = \"90\"\n",
+		"[poster]\ndepth = 3\n",
+	} {
+		os.WriteFile(path, []byte(bad), 0644)
+		if _, err := LoadSettings(path, nil); err == nil {
+			t.Errorf("accepted %q", bad)
+		}
+	}
+
+	s = DefaultSettings()
+	s.Video.Resolution = Resolution{1279, 720}
+	s.Poster.Columns = 4
+	err = s.Validate()
+	if err == nil || !strings.Contains(err.Error(), "video.resolution: sides must be even") || !strings.Contains(err.Error(), "poster.columns") {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	subtitleLineChars = 42 // readable line length, as in common broadcast guidelines
+	subtitleCueChars  = 2 * subtitleLineChars
+)
+
+// SubtitleSegment is a stretch of narration played back to back with the
+// others: the spoken text and how long it lasts in the video
+type SubtitleSegment struct {
+	Text     string
+	Duration float64 // seconds
+}
+
+// SubtitleCue is one caption shown from Start to End seconds
+type SubtitleCue struct {
+	Start, End float64
+	Text       string // at most two lines
+}
+
+// SubtitleStyle is the look of burned-in subtitles
+type SubtitleStyle struct {
+	Font         string `json:"font,omitempty"`     // font family, e.g. "Noto Sans"; a Noto font for the language if empty
+	Size         int    `json:"size,omitempty"`     // font size in libass units (about 1/288 of the video height)
+	Color        string `json:"color,omitempty"`    // text color, #RRGGBB
+	OutlineColor string `json:"outline,omitempty"`  // outline color, #RRGGBB
+	Position     string `json:"position,omitempty"` // "bottom" or "top"
+}
+
+// DefaultSubtitleStyle is white text with a black outline at the bottom
+var DefaultSubtitleStyle = SubtitleStyle{Size: 18, Color: "#FFFFFF", OutlineColor: "#000000", Position: "bottom"}
+
+var (
+	subtitleFontRe  = regexp.MustCompile(`^[A-Za-z0-9 -]+$`)
+	subtitleColorRe = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+)
+
+// ParseSubtitleStyle reads a style such as "font=Noto Sans,size=20,
+// color=#FFFF00,outline=#000000,position=top" over DefaultSubtitleStyle
+func ParseSubtitleStyle(spec string) (SubtitleStyle, error) {
+	style := DefaultSubtitleStyle
+	for _, field := range strings.Split(spec, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return style, fmt.Errorf("subtitle style %q: expected key=value", field)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "font":
+			style.Font = value
+		case "size":
+			size, err := strconv.Atoi(value)
+			if err != nil {
+				return style, fmt.Errorf("subtitle style size: %w", err)
+			}
+			style.Size = size
+		case "color":
+			style.Color = value
+		case "outline":
+			style.OutlineColor = value
+		case "position":
+			style.Position = value
+		default:
+			return style, fmt.Errorf("unknown subtitle style key %q, use font, size, color, outline or position", key)
+		}
+	}
+	return style, style.Validate()
+}
+
+// Validate checks the style, which ends up inside an ffmpeg filter
+func (s SubtitleStyle) Validate() error {
+	if s.Font != "" && !subtitleFontRe.MatchString(s.Font) {
+		return fmt.Errorf("subtitle font %q may only contain letters, digits, spaces and dashes", s.Font)
+	}
+	if s.Size < 0 || s.Size > 200 {
+		return fmt.Errorf("subtitle size %d out of range", s.Size)
+	}
+	for _, c := range []string{s.Color, s.OutlineColor} {
+		if c != "" && !subtitleColorRe.MatchString(c) {
+			return fmt.Errorf("subtitle color %q must be #RRGGBB", c)
+		}
+	}
+	if s.Position != "" && s.Position != "bottom" && s.Position != "top" {
+		return fmt.Errorf("subtitle position %q must be bottom or top", s.Position)
+	}
+	return nil
+}
+
+// ForLanguage fills in the Noto font for language's script if no font is
+// set, and DefaultSubtitleStyle if the style is empty
+func (s SubtitleStyle) ForLanguage(language string) SubtitleStyle {
+	if s == (SubtitleStyle{}) {
+		s = DefaultSubtitleStyle
+	}
+	if s.Font == "" {
+		s.Font = LanguageFonts[NormalizeLanguage(language)]
+	}
+	return s
+}
+
+// forceStyle renders the style as a libass force_style value
+func (s SubtitleStyle) forceStyle() string {
+	fields := []string{"BorderStyle=1", "Outline=2", "Shadow=0", "MarginV=30"}
+	if s.Font != "" {
+		fields = append(fields, "FontName="+s.Font)
+	}
+	if s.Size > 0 {
+		fields = append(fields, "FontSize="+strconv.Itoa(s.Size))
+	}
+	if s.Color != "" {
+		fields = append(fields, "PrimaryColour="+assColor(s.Color))
+	}
+	if s.OutlineColor != "" {
+		fields = append(fields, "OutlineColour="+assColor(s.OutlineColor))
+	}
+	if s.Position == "top" {
+		fields = append(fields, "Alignment=8")
+	} else {
+		fields = append(fields, "Alignment=2")
+	}
+	return strings.Join(fields, ",")
+}
+
+// assColor converts #RRGGBB to the &HAABBGGRR form libass expects
+func assColor(hex string) string {
+	return "&H00" + strings.ToUpper(hex[5:7]+hex[3:5]+hex[1:3])
+}
+
+// BuildSubtitleCues splits each segment's text into cues of at most two
+// readable lines, timed in proportion to their length within the segment
+func BuildSubtitleCues(segments []SubtitleSegment) []SubtitleCue {
+	var cues []SubtitleCue
+	offset := 0.0
+	for _, seg := range segments {
+		pieces := splitSubtitleText(CleanTextForTTS(seg.Text))
+		total := 0
+		for _, p := range pieces {
+			total += utf8.RuneCountInString(p)
+		}
+
+		start := offset
+		for _, p := range pieces {
+			end := start + seg.Duration*float64(utf8.RuneCountInString(p))/float64(total)
+			cues = append(cues, SubtitleCue{Start: start, End: end, Text: wrapSubtitle(p)})
+			start = end
+		}
+		offset += seg.Duration
+	}
+	return cues
+}
+
+// splitSubtitleText breaks text into pieces of at most subtitleCueChars,
+// at sentence ends where possible, then after commas, then between words
+func splitSubtitleText(text string) []string {
+	var pieces []string
+	for _, sentence := range splitOnSentences(text) {
+		if sentence == "" {
+			continue
+		}
+		if utf8.RuneCountInString(sentence) <= subtitleCueChars {
+			pieces = append(pieces, sentence)
+			continue
+		}
+
+		var current string
+		for _, word := range strings.Fields(sentence) {
+			candidate := strings.TrimSpace(current + " " + word)
+			if current != "" && utf8.RuneCountInString(candidate) > subtitleCueChars {
+				pieces = append(pieces, current)
+				candidate = word
+			}
+			current = candidate
+			// A comma past the first line is a natural place to break
+			if strings.HasSuffix(word, ",") && utf8.RuneCountInString(current) > subtitleLineChars {
+				pieces = append(pieces, current)
+				current = ""
+			}
+		}
+		if current != "" {
+			pieces = append(pieces, current)
+		}
+	}
+	return pieces
+}
+
+// wrapSubtitle puts a cue on two lines if it is longer than one, breaking
+// at the space closest to the middle
+func wrapSubtitle(text string) string {
+	if utf8.RuneCountInString(text) <= subtitleLineChars {
+		return text
+	}
+	mid := len(text) / 2
+	best, bestDist := -1, len(text)
+	for i, r := range text {
+		dist := i - mid
+		if dist < 0 {
+			dist = -dist
+		}
+		if r == ' ' && dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	if best < 0 {
+		return text
+	}
+	return text[:best] + "\n" + text[best+1:]
+}
+
+// WriteSRT writes the cues as a SubRip file
+func WriteSRT(path string, cues []SubtitleCue) error {
+	var sb strings.Builder
+	for i, c := range cues {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, subtitleTime(c.Start, ","), subtitleTime(c.End, ","), c.Text)
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// WriteVTT writes the cues as a WebVTT file
+func WriteVTT(path string, cues []SubtitleCue) error {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n\n")
+	for _, c := range cues {
+		fmt.Fprintf(&sb, "%s --> %s\n%s\n\n", subtitleTime(c.Start, "."), subtitleTime(c.End, "."), c.Text)
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// subtitleTime formats seconds as HH:MM:SS plus milliseconds after sep
+func subtitleTime(seconds float64, sep string) string {
+	ms := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// CreateSubtitles writes SRT and WebVTT files next to videoPath for the
+// segments it is made of and, if burn is set, also burns the subtitles into
+// the video in place, within the encode timeout. It returns the sidecar files.
+func CreateSubtitles(ctx context.Context, videoPath string, segments []SubtitleSegment, burn bool, style SubtitleStyle, timeout time.Duration) ([]string, error) {
+	cues := BuildSubtitleCues(segments)
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("no narration to subtitle")
+	}
+
+	base := strings.TrimSuffix(videoPath, filepath.Ext(videoPath))
+	srtPath, vttPath := base+".srt", base+".vtt"
+	if err := WriteSRT(srtPath, cues); err != nil {
+		return nil, err
+	}
+	if err := WriteVTT(vttPath, cues); err != nil {
+		return nil, err
+	}
+	files := []string{srtPath, vttPath}
+
+	if burn {
+		if err := BurnSubtitles(ctx, videoPath, srtPath, style, timeout); err != nil {
+			return files, err
+		}
+	}
+	return files, nil
+}
+
+// BurnSubtitles renders the subtitle file into the video, replacing it. The
+// re-encode is bounded by timeout like any other encode stage.
+func BurnSubtitles(ctx context.Context, videoPath, subtitlePath string, style SubtitleStyle, timeout time.Duration) error {
+	if err := style.Validate(); err != nil {
+		return err
+	}
+	absVideo, err := filepath.Abs(videoPath)
+	if err != nil {
+		return err
+	}
+	tmpPath := strings.TrimSuffix(absVideo, filepath.Ext(absVideo)) + ".subtitled.mp4"
+
+	// ffmpeg runs in the subtitle's directory so the filter only sees its
+	// base name and needs no path escaping
+	filter := fmt.Sprintf("subtitles=%s:force_style='%s'", filepath.Base(subtitlePath), style.forceStyle())
+	output, err := CommandOutput(ctx, StageEncode, timeout, func(ctx context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "ffmpeg",
+			"-y",
+			"-i", absVideo,
+			"-vf", filter,
+			"-c:v", "libx264",
+			"-pix_fmt", "yuv420p",
+			"-c:a", "copy",
+			tmpPath,
+		)
+		cmd.Dir = filepath.Dir(subtitlePath)
+		return cmd
+	})
+	if ctx.Err() != nil {
+		os.Remove(tmpPath)
+		return ctx.Err()
+	}
+	if IsTimeout(err) {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("ffmpeg subtitle burn-in failed: %s, output: %s", err, string(output))
+	}
+	return os.Rename(tmpPath, absVideo)
+}
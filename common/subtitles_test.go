@@ -0,0 +1,84 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBuildSubtitleCues(t *testing.T) {
+	long := "Our method trains a small model on synthetic data, distills it into a larger one, and evaluates both on every benchmark we could find."
+	cues := BuildSubtitleCues([]SubtitleSegment{
+		{Text: "Hello **world**. Short one!", Duration: 4},
+		{Text: long, Duration: 10},
+	})
+
+	if len(cues) < 4 {
+		t.Fatalf("expected the long sentence to be split, got %+v", cues)
+	}
+	if cues[0].Text != "Hello world." || cues[0].Start != 0 {
+		t.Errorf("unexpected first cue %+v", cues[0])
+	}
+	if cues[1].End != 4 || cues[2].Start != 4 {
+		t.Errorf("segments not back to back: %+v %+v", cues[1], cues[2])
+	}
+	if last := cues[len(cues)-1]; last.End < 13.999 || last.End > 14.001 {
+		t.Errorf("cues end at %.3f, want 14", last.End)
+	}
+	for _, c := range cues {
+		lines := strings.Split(c.Text, "\n")
+		if len(lines) > 2 {
+			t.Errorf("cue %q has more than two lines", c.Text)
+		}
+		for _, l := range lines {
+			if utf8.RuneCountInString(l) > subtitleLineChars+10 {
+				t.Errorf("line %q too long", l)
+			}
+		}
+	}
+}
+
+func TestWriteSubtitles(t *testing.T) {
+	dir := t.TempDir()
+	cues := []SubtitleCue{{Start: 0, End: 1.5, Text: "One"}, {Start: 3661.25, End: 3662, Text: "Two"}}
+
+	srt := filepath.Join(dir, "a.srt")
+	if err := WriteSRT(srt, cues); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(srt)
+	want := "1\n00:00:00,000 --> 00:00:01,500\nOne\n\n2\n01:01:01,250 --> 01:01:02,000\nTwo\n\n"
+	if string(data) != want {
+		t.Errorf("SRT = %q, want %q", data, want)
+	}
+
+	vtt := filepath.Join(dir, "a.vtt")
+	if err := WriteVTT(vtt, cues); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(vtt)
+	if !strings.HasPrefix(string(data), "WEBVTT\n\n00:00:00.000 --> 00:00:01.500\nOne\n") {
+		t.Errorf("unexpected VTT %q", data)
+	}
+}
+
+func TestParseSubtitleStyle(t *testing.T) {
+	style, err := ParseSubtitleStyle("font=Noto Sans, size=24,color=#FFCC00,position=top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if style.Font != "Noto Sans" || style.Size != 24 || style.OutlineColor != "#000000" {
+		t.Errorf("unexpected style %+v", style)
+	}
+	if fs := style.forceStyle(); !strings.Contains(fs, "PrimaryColour=&H0000CCFF") || !strings.Contains(fs, "Alignment=8") {
+		t.Errorf("unexpected force_style %q", fs)
+	}
+
+	for _, bad := range []string{"font=x'y", "size=big", "color=red", "position=middle", "weight=bold"} {
+		if _, err := ParseSubtitleStyle(bad); err == nil {
+			t.Errorf("ParseSubtitleStyle(%q) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Stages bounded by Timeouts, as named in StageTimeoutError
+const (
+	StageLLM     = "llm"     // one LLM call, including retries
+	StageTTS     = "tts"     // one TTS chunk, including retries
+	StageCompile = "compile" // one pdflatex/xelatex run of slides or a poster
+	StageEncode  = "encode"  // one ffmpeg run encoding a video segment, reel clip or subtitle burn-in
+)
+
+// Timeouts bounds how long a job and its slowest steps may run, so a hung
+// pdflatex, ffmpeg or API call fails the job instead of pinning a worker.
+// Zero means no limit.
+type Timeouts struct {
+	LLMCall       time.Duration `toml:"llm_call" flag:"llm-timeout" help:"Time limit for each LLM call, including retries (0 = none)"`
+	TTSChunk      time.Duration `toml:"tts_chunk" flag:"tts-timeout" help:"Time limit for each TTS chunk, including retries (0 = none)"`
+	SlideCompile  time.Duration `toml:"slide_compile" flag:"compile-timeout" help:"Time limit for each pdflatex/xelatex run of slides or posters (0 = none)"`
+	SegmentEncode time.Duration `toml:"segment_encode" flag:"encode-timeout" help:"Time limit for each ffmpeg run encoding a video segment, reel clip or subtitle burn-in (0 = none)"`
+	Job           time.Duration `toml:"job" flag:"job-timeout" help:"Time limit for a whole pipeline run (0 = none)"`
+}
+
+// DefaultTimeouts are generous enough for long papers on a slow machine
+var DefaultTimeouts = Timeouts{
+	LLMCall:       5 * time.Minute,
+	TTSChunk:      2 * time.Minute,
+	SlideCompile:  5 * time.Minute,
+	SegmentEncode: 15 * time.Minute,
+	Job:           2 * time.Hour,
+}
+
+// StageTimeoutError reports that a stage ran out of time
+type StageTimeoutError struct {
+	Stage   string
+	Timeout time.Duration
+}
+
+func (e *StageTimeoutError) Error() string {
+	return fmt.Sprintf("timed out in stage %s after %s", e.Stage, e.Timeout)
+}
+
+// IsTimeout reports whether err comes from a stage or job running out of time
+func IsTimeout(err error) bool {
+	var timeoutErr *StageTimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
+// WithTimeout runs fn with a context that expires after timeout, and turns
+// the expiry into a StageTimeoutError for stage. Cancellation of ctx itself
+// is returned as is. A zero timeout runs fn with ctx.
+func WithTimeout(ctx context.Context, stage string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	stageCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := fn(stageCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(stageCtx.Err(), context.DeadlineExceeded) {
+		return &StageTimeoutError{Stage: stage, Timeout: timeout}
+	}
+	return err
+}
+
+// CommandOutput runs the command newCmd builds under the stage timeout and
+// returns its combined output. newCmd should use exec.CommandContext with
+// the context it is given, so the process is killed when time runs out.
+func CommandOutput(ctx context.Context, stage string, timeout time.Duration, newCmd func(ctx context.Context) *exec.Cmd) ([]byte, error) {
+	var output []byte
+	err := WithTimeout(ctx, stage, timeout, func(ctx context.Context) error {
+		var err error
+		output, err = newCmd(ctx).CombinedOutput()
+		return err
+	})
+	return output, err
+}
+
+// RunJob runs a pipeline under the job deadline of its settings. If the
+// deadline passes, the error names the stage the pipeline had reached, from
+// its last progress event.
+func RunJob(ctx context.Context, config PipelineConfig, run func(ctx context.Context, config PipelineConfig) error) error {
+	var mu sync.Mutex
+	stage := "setup"
+	progress := config.Progress
+	config.Progress = func(e ProgressEvent) {
+		mu.Lock()
+		stage = e.Stage
+		mu.Unlock()
+		if progress != nil {
+			progress(e)
+		}
+	}
+
+	err := WithTimeout(ctx, "", config.Settings.OrDefault().Timeouts.Job, func(ctx context.Context) error {
+		return run(ctx, config)
+	})
+	if timeoutErr, ok := err.(*StageTimeoutError); ok && timeoutErr.Stage == "" {
+		mu.Lock()
+		timeoutErr.Stage = stage
+		mu.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestRunJobTimeouts(t *testing.T) {
+	// A hung command is killed at its stage timeout
+	run := func(ctx context.Context, config PipelineConfig) error {
+		config.Progress(ProgressEvent{Stage: "assets"})
+		_, err := CommandOutput(ctx, StageCompile, 20*time.Millisecond, func(ctx context.Context) *exec.Cmd {
+			return exec.CommandContext(ctx, "sleep", "5")
+		})
+		return fmt.Errorf("slides failed: %w", err)
+	}
+	err := RunJob(context.Background(), PipelineConfig{Settings: &Settings{Timeouts: Timeouts{Job: time.Minute}}}, run)
+	if !IsTimeout(err) || err.Error() != "slides failed: timed out in stage compile after 20ms" {
+		t.Errorf("unexpected stage timeout error: %v", err)
+	}
+
+	// The job deadline names the stage the pipeline reached
+	var stages []string
+	config := PipelineConfig{
+		Settings: &Settings{Timeouts: Timeouts{Job: 20 * time.Millisecond}},
+		Progress: func(e ProgressEvent) { stages = append(stages, e.Stage) },
+	}
+	err = RunJob(context.Background(), config, func(ctx context.Context, config PipelineConfig) error {
+		config.Progress(ProgressEvent{Stage: "script"})
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !IsTimeout(err) || err.Error() != "timed out in stage script after 20ms" || len(stages) != 1 {
+		t.Errorf("unexpected job timeout error: %v (stages %v)", err, stages)
+	}
+
+	// Cancellation isn't reported as a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = RunJob(ctx, config, func(ctx context.Context, config PipelineConfig) error { return ctx.Err() })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("cancellation reported as %v", err)
+	}
+}
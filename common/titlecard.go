@@ -0,0 +1,71 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TitleCard is the look of the reel's title background
+type TitleCard struct {
+	TitleFont  string // TrueType/OpenType file for the title, Go Bold if empty
+	TextFont   string // TrueType/OpenType file for the authors and venue, Go Regular if empty
+	Background string // #RRGGBB, white if empty
+	TitleColor string // #RRGGBB, near black if empty
+	TextColor  string // #RRGGBB, dark grey if empty
+	Logo       string // Optional PNG or JPEG shown above the title
+	Venue      string // Optional, e.g. "NeurIPS 2024", overrides the paper's venue
+}
+
+// ParseTitleCard reads a title card such as "title_font=/fonts/Inter-Bold.ttf,
+// background=#0B1F3A,title_color=#FFFFFF,color=#C8D3E0,logo=lab.png,venue=ICML 2025"
+func ParseTitleCard(spec string) (TitleCard, error) {
+	var card TitleCard
+	for _, field := range strings.Split(spec, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return card, fmt.Errorf("title card %q: expected key=value", field)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "title_font":
+			card.TitleFont = value
+		case "font":
+			card.TextFont = value
+		case "background":
+			card.Background = value
+		case "title_color":
+			card.TitleColor = value
+		case "color":
+			card.TextColor = value
+		case "logo":
+			card.Logo = value
+		case "venue":
+			card.Venue = value
+		default:
+			return card, fmt.Errorf("unknown title card key %q, use title_font, font, background, title_color, color, logo or venue", key)
+		}
+	}
+	return card, card.Validate()
+}
+
+// Validate checks the colors and that the font and logo files exist
+func (c TitleCard) Validate() error {
+	for _, col := range []string{c.Background, c.TitleColor, c.TextColor} {
+		if col != "" && !subtitleColorRe.MatchString(col) {
+			return fmt.Errorf("title card color %q must be #RRGGBB", col)
+		}
+	}
+	for _, path := range []string{c.TitleFont, c.TextFont, c.Logo} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("title card: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// TTSOptions selects how text is spoken
+type TTSOptions struct {
+	Voice      string // provider voice name, e.g. "vidya"
+	Language   string // BCP-47 code, e.g. "en-IN"
+	SampleRate int    // Hz, 0 for the provider default
+}
+
+// TTSProvider turns text into a WAV file. Implementations chunk long text
+// and concatenate the pieces, so callers can pass whole sections.
+type TTSProvider interface {
+	Synthesize(ctx context.Context, text, outputPath string, opts TTSOptions) error
+}
+
+// TTS providers selectable through PipelineConfig.TTSProvider
+const (
+	TTSSarvam = "sarvam"
+	TTSEspeak = "espeak" // offline, uses a local espeak-ng binary
+)
+
+// SarvamVoices are the speakers of Sarvam's bulbul:v2 model
+var SarvamVoices = []string{"anushka", "manisha", "vidya", "arya", "abhilash", "karun", "hitesh"}
+
+// Voices lists the voices of a TTS provider. espeak-ng stands in for each
+// Sarvam voice with a variant of the same gender.
+func Voices(provider string) []string {
+	switch provider {
+	case "", TTSSarvam:
+		return SarvamVoices
+	case TTSEspeak:
+		voices := make([]string, 0, len(espeakVariants))
+		for name := range espeakVariants {
+			voices = append(voices, name)
+		}
+		sort.Strings(voices)
+		return voices
+	}
+	return nil
+}
+
+// ValidateVoice checks that voice is one of the Voices of the TTS provider
+func ValidateVoice(provider, voice string) error {
+	if provider == "" {
+		provider = TTSSarvam
+	}
+	voices := Voices(provider)
+	if !slices.Contains(voices, voice) {
+		return fmt.Errorf("unsupported voice %q for %s, use one of %s", voice, provider, strings.Join(voices, ", "))
+	}
+	return nil
+}
+
+// DefaultTTSSampleRate is used when TTSOptions.SampleRate is unset
+const DefaultTTSSampleRate = 22050
+
+// NewTTSProvider creates the TTS provider selected by config, defaulting to Sarvam
+func NewTTSProvider(config PipelineConfig) (TTSProvider, error) {
+	settings := config.Settings.OrDefault()
+	switch config.TTSProvider {
+	case "", TTSSarvam:
+		if config.SarvamKey == "" {
+			return nil, fmt.Errorf("SARVAM_API_KEY is required for the sarvam TTS provider")
+		}
+		tts := NewSarvamTTS(config.SarvamKey)
+		tts.Cache = config.Cache
+		tts.ChunkSize = settings.TTS.ChunkSize
+		tts.Timeout = settings.Timeouts.TTSChunk
+		return tts, nil
+	case TTSEspeak:
+		tts, err := NewEspeakTTS()
+		if err != nil {
+			return nil, err
+		}
+		tts.Cache = config.Cache
+		tts.Timeout = settings.Timeouts.TTSChunk
+		return tts, nil
+	default:
+		return nil, fmt.Errorf("unknown TTS provider: %s", config.TTSProvider)
+	}
+}
+
+// synthesizeChunked cleans and splits text into chunks of at most maxChunk
+// bytes, synthesizes each with synth and concatenates the results into
+// outputPath. Chunks that fail are skipped unless all of them fail.
+func synthesizeChunked(ctx context.Context, text, outputPath string, maxChunk int, synth func(chunk, chunkPath string) error) error {
+	text = CleanTextForTTS(text)
+	if text == "" {
+		return fmt.Errorf("empty text after cleaning")
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return err
+	}
+
+	chunks := SplitTextIntoChunks(text, maxChunk)
+	if len(chunks) == 1 {
+		return synth(chunks[0], outputPath)
+	}
+
+	tempDir := filepath.Join(filepath.Dir(outputPath), "temp_chunks")
+	os.MkdirAll(tempDir, 0755)
+	baseName := strings.TrimSuffix(filepath.Base(outputPath), filepath.Ext(outputPath))
+
+	var chunkFiles []string
+	for i, chunk := range chunks {
+		chunkPath := filepath.Join(tempDir, fmt.Sprintf("%s_chunk_%03d.wav", baseName, i))
+		if err := synth(chunk, chunkPath); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if IsTimeout(err) {
+				return err
+			}
+			log.Printf("[TTS] Error on chunk %d of %s: %v", i, baseName, err)
+			continue
+		}
+		chunkFiles = append(chunkFiles, chunkPath)
+	}
+
+	if len(chunkFiles) == 0 {
+		return fmt.Errorf("no audio chunks generated")
+	}
+	if len(chunkFiles) == 1 {
+		data, err := os.ReadFile(chunkFiles[0])
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(outputPath, data, 0644)
+	}
+
+	return concatenateAudioFiles(ctx, chunkFiles, outputPath, tempDir, baseName)
+}
+
+// concatenateAudioFiles joins WAV files with ffmpeg
+func concatenateAudioFiles(ctx context.Context, files []string, outputPath, tempDir, baseName string) error {
+	listContent := ""
+	for _, f := range files {
+		absPath, _ := filepath.Abs(f)
+		listContent += fmt.Sprintf("file '%s'\n", absPath)
+	}
+
+	listPath := filepath.Join(tempDir, baseName+"_list.txt")
+	if err := os.WriteFile(listPath, []byte(listContent), 0644); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-f", "concat", "-safe", "0", "-i", listPath, "-c", "copy", outputPath)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("ffmpeg concat failed: %s, output: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// CleanTextForTTS strips markdown and symbols that TTS engines read aloud.
+// Non-ASCII letters are kept so non-English text survives.
+func CleanTextForTTS(text string) string {
+	text = strings.ReplaceAll(text, "**", "")
+	text = strings.ReplaceAll(text, "*", "")
+
+	for strings.Contains(text, "#") {
+		idx := strings.Index(text, "#")
+		endIdx := strings.Index(text[idx:], " ")
+		if endIdx == -1 {
+			text = text[:idx]
+		} else {
+			text = text[:idx] + text[idx+endIdx:]
+		}
+	}
+
+	var result strings.Builder
+	for _, r := range text {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
+			r == ' ' || r == '.' || r == ',' || r == '!' || r == '?' || r == ';' ||
+			r == ':' || r == '-' || r == '(' || r == ')' || r == '"' || r == '\'' ||
+			r > 127 {
+			result.WriteRune(r)
+		} else {
+			result.WriteRune(' ')
+		}
+	}
+
+	text = result.String()
+	for strings.Contains(text, "  ") {
+		text = strings.ReplaceAll(text, "  ", " ")
+	}
+
+	return strings.TrimSpace(text)
+}
+
+// SplitTextIntoChunks groups sentences into chunks of at most maxLength bytes
+func SplitTextIntoChunks(text string, maxLength int) []string {
+	if len(text) <= maxLength {
+		return []string{text}
+	}
+
+	var chunks []string
+	sentences := splitOnSentences(text)
+
+	currentChunk := ""
+	for _, sentence := range sentences {
+		if len(currentChunk)+len(sentence)+1 <= maxLength {
+			currentChunk += sentence + " "
+		} else {
+			if currentChunk != "" {
+				chunks = append(chunks, strings.TrimSpace(currentChunk))
+			}
+			currentChunk = sentence + " "
+		}
+	}
+	if currentChunk != "" {
+		chunks = append(chunks, strings.TrimSpace(currentChunk))
+	}
+
+	return chunks
+}
+
+func splitOnSentences(text string) []string {
+	var sentences []string
+	current := ""
+
+	for _, r := range text {
+		current += string(r)
+		if r == '.' || r == '!' || r == '?' || r == '।' || r == '॥' {
+			sentences = append(sentences, strings.TrimSpace(current))
+			current = ""
+		}
+	}
+	if current != "" {
+		sentences = append(sentences, strings.TrimSpace(current))
+	}
+
+	return sentences
+}
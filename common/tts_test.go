@@ -0,0 +1,104 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateVoice(t *testing.T) {
+	tests := []struct {
+		provider, voice string
+		ok              bool
+	}{
+		{"", "vidya", true},
+		{TTSSarvam, "karun", true},
+		{TTSSarvam, "alloy", false},
+		{TTSEspeak, "hitesh", true},
+		{TTSEspeak, "f3", false},
+		{"polly", "vidya", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateVoice(tt.provider, tt.voice); (err == nil) != tt.ok {
+			t.Errorf("ValidateVoice(%q, %q) = %v, want ok %v", tt.provider, tt.voice, err, tt.ok)
+		}
+	}
+
+	// espeak-ng stands in for every Sarvam voice
+	if got, want := strings.Join(Voices(TTSEspeak), ","), "abhilash,anushka,arya,hitesh,karun,manisha,vidya"; got != want {
+		t.Errorf("espeak voices %s, want %s", got, want)
+	}
+	if len(Voices("")) != len(SarvamVoices) || Voices("polly") != nil {
+		t.Error("unexpected voices for the default or an unknown provider")
+	}
+}
+
+func TestEspeakVoice(t *testing.T) {
+	tests := []struct {
+		opts TTSOptions
+		want string
+	}{
+		{TTSOptions{Language: "hi-IN", Voice: "vidya"}, "hi+f3"},
+		{TTSOptions{Language: "od-IN", Voice: "karun"}, "or+m3"},
+		{TTSOptions{Language: "ta-IN", Voice: "Hitesh"}, "ta+m4"},
+		{TTSOptions{Voice: "anushka"}, "en+f2"},
+		{TTSOptions{Language: "bn-IN", Voice: "alloy"}, "bn"},
+		{TTSOptions{}, "en"},
+	}
+	for _, tt := range tests {
+		if got := espeakVoice(tt.opts); got != tt.want {
+			t.Errorf("espeakVoice(%+v) = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestSynthesizeChunked(t *testing.T) {
+	// Two sentences, too long for one chunk together
+	text := "The first sentence is here. The second sentence is there."
+	output := filepath.Join(t.TempDir(), "audio", "intro.wav")
+	run := func(ctx context.Context, synth func(n int, chunkPath string) error) (int, error) {
+		calls := 0
+		err := synthesizeChunked(ctx, text, output, 30, func(chunk, chunkPath string) error {
+			calls++
+			return synth(calls, chunkPath)
+		})
+		return calls, err
+	}
+
+	// A failed chunk is skipped
+	calls, err := run(context.Background(), func(n int, chunkPath string) error {
+		if n == 2 {
+			return errors.New("rate limited")
+		}
+		return os.WriteFile(chunkPath, []byte(fmt.Sprintf("chunk %d", n)), 0644)
+	})
+	if data, _ := os.ReadFile(output); err != nil || calls != 2 || string(data) != "chunk 1" {
+		t.Errorf("failed chunk not skipped: %v, %d calls, output %q", err, calls, data)
+	}
+
+	calls, err = run(context.Background(), func(n int, chunkPath string) error { return errors.New("rate limited") })
+	if err == nil || calls != 2 {
+		t.Errorf("all chunks failing should fail: %v, %d calls", err, calls)
+	}
+
+	// A timeout fails the whole text rather than leaving a gap
+	timeout := &StageTimeoutError{Stage: StageTTS, Timeout: time.Second}
+	calls, err = run(context.Background(), func(n int, chunkPath string) error { return timeout })
+	if err != timeout || calls != 1 {
+		t.Errorf("timeout not returned: %v, %d calls", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls, err = run(ctx, func(n int, chunkPath string) error {
+		cancel()
+		return errors.New("request aborted")
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("cancellation not returned: %v, %d calls", err, calls)
+	}
+}
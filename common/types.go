@@ -1,10 +1,13 @@
 package common
 
+import "time"
+
 type SectionData struct {
 	Title   string
 	Script  string
 	Bullets []string
 	Image   string // Path to image file
+	Caption string // Caption of the image, from the paper
 }
 
 type PipelineConfig struct {
@@ -14,6 +17,41 @@ type PipelineConfig struct {
 	SarvamKey string
 	OpenAIKey string // Optional
 	Mode      string // "video" or "poster"
+
+	SourceType string         // "pdf" (default) or "latex" to read the paper from SourcePath
+	SourcePath string         // Optional, LaTeX source of the paper: a .tex file or .zip/.tar.gz project
+	Metadata   *PaperMetadata // Optional, known title/authors (e.g. from arXiv), skips extraction
+
+	LLMProvider   string // "gemini" (default), "openai" or "fake"
+	LLMModel      string // Optional, provider default if empty
+	OpenAIBaseURL string // Optional, for OpenAI-compatible servers such as llama.cpp or Ollama
+	TTSProvider   string // "sarvam" (default) or "espeak"
+	Language      string // Optional, narration and slide language, one of LanguageCodes (DefaultLanguage)
+
+	MaxInputTokens int // Optional, papers above this are condensed before prompting (DefaultMaxInputTokens)
+
+	BurnSubtitles bool          // Also render subtitles into the video; SRT/WebVTT files are always written
+	SubtitleStyle SubtitleStyle // Optional, look of burned-in subtitles (DefaultSubtitleStyle if zero)
+	TitleCard     TitleCard     // Optional, fonts, colors and logo of the reel title background
+	AvatarPairID  string        // Optional, reel avatar pair from the assets' avatars.json (the first if empty)
+	ReviewScript  bool          // Reel only: stop once the dialogue is written, so it can be edited before rendering
+
+	TargetDuration time.Duration // Optional, aimed length of the video narration or reel dialogue (the prompts' own if zero)
+	PosterTheme    string        // Optional, poster color theme, one of poster.ColorThemes ("default" if empty)
+
+	Settings    *Settings         // Optional, model, media, concurrency and timeout knobs (DefaultSettings if nil)
+	LayoutModel LayoutModelLoader // Optional, loads the PDF layout model, e.g. layout.Load (font heuristics and no cropped figures if nil)
+
+	Progress ProgressFunc // Optional, receives stage progress events
+	Cache    *Cache       // Optional, shared cache for LLM and TTS responses
+}
+
+// Input returns the file the paper is read from, for logs and output names
+func (c PipelineConfig) Input() string {
+	if c.SourceType == SourceLatex && c.PDFPath == "" {
+		return c.SourcePath
+	}
+	return c.PDFPath
 }
 
 // Standard section order for academic papers
@@ -10,7 +10,7 @@ import (
 	"strings"
 )
 
-// Constants for YOLO detection
+// Defaults for YOLO detection
 const (
 	ConfThreshold = 0.30
 	NMSThreshold  = 0.45
@@ -65,8 +65,8 @@ func SaveImage(path string, img image.Image) error {
 	return png.Encode(f, img)
 }
 
-// ParseYOLOOutput parses YOLO model output into bounding boxes
-func ParseYOLOOutput(data []float32, imgW, imgH, dx, dy int, scale float64) ([]image.Rectangle, []int, []float32) {
+// ParseYOLOOutput parses YOLO model output into bounding boxes scoring above confThreshold
+func ParseYOLOOutput(data []float32, imgW, imgH, dx, dy int, scale float64, confThreshold float32) ([]image.Rectangle, []int, []float32) {
 	channels := 15
 	anchors := 21504
 
@@ -85,7 +85,7 @@ func ParseYOLOOutput(data []float32, imgW, imgH, dx, dy int, scale float64) ([]i
 			}
 		}
 
-		if maxScore > ConfThreshold {
+		if maxScore > confThreshold {
 			cx := data[0*anchors+j]
 			cy := data[1*anchors+j]
 			w := data[2*anchors+j]
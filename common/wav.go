@@ -0,0 +1,90 @@
+package common
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+)
+
+// ReadWAV returns the samples of a PCM (8/16/24/32-bit) or 32-bit float WAV
+// file, mixed down to mono in [-1, 1], and its sample rate
+func ReadWAV(path string) ([]float64, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, fmt.Errorf("%s is not a WAV file", path)
+	}
+
+	var format, channels, rate, bits int
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := data[pos+8:]
+		if size > len(body) {
+			// Streamed WAVs (e.g. from ffmpeg pipes) leave the size unset
+			size = len(body)
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, fmt.Errorf("%s: short fmt chunk", path)
+			}
+			format = int(binary.LittleEndian.Uint16(body[0:2]))
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			rate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bits = int(binary.LittleEndian.Uint16(body[14:16]))
+			if format == 0xFFFE && size >= 26 {
+				// WAVE_FORMAT_EXTENSIBLE keeps the real format in the sub-format GUID
+				format = int(binary.LittleEndian.Uint16(body[24:26]))
+			}
+		case "data":
+			if channels == 0 {
+				return nil, 0, fmt.Errorf("%s: data before fmt chunk", path)
+			}
+			samples, err := decodePCM(body, format, channels, bits)
+			if err != nil {
+				return nil, 0, fmt.Errorf("%s: %w", path, err)
+			}
+			return samples, rate, nil
+		}
+		pos += 8 + size + size%2 // chunks are word aligned
+	}
+	return nil, 0, fmt.Errorf("%s: no audio data", path)
+}
+
+func decodePCM(body []byte, format, channels, bits int) ([]float64, error) {
+	width := bits / 8
+	if (format != 1 && format != 3) || (format == 3 && bits != 32) || width < 1 || width > 4 {
+		return nil, fmt.Errorf("unsupported WAV format %d with %d-bit samples", format, bits)
+	}
+
+	frame := width * channels
+	samples := make([]float64, len(body)/frame)
+	for i := range samples {
+		var sum float64
+		for c := 0; c < channels; c++ {
+			b := body[i*frame+c*width:]
+			var v float64
+			switch {
+			case format == 3:
+				v = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+			case width == 1:
+				v = (float64(b[0]) - 128) / 128 // 8-bit PCM is unsigned
+			case width == 2:
+				v = float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+			case width == 3:
+				v = float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+			default:
+				v = float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+			}
+			sum += v
+		}
+		samples[i] = sum / float64(channels)
+	}
+	return samples, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sync"
@@ -56,7 +57,7 @@ func TestConcurrentUsers(t *testing.T) {
 			}
 
 			fmt.Printf("[User %d] Starting pipeline...\n", id)
-			if err := video.ProcessVideoPipeline(config); err != nil {
+			if err := video.ProcessVideoPipeline(context.Background(), config); err != nil {
 				fmt.Printf("[User %d] Failed: %v\n", id, err)
 				errors <- fmt.Errorf("user %d error: %w", id, err)
 			} else {
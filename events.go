@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"saral_go_testing/common"
+
+	"github.com/gorilla/websocket"
+)
+
+// JobEvent is a live update about a job, streamed to /jobs/{id}/events clients
+type JobEvent struct {
+	Type     string                `json:"type"` // "status", "progress" or "log"
+	JobID    string                `json:"job_id"`
+	Status   *JobStatus            `json:"status,omitempty"`
+	Progress *common.ProgressEvent `json:"progress,omitempty"`
+	Message  string                `json:"message,omitempty"`
+	Time     time.Time             `json:"time"`
+}
+
+// EventBroker fans job events out to subscribers of each job
+type EventBroker struct {
+	subscribers map[string]map[chan JobEvent]struct{}
+	mu          sync.Mutex
+}
+
+func NewEventBroker() *EventBroker {
+	return &EventBroker{subscribers: make(map[string]map[chan JobEvent]struct{})}
+}
+
+// Subscribe returns a channel of events for jobID and a func to stop receiving them
+func (b *EventBroker) Subscribe(jobID string) (<-chan JobEvent, func()) {
+	ch := make(chan JobEvent, 64)
+
+	b.mu.Lock()
+	if b.subscribers[jobID] == nil {
+		b.subscribers[jobID] = make(map[chan JobEvent]struct{})
+	}
+	b.subscribers[jobID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if subs, ok := b.subscribers[jobID]; ok {
+			delete(subs, ch)
+			if len(subs) == 0 {
+				delete(b.subscribers, jobID)
+			}
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Publish delivers an event to every subscriber of its job. Slow subscribers
+// drop events rather than stalling the worker that publishes them.
+func (b *EventBroker) Publish(event JobEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[event.JobID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// eventStream writes job events to a single client connection
+type eventStream interface {
+	Send(event JobEvent) error
+	Ping() error
+	Done() <-chan struct{}
+	Close()
+}
+
+// sseStream streams events as Server-Sent Events
+type sseStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	done    <-chan struct{}
+	counter int
+}
+
+func newSSEStream(w http.ResponseWriter, r *http.Request) (*sseStream, error) {
+	rc := http.NewResponseController(w)
+	// Streams outlive the server's WriteTimeout, so lift the deadline
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		return nil, err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return nil, fmt.Errorf("streaming not supported: %w", err)
+	}
+
+	return &sseStream{w: w, rc: rc, done: r.Context().Done()}, nil
+}
+
+func (s *sseStream) Send(event JobEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.counter++
+	if _, err := fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", s.counter, event.Type, data); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *sseStream) Ping() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *sseStream) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *sseStream) Close() {}
+
+// originAllowed accepts WebSocket upgrades from the server's own origin, the
+// allowed ones, and clients that send no Origin (anything but a browser)
+func originAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// wsStream streams events as JSON text messages over a WebSocket
+type wsStream struct {
+	conn *websocket.Conn
+	done chan struct{}
+}
+
+func newWebSocketStream(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (*wsStream, error) {
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return originAllowed(r, allowedOrigins) },
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &wsStream{conn: conn, done: make(chan struct{})}
+
+	// Read (and discard) client frames so close and pong messages are processed
+	go func() {
+		defer close(stream.done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	return stream, nil
+}
+
+func (s *wsStream) Send(event JobEvent) error {
+	s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return s.conn.WriteJSON(event)
+}
+
+func (s *wsStream) Ping() error {
+	return s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+}
+
+func (s *wsStream) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *wsStream) Close() {
+	s.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	s.conn.Close()
+}
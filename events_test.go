@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestEventBroker(t *testing.T) {
+	b := NewEventBroker()
+	events, unsubscribe := b.Subscribe("a")
+	other, unsubscribeOther := b.Subscribe("b")
+
+	b.Publish(JobEvent{Type: "log", JobID: "a", Message: "hello"})
+	if event := <-events; event.Message != "hello" || event.Time.IsZero() {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if len(other) != 0 {
+		t.Error("event delivered to another job's subscriber")
+	}
+
+	// A subscriber that doesn't read loses events instead of blocking
+	for i := 0; i < 100; i++ {
+		b.Publish(JobEvent{Type: "log", JobID: "a"})
+	}
+	if len(events) != cap(events) {
+		t.Errorf("expected a full buffer of %d events, got %d", cap(events), len(events))
+	}
+
+	unsubscribe()
+	unsubscribeOther()
+	b.Publish(JobEvent{Type: "log", JobID: "a"})
+	if len(events) != cap(events) {
+		t.Error("event delivered after unsubscribe")
+	}
+	if len(b.subscribers) != 0 {
+		t.Errorf("subscribers left after unsubscribe: %v", b.subscribers)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	store := NewMemoryJobStore()
+	store.Save(&JobStatus{ID: "done", Status: "completed", Mode: "video"})
+	s := &Server{pool: NewWorkerPool(0, 1, store), allowedOrigins: []string{"https://app.example.com"}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jobs/{id}/events", s.handleJobEvents)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/jobs/done/events"
+	for origin, wantOK := range map[string]bool{
+		"":                        true,
+		ts.URL:                    true,
+		"https://app.example.com": true,
+		"https://evil.example":    false,
+	} {
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+		if wantOK {
+			if err != nil {
+				t.Errorf("origin %q rejected: %v", origin, err)
+				continue
+			}
+			var event JobEvent
+			if err := conn.ReadJSON(&event); err != nil || event.Status == nil || event.Status.Status != "completed" {
+				t.Errorf("origin %q: unexpected first event %+v (%v)", origin, event, err)
+			}
+			conn.Close()
+		} else if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("origin %q accepted", origin)
+		}
+	}
+}
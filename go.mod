@@ -3,10 +3,14 @@ module saral_go_testing
 go 1.25.6
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/gen2brain/go-fitz v1.24.15
 	github.com/google/generative-ai-go v0.20.1
+	github.com/gorilla/websocket v1.5.3
+	github.com/mattn/go-sqlite3 v1.14.33
 	github.com/yalue/onnxruntime_go v1.25.0
 	gocv.io/x/gocv v0.43.0
+	golang.org/x/image v0.35.0
 	google.golang.org/api v0.263.0
 )
 
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ErrJobNotFound is returned by a JobStore when no job exists for an ID
+var ErrJobNotFound = errors.New("job not found")
+
+// JobStore persists JobStatus records so job history survives restarts
+type JobStore interface {
+	Save(status *JobStatus) error
+	Get(jobID string) (*JobStatus, error)
+	List() ([]*JobStatus, error)
+	Close() error
+}
+
+// NewJobStore creates a job store of the given kind ("file", "sqlite" or "memory")
+func NewJobStore(kind, path string) (JobStore, error) {
+	switch kind {
+	case "", "file":
+		if path == "" {
+			path = "./jobs"
+		}
+		return NewFileJobStore(path)
+	case "sqlite":
+		if path == "" {
+			path = "./jobs.db"
+		}
+		return NewSQLiteJobStore(path)
+	case "memory":
+		return NewMemoryJobStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown job store: %s", kind)
+	}
+}
+
+// MemoryJobStore keeps jobs in memory only (history is lost on restart)
+type MemoryJobStore struct {
+	jobs map[string]*JobStatus
+	mu   sync.RWMutex
+}
+
+// NewMemoryJobStore creates an empty in-memory job store
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: make(map[string]*JobStatus)}
+}
+
+func (m *MemoryJobStore) Save(status *JobStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *status
+	m.jobs[status.ID] = &copied
+	return nil
+}
+
+func (m *MemoryJobStore) Get(jobID string) (*JobStatus, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status, ok := m.jobs[jobID]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	copied := *status
+	return &copied, nil
+}
+
+func (m *MemoryJobStore) List() ([]*JobStatus, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var statuses []*JobStatus
+	for _, status := range m.jobs {
+		copied := *status
+		statuses = append(statuses, &copied)
+	}
+	sortJobs(statuses)
+	return statuses, nil
+}
+
+func (m *MemoryJobStore) Close() error {
+	return nil
+}
+
+// FileJobStore stores one JSON file per job in a directory
+type FileJobStore struct {
+	Dir string
+	mu  sync.RWMutex
+}
+
+// NewFileJobStore creates a file-backed job store rooted at dir
+func NewFileJobStore(dir string) (*FileJobStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job store dir: %w", err)
+	}
+	return &FileJobStore{Dir: dir}, nil
+}
+
+func (f *FileJobStore) path(jobID string) string {
+	return filepath.Join(f.Dir, jobID+".json")
+}
+
+func (f *FileJobStore) Save(status *JobStatus) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Write to a temp file first so a crash never leaves a truncated record
+	tmpPath := f.path(status.ID) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, f.path(status.ID))
+}
+
+func (f *FileJobStore) Get(jobID string) (*JobStatus, error) {
+	if jobID == "" || strings.ContainsAny(jobID, `/\`) {
+		return nil, ErrJobNotFound
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	data, err := os.ReadFile(f.path(jobID))
+	if os.IsNotExist(err) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var status JobStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("corrupt job record %s: %w", jobID, err)
+	}
+	return &status, nil
+}
+
+func (f *FileJobStore) List() ([]*JobStatus, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	files, err := filepath.Glob(filepath.Join(f.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []*JobStatus
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var status JobStatus
+		if err := json.Unmarshal(data, &status); err != nil {
+			continue
+		}
+		statuses = append(statuses, &status)
+	}
+	sortJobs(statuses)
+	return statuses, nil
+}
+
+func (f *FileJobStore) Close() error {
+	return nil
+}
+
+// SQLiteJobStore stores jobs in an embedded SQLite database
+type SQLiteJobStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteJobStore opens (or creates) a SQLite job database at path
+func NewSQLiteJobStore(path string) (*SQLiteJobStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job database dir: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job database: %w", err)
+	}
+	// SQLite allows a single writer; serialise access through one connection
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS jobs (
+		id         TEXT PRIMARY KEY,
+		status     TEXT NOT NULL,
+		mode       TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		started_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create jobs table: %w", err)
+	}
+
+	return &SQLiteJobStore{db: db}, nil
+}
+
+func (s *SQLiteJobStore) Save(status *JobStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO jobs (id, status, mode, data, started_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status,
+			mode = excluded.mode,
+			data = excluded.data,
+			updated_at = excluded.updated_at`,
+		status.ID, status.Status, status.Mode, string(data),
+		status.StartedAt.UnixNano(), time.Now().UnixNano())
+	return err
+}
+
+func (s *SQLiteJobStore) Get(jobID string) (*JobStatus, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM jobs WHERE id = ?`, jobID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var status JobStatus
+	if err := json.Unmarshal([]byte(data), &status); err != nil {
+		return nil, fmt.Errorf("corrupt job record %s: %w", jobID, err)
+	}
+	return &status, nil
+}
+
+func (s *SQLiteJobStore) List() ([]*JobStatus, error) {
+	rows, err := s.db.Query(`SELECT data FROM jobs ORDER BY started_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []*JobStatus
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var status JobStatus
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
+			continue
+		}
+		statuses = append(statuses, &status)
+	}
+	return statuses, rows.Err()
+}
+
+func (s *SQLiteJobStore) Close() error {
+	return s.db.Close()
+}
+
+// sortJobs orders jobs by submission time, oldest first
+func sortJobs(statuses []*JobStatus) {
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].StartedAt.Before(statuses[j].StartedAt)
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"saral_go_testing/common"
+)
+
+func TestJobStores(t *testing.T) {
+	dir := t.TempDir()
+
+	fileStore, err := NewFileJobStore(filepath.Join(dir, "jobs"))
+	if err != nil {
+		t.Fatalf("file store: %v", err)
+	}
+	sqliteStore, err := NewSQLiteJobStore(filepath.Join(dir, "jobs.db"))
+	if err != nil {
+		t.Fatalf("sqlite store: %v", err)
+	}
+
+	stores := map[string]JobStore{
+		"memory": NewMemoryJobStore(),
+		"file":   fileStore,
+		"sqlite": sqliteStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			defer store.Close()
+
+			if _, err := store.Get("missing"); err != ErrJobNotFound {
+				t.Fatalf("expected ErrJobNotFound, got %v", err)
+			}
+
+			first := &JobStatus{ID: "1", Status: "queued", Mode: "video", StartedAt: time.Now()}
+			second := &JobStatus{ID: "2", Status: "queued", Mode: "poster", StartedAt: time.Now().Add(time.Second)}
+			for _, status := range []*JobStatus{second, first} {
+				if err := store.Save(status); err != nil {
+					t.Fatalf("save: %v", err)
+				}
+			}
+
+			first.Status = "completed"
+			if err := store.Save(first); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+
+			got, err := store.Get("1")
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if got.Status != "completed" || got.Mode != "video" {
+				t.Errorf("unexpected status: %+v", got)
+			}
+
+			all, err := store.List()
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if len(all) != 2 || all[0].ID != "1" || all[1].ID != "2" {
+				t.Errorf("expected jobs [1 2] in submission order, got %d jobs", len(all))
+			}
+		})
+	}
+}
+
+func TestWorkerPoolRecover(t *testing.T) {
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "paper.pdf")
+	os.WriteFile(pdfPath, []byte("%PDF-1.4"), 0644)
+
+	store := NewMemoryJobStore()
+	store.Save(&JobStatus{ID: "done", Status: "completed", Mode: "video", PDFPath: pdfPath})
+	store.Save(&JobStatus{ID: "running", Status: "processing", Mode: "video", PDFPath: pdfPath})
+	store.Save(&JobStatus{ID: "lost", Status: "queued", Mode: "poster", PDFPath: filepath.Join(dir, "gone.pdf")})
+
+	// No workers, so recovered jobs stay on the channel for inspection
+	pool := NewWorkerPool(0, 10, store)
+
+	n := pool.Recover(func(status *JobStatus) *Job {
+		return &Job{ID: status.ID, PDFPath: status.PDFPath, Mode: status.Mode}
+	})
+	if n != 1 {
+		t.Fatalf("expected 1 recovered job, got %d", n)
+	}
+
+	select {
+	case job := <-pool.jobs:
+		if job.ID != "running" {
+			t.Errorf("expected job 'running' to be re-queued, got %s", job.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("recovered job was not re-queued")
+	}
+
+	if status, _ := pool.GetStatus("lost"); status == nil || status.Status != "failed" {
+		t.Errorf("job with missing PDF should be marked failed, got %+v", status)
+	}
+}
+
+func TestWorkerPoolShutdown(t *testing.T) {
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "paper.pdf")
+	os.WriteFile(pdfPath, []byte("%PDF-1.4"), 0644)
+
+	store := NewMemoryJobStore()
+	store.Save(&JobStatus{ID: "failed", Status: "failed", Mode: "poster", PDFPath: pdfPath})
+	store.Save(&JobStatus{ID: "queued", Status: "queued", Mode: "poster", PDFPath: pdfPath})
+	pool := NewWorkerPool(2, 10, store)
+	pool.Shutdown()
+	pool.Shutdown()
+
+	// Nothing sends on a closed queue; jobs stay queued for the next start
+	if err := pool.Submit(&Job{ID: "new", Mode: "poster", PDFPath: pdfPath}); err != ErrPoolClosed {
+		t.Errorf("submit after shutdown: %v", err)
+	}
+	if status, _ := pool.GetStatus("new"); status == nil || status.Status != "queued" {
+		t.Errorf("job submitted after shutdown should stay queued, got %+v", status)
+	}
+	if _, err := pool.Retry(&Job{ID: "failed", Mode: "poster", PDFPath: pdfPath}); err != ErrPoolClosed {
+		t.Errorf("retry after shutdown: %v", err)
+	}
+	pool.Recover(func(status *JobStatus) *Job { return &Job{ID: status.ID} })
+	time.Sleep(10 * time.Millisecond)
+	if len(pool.jobs) != 0 {
+		t.Errorf("%d jobs queued after shutdown", len(pool.jobs))
+	}
+}
+
+func TestWorkerPoolProgress(t *testing.T) {
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "paper.pdf")
+	os.WriteFile(pdfPath, []byte("%PDF-1.4"), 0644)
+
+	store := NewMemoryJobStore()
+	store.Save(&JobStatus{ID: "job", Status: "processing", Mode: "video", PDFPath: pdfPath})
+	pool := NewWorkerPool(0, 10, store)
+	events, unsubscribe := pool.events.Subscribe("job")
+	defer unsubscribe()
+
+	reporter := common.NewProgressReporter(func(e common.ProgressEvent) { pool.updateProgress("job", e) }, 5)
+	reporter.Stage(3, "audio")
+	reporter.Item("audio", 1, 2)
+
+	status, _ := pool.GetStatus("job")
+	if p := status.Progress; p == nil || p.Stage != "audio" || p.Done != 1 || p.Total != 2 || p.Percent != 50 {
+		t.Errorf("unexpected progress %+v", p)
+	}
+	if event := <-events; event.Type != "progress" || event.Progress.Stage != "audio" {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	// A retried job starts again without its old progress
+	pool.updateStatus("job", "failed", "boom")
+	if _, err := pool.Retry(&Job{ID: "job", Mode: "video", PDFPath: pdfPath}); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := pool.GetStatus("job"); status.Progress != nil {
+		t.Errorf("progress kept on retry: %+v", status.Progress)
+	}
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"os"
+	"os/signal"
 	"runtime"
+	"strings"
 	"time"
 
 	"saral_go_testing/common"
+	"saral_go_testing/common/layout"
 	"saral_go_testing/pipelines/poster"
+	"saral_go_testing/pipelines/reel"
 	"saral_go_testing/pipelines/video"
 )
 
@@ -17,47 +22,209 @@ func main() {
 	serverMode := flag.Bool("server", false, "Run as HTTP server")
 	port := flag.String("port", ":8080", "Server port (only with --server)")
 	workers := flag.Int("workers", runtime.NumCPU(), "Number of worker goroutines (only with --server)")
+	jobStore := flag.String("job-store", "file", "Job store backend: 'file', 'sqlite' or 'memory' (only with --server)")
+	jobStorePath := flag.String("job-store-path", "", "Job store directory or database file (default ./jobs or ./jobs.db)")
+	resume := flag.String("resume", "", "Resume an interrupted run from its output directory, skipping completed stages")
+	cacheDir := flag.String("cache-dir", "./cache", "Directory for cached Gemini and TTS responses (empty disables caching)")
+	cacheMaxMB := flag.Int64("cache-max-mb", 1024, "Maximum cache size in MB before least recently used entries are evicted (0 = unlimited)")
+	cacheTTL := flag.Duration("cache-ttl", 30*24*time.Hour, "How long cached responses stay valid (0 = forever)")
+	llmProvider := flag.String("llm", common.LLMGemini, "LLM provider: 'gemini', 'openai' (any OpenAI-compatible server) or 'fake'")
+	openAIBaseURL := flag.String("openai-base-url", "", "Base URL of an OpenAI-compatible API, e.g. http://localhost:11434/v1 for Ollama")
+	ttsProvider := flag.String("tts", common.TTSSarvam, "TTS provider: 'sarvam' or 'espeak' (offline, needs espeak-ng)")
+	language := flag.String("language", common.DefaultLanguage, "Narration and slide language for video and reel mode, e.g. 'hindi' or 'tamil'")
+	burnSubtitles := flag.Bool("burn-subtitles", false, "Burn subtitles into the video or reel (SRT and WebVTT files are always written)")
+	subtitleStyle := flag.String("subtitle-style", "", "Burned-in subtitle style, e.g. 'font=Noto Sans,size=20,color=#FFFF00,outline=#000000,position=top'")
+	avatarPair := flag.String("avatar-pair", "", "Reel avatar pair ID from assets/avatars.json (default: the first pair)")
+	titleCard := flag.String("title-card", "", "Reel title background, e.g. 'title_font=Inter-Bold.ttf,font=Inter.ttf,background=#0B1F3A,title_color=#FFFFFF,color=#C8D3E0,logo=lab.png,venue=ICML 2025'")
+	arxivSource := flag.Bool("arxiv-source", false, "Also download the LaTeX source when the input is an arXiv ID or URL, and read the paper from it")
+	arxivBaseURL := flag.String("arxiv-base-url", common.DefaultArxivBaseURL, "arXiv server to fetch papers and metadata from")
+	allowedOrigins := flag.String("allowed-origins", "", "Comma-separated browser origins besides the server's own that may open WebSocket event streams, or '*' (only with --server)")
+	configPath := flag.String("config", "", "TOML settings file; --print-config shows every setting")
+	printConfig := flag.Bool("print-config", false, "Print the effective settings as TOML and exit")
+	settingsFlags := common.RegisterSettingsFlags(flag.CommandLine)
 	flag.Parse()
 
+	// Flags given with --resume override what the interrupted run used
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	// .env may hold SARAL_* settings as well as API keys
+	if err := common.LoadEnv(".env"); err != nil {
+		log.Println("No .env file found or error reading it")
+	}
+	settings, settingsErr := common.LoadSettings(*configPath, settingsFlags)
+	if settingsErr != nil {
+		log.Fatal(settingsErr)
+	}
+	if *printConfig {
+		if err := settings.WriteTOML(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	common.SetTTSConcurrency(settings.TTS.Concurrency)
+	video.SetFFmpegConcurrency(settings.Video.FFmpegConcurrency)
+
+	card, cardErr := common.ParseTitleCard(*titleCard)
+	if cardErr != nil {
+		log.Fatal(cardErr)
+	}
+	var style common.SubtitleStyle
+	if *subtitleStyle != "" {
+		var err error
+		if style, err = common.ParseSubtitleStyle(*subtitleStyle); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var cache *common.Cache
+	if *cacheDir != "" {
+		var err error
+		cache, err = common.NewCache(*cacheDir, *cacheMaxMB*1024*1024, *cacheTTL)
+		if err != nil {
+			log.Printf("Warning: caching disabled: %v", err)
+		}
+	}
+
 	if *serverMode {
-		StartServer(*port, *workers)
+		StartServer(ServerOptions{
+			Addr:         *port,
+			Workers:      *workers,
+			JobStore:     *jobStore,
+			JobStorePath: *jobStorePath,
+			Cache:        cache,
+
+			LLMProvider:   *llmProvider,
+			OpenAIBaseURL: *openAIBaseURL,
+			TTSProvider:   *ttsProvider,
+			Language:      *language,
+			ArxivBaseURL:  *arxivBaseURL,
+			TitleCard:     card,
+			Settings:      settings,
+
+			AllowedOrigins: strings.FieldsFunc(*allowedOrigins, func(r rune) bool { return r == ',' || r == ' ' }),
+		})
 		return
 	}
 
+	// Ctrl-C cancels the pipeline and kills any running ffmpeg/pdflatex
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	args := flag.Args()
-	if len(args) < 1 {
-		log.Fatal("Usage: go run . [--mode=video|poster] <pdf_path>\n       go run . --server [--port=:8080] [--workers=4]")
-	}
-	pdfPath := args[0]
+	outputDir := "./output/output_" + time.Now().Format("20060102_150405")
+	var pdfPath, sourceType, sourcePath string
+	var paper *common.ArxivPaper
 
-	if err := common.LoadEnv(".env"); err != nil {
-		log.Println("No .env file found or error reading it")
+	if *resume != "" {
+		// The manifest remembers the input and mode of the interrupted run
+		manifest, err := common.LoadManifest(*resume)
+		if err != nil {
+			log.Fatalf("Cannot resume from %s: %v", *resume, err)
+		}
+		if manifest.PDFPath == "" && manifest.SourcePath == "" {
+			log.Fatalf("Cannot resume from %s: no %s found", *resume, common.ManifestFile)
+		}
+		pdfPath = manifest.PDFPath
+		sourceType, sourcePath = manifest.SourceType, manifest.SourcePath
+		if manifest.LLM != "" && !set["llm"] {
+			*llmProvider = manifest.LLM
+		}
+		if manifest.LLM == *llmProvider && !set["llm-model"] {
+			settings.LLM.Model = manifest.LLMModel
+		}
+		if manifest.Language != "" && !set["language"] {
+			*language = manifest.Language
+		}
+		if manifest.AvatarPair != "" && !set["avatar-pair"] {
+			*avatarPair = manifest.AvatarPair
+		}
+		if !set["burn-subtitles"] {
+			*burnSubtitles = manifest.BurnSubtitles
+		}
+		if manifest.SubtitleStyle != nil && !set["subtitle-style"] {
+			style = *manifest.SubtitleStyle
+		}
+		*mode = manifest.Mode
+		outputDir = *resume
+		log.Printf("Resuming %s run in %s", *mode, outputDir)
+	} else {
+		if len(args) < 1 {
+			log.Fatal("Usage: go run . [--mode=video|poster|reel] <pdf_path|tex_path|latex_archive|arxiv_id|arxiv_url>\n       go run . --resume <output_dir>\n       go run . --server [--port=:8080] [--workers=4] [--job-store=file|sqlite|memory]")
+		}
+		pdfPath = args[0]
+
+		// Anything that isn't a local file but parses as an arXiv ID is fetched
+		if id, ok := common.ParseArxivID(pdfPath); ok {
+			if _, err := os.Stat(pdfPath); err != nil {
+				log.Printf("Fetching arXiv %s...", id)
+				paper, err = common.NewArxivClient(*arxivBaseURL).Fetch(ctx, id, "./uploads", "", *arxivSource)
+				if err != nil {
+					log.Fatalf("Failed to fetch arXiv %s: %v", id, err)
+				}
+				pdfPath = paper.PDFPath
+				if paper.SourcePath != "" {
+					sourceType, sourcePath = common.SourceLatex, paper.SourcePath
+				}
+			}
+		}
+
+		// .tex files and .zip/.tar.gz projects are read as LaTeX
+		if paper == nil && common.IsLatexSource(pdfPath) {
+			sourceType, sourcePath, pdfPath = common.SourceLatex, pdfPath, ""
+		}
 	}
 
 	config := common.PipelineConfig{
-		PDFPath:   pdfPath,
-		OutputDir: "./output/output_" + time.Now().Format("20060102_150405"),
-		GeminiKey: os.Getenv("GEMINI_API_KEY"),
-		SarvamKey: os.Getenv("SARVAM_API_KEY"),
-		Mode:      *mode,
+		PDFPath:    pdfPath,
+		SourceType: sourceType,
+		SourcePath: sourcePath,
+		OutputDir:  outputDir,
+		GeminiKey:  os.Getenv("GEMINI_API_KEY"),
+		SarvamKey:  os.Getenv("SARVAM_API_KEY"),
+		OpenAIKey:  os.Getenv("OPENAI_API_KEY"),
+		Mode:       *mode,
+		Cache:      cache,
+
+		LLMProvider:   *llmProvider,
+		LLMModel:      settings.LLM.Model,
+		OpenAIBaseURL: *openAIBaseURL,
+		TTSProvider:   *ttsProvider,
+		Language:      common.NormalizeLanguage(*language),
+		BurnSubtitles: *burnSubtitles,
+		SubtitleStyle: style,
+		TitleCard:     card,
+		AvatarPairID:  *avatarPair,
+		Settings:      &settings,
+		LayoutModel:   layout.Load,
+	}
+	if paper != nil {
+		config.Metadata = paper.Metadata
+	}
+
+	if err := common.ValidateLanguage(config.Language); err != nil {
+		log.Fatal(err)
 	}
 
-	if config.GeminiKey == "" {
+	if config.LLMProvider == common.LLMGemini && config.GeminiKey == "" {
 		log.Fatal("Please set GEMINI_API_KEY environment variable")
 	}
 
-	if *mode == "video" && config.SarvamKey == "" {
-		log.Fatal("Please set SARVAM_API_KEY environment variable for video mode")
+	if (*mode == "video" || *mode == "reel") && config.TTSProvider == common.TTSSarvam && config.SarvamKey == "" {
+		log.Fatal("Please set SARVAM_API_KEY environment variable for " + *mode + " mode, or use --tts=espeak")
 	}
 
 	var err error
 	switch *mode {
 	case "video":
 		log.Println("Running Video Pipeline...")
-		err = video.ProcessVideoPipeline(config)
+		err = common.RunJob(ctx, config, video.ProcessVideoPipeline)
 	case "poster":
 		log.Println("Running Poster Pipeline...")
-		err = poster.ProcessPosterPipeline(config)
+		err = common.RunJob(ctx, config, poster.ProcessPosterPipeline)
+	case "reel":
+		log.Println("Running Reel Pipeline...")
+		err = common.RunJob(ctx, config, reel.ProcessReelPipeline)
 	default:
 		log.Fatalf("Unknown mode: %s. Use 'video' or 'poster'", *mode)
 	}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"saral_go_testing/common"
+	"saral_go_testing/pipelines/poster"
+	"saral_go_testing/pipelines/reel"
+)
+
+// JobOptions are the per-job choices an upload can make in its JSON
+// "options" form field, over the server's settings. Zero values keep the
+// server default.
+type JobOptions struct {
+	Language   string `json:"language,omitempty"`    // video and reel
+	Voice      string `json:"voice,omitempty"`       // video narrator
+	AvatarPair string `json:"avatar_pair,omitempty"` // reel
+
+	PosterColumns int    `json:"poster_columns,omitempty"`
+	PosterWidth   int    `json:"poster_width,omitempty"`  // cm
+	PosterHeight  int    `json:"poster_height,omitempty"` // cm
+	PosterTheme   string `json:"poster_theme,omitempty"`  // one of poster.ColorThemes
+
+	VideoResolution string `json:"video_resolution,omitempty"` // e.g. "1280x720"
+	TargetDuration  string `json:"target_duration,omitempty"`  // e.g. "5m", video and reel
+	BurnSubtitles   *bool  `json:"burn_subtitles,omitempty"`   // video and reel
+}
+
+// FieldError is a problem with one field of a job's options
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Limits on what one job may ask for, beyond what Settings.Validate allows
+var (
+	maxJobResolution = common.Resolution{Width: 3840, Height: 2160}
+	videoDurations   = [2]time.Duration{time.Minute, time.Hour}
+	reelDurations    = [2]time.Duration{15 * time.Second, 3 * time.Minute}
+)
+
+// parseJobOptions decodes the JSON options of an upload, rejecting unknown
+// fields and values of the wrong type. encoding/json stops at the first such
+// field, so only it is reported; validate reports every invalid value.
+func parseJobOptions(data string) (*JobOptions, []FieldError) {
+	var options JobOptions
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&options)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the options object")
+	}
+	if err == nil {
+		return &options, nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return nil, []FieldError{{Field: typeErr.Field, Message: "must be a " + jsonKind(typeErr.Type.String())}}
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return nil, []FieldError{{Field: strings.Trim(field, `"`), Message: "unknown option"}}
+	}
+	return nil, []FieldError{{Field: "options", Message: "invalid JSON: " + err.Error()}}
+}
+
+// jsonKind names a Go type of JobOptions the way a JSON client sees it
+func jsonKind(goType string) string {
+	switch strings.TrimPrefix(goType, "*") {
+	case "int":
+		return "number"
+	case "bool":
+		return "boolean"
+	}
+	return "string"
+}
+
+// validate checks the options against mode, the job's TTS provider and the
+// server's avatar pairs. Options that don't apply to mode are errors rather
+// than silently ignored.
+func (o *JobOptions) validate(mode, tts string, avatars []reel.AvatarPair) []FieldError {
+	var errs []FieldError
+	check := func(ok bool, field, problem string) {
+		if !ok {
+			errs = append(errs, FieldError{Field: field, Message: problem})
+		}
+	}
+	onlyFor := func(set bool, field string, modes ...string) bool {
+		for _, m := range modes {
+			if m == mode {
+				return set
+			}
+		}
+		check(!set, field, "not supported in "+mode+" mode, only "+strings.Join(modes, " and "))
+		return false
+	}
+
+	if onlyFor(o.Language != "", "language", "video", "reel") {
+		if err := common.ValidateLanguage(o.Language); err != nil {
+			check(false, "language", err.Error())
+		}
+	}
+	if onlyFor(o.Voice != "", "voice", "video") {
+		if err := common.ValidateVoice(tts, o.Voice); err != nil {
+			check(false, "voice", err.Error())
+		}
+	}
+	if onlyFor(o.AvatarPair != "", "avatar_pair", "reel") {
+		check(reel.GetAvatarPairByID(avatars, o.AvatarPair) != nil, "avatar_pair", fmt.Sprintf("unknown pair %q, see GET /avatars", o.AvatarPair))
+	}
+
+	if onlyFor(o.PosterColumns != 0, "poster_columns", "poster") {
+		check(o.PosterColumns == 2 || o.PosterColumns == 3, "poster_columns", "must be 2 or 3")
+	}
+	if onlyFor(o.PosterWidth != 0, "poster_width", "poster") {
+		check(o.PosterWidth >= 20 && o.PosterWidth <= 500, "poster_width", "must be between 20 and 500 cm")
+	}
+	if onlyFor(o.PosterHeight != 0, "poster_height", "poster") {
+		check(o.PosterHeight >= 20 && o.PosterHeight <= 500, "poster_height", "must be between 20 and 500 cm")
+	}
+	if onlyFor(o.PosterTheme != "", "poster_theme", "poster") {
+		_, ok := poster.ColorThemes[o.PosterTheme]
+		check(ok, "poster_theme", fmt.Sprintf("unknown theme %q, use one of %s", o.PosterTheme, strings.Join(colorThemeNames(), ", ")))
+	}
+
+	if onlyFor(o.VideoResolution != "", "video_resolution", "video") {
+		var r common.Resolution
+		if err := r.UnmarshalText([]byte(o.VideoResolution)); err != nil {
+			check(false, "video_resolution", err.Error())
+		} else {
+			check(r.Width >= 16 && r.Height >= 16, "video_resolution", "must be at least 16x16")
+			check(r.Width <= maxJobResolution.Width && r.Height <= maxJobResolution.Height, "video_resolution", "must be at most "+maxJobResolution.String())
+			check(r.Width%2 == 0 && r.Height%2 == 0, "video_resolution", "sides must be even for H.264")
+		}
+	}
+	if onlyFor(o.TargetDuration != "", "target_duration", "video", "reel") {
+		limits := videoDurations
+		if mode == "reel" {
+			limits = reelDurations
+		}
+		d, err := time.ParseDuration(o.TargetDuration)
+		check(err == nil, "target_duration", fmt.Sprintf("invalid duration %q, e.g. 90s or 5m", o.TargetDuration))
+		if err == nil {
+			check(d >= limits[0] && d <= limits[1], "target_duration", fmt.Sprintf("must be between %s and %s for a %s", limits[0], limits[1], mode))
+		}
+	}
+	onlyFor(o.BurnSubtitles != nil, "burn_subtitles", "video", "reel")
+	return errs
+}
+
+// colorThemeNames lists poster.ColorThemes for error messages
+func colorThemeNames() []string {
+	names := make([]string, 0, len(poster.ColorThemes))
+	for name := range poster.ColorThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyTo carries validated options into a job's config. Settings are copied
+// before changing them, as the server shares its own across jobs. Language,
+// avatar pair and subtitles are the job's own fields, which the upload
+// resolves before building the config.
+func (o *JobOptions) applyTo(config *common.PipelineConfig) {
+	settings := *config.Settings.OrDefault()
+	if o.Voice != "" {
+		settings.Video.Voice = o.Voice
+	}
+	if o.VideoResolution != "" {
+		// validate has rejected resolutions that don't parse
+		settings.Video.Resolution.UnmarshalText([]byte(o.VideoResolution))
+	}
+	if o.PosterColumns != 0 {
+		settings.Poster.Columns = o.PosterColumns
+	}
+	if o.PosterWidth != 0 {
+		settings.Poster.Width = o.PosterWidth
+	}
+	if o.PosterHeight != 0 {
+		settings.Poster.Height = o.PosterHeight
+	}
+	config.Settings = &settings
+
+	config.PosterTheme = o.PosterTheme
+	if o.TargetDuration != "" {
+		config.TargetDuration, _ = time.ParseDuration(o.TargetDuration)
+	}
+}
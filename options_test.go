@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"saral_go_testing/common"
+)
+
+func TestUploadOptions(t *testing.T) {
+	store := NewMemoryJobStore()
+	s := &Server{
+		pool:        NewWorkerPool(0, 10, store),
+		uploadDir:   t.TempDir(),
+		llmProvider: common.LLMFake,
+		settings:    common.DefaultSettings(),
+	}
+
+	send := func(handler http.HandlerFunc, path, options string, wantCode int, fields ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("options", options)
+		for i := 0; i+1 < len(fields); i += 2 {
+			form.WriteField(fields[i], fields[i+1])
+		}
+		file, _ := form.CreateFormFile("pdf", "paper.pdf")
+		file.Write([]byte("%PDF-1.4"))
+		form.Close()
+
+		r := httptest.NewRequest("POST", path, &body)
+		r.Header.Set("Content-Type", form.FormDataContentType())
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != wantCode {
+			t.Fatalf("%s options %s: got %d (%s), want %d", path, options, w.Code, w.Body, wantCode)
+		}
+		return w
+	}
+	upload := func(options string, wantCode int, fields ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		return send(s.handlePDFUpload, "/?mode=poster", options, wantCode, fields...)
+	}
+	fields := func(w *httptest.ResponseRecorder) string {
+		var response struct{ Fields []FieldError }
+		json.NewDecoder(w.Body).Decode(&response)
+		var names []string
+		for _, f := range response.Fields {
+			names = append(names, f.Field)
+		}
+		return strings.Join(names, ", ")
+	}
+
+	if got := fields(upload(`{"voice": "vidya", "poster_columns": 4, "poster_theme": "neon", "target_duration": "5m"}`, 400)); got != "voice, poster_columns, poster_theme, target_duration" {
+		t.Errorf("unexpected invalid fields %q", got)
+	}
+	if got := fields(upload(`{"poster_colour": "red"}`, 400)); got != "poster_colour" {
+		t.Errorf("unknown option reported as %q", got)
+	}
+	if got := fields(upload(`{"poster_width": "90"}`, 400)); got != "poster_width" {
+		t.Errorf("mistyped option reported as %q", got)
+	}
+	if got := fields(upload(`{"poster_columns": 4}`, 400, "llm", "claude", "subtitle_style", "size=500", "review", "true")); got != "poster_columns, llm, subtitle_style, review" {
+		t.Errorf("invalid form fields reported as %q", got)
+	}
+	if got := fields(send(s.handlePosterDirect, "/poster", `{"poster_columns": 4, "colour": "red"}`, 400)); got != "colour" {
+		t.Errorf("direct poster route reported %q", got)
+	}
+	if got := fields(send(s.handlePosterDirect, "/poster", `{"poster_columns": 4}`, 400)); got != "poster_columns" {
+		t.Errorf("direct poster route reported %q", got)
+	}
+
+	upload(`{"poster_columns": 2, "poster_width": 90, "poster_theme": "forest"}`, 200)
+	job := <-s.pool.jobs
+	if poster := job.Config.Settings.Poster; poster.Columns != 2 || poster.Width != 90 || poster.Height != 72 || job.Config.PosterTheme != "forest" {
+		t.Errorf("options not applied: %+v %q", poster, job.Config.PosterTheme)
+	}
+	if s.settings.Poster.Columns != 3 {
+		t.Errorf("options changed the server's settings: %+v", s.settings.Poster)
+	}
+
+	// Retries and recovery rebuild the job with its options
+	status, _ := store.Get(job.ID)
+	if rebuilt := s.jobFromStatus(status); rebuilt.Config.Settings.Poster != job.Config.Settings.Poster || rebuilt.Config.PosterTheme != "forest" {
+		t.Errorf("options lost from the persisted status: %+v", status.Options)
+	}
+}
+
+func TestJobOptionsValidate(t *testing.T) {
+	options := &JobOptions{
+		Language:        "klingon",
+		Voice:           "alloy",
+		AvatarPair:      "a",
+		VideoResolution: "1281x720",
+		TargetDuration:  "10s",
+	}
+	var got []string
+	for _, err := range options.validate("video", common.TTSSarvam, nil) {
+		got = append(got, err.Field+": "+err.Message)
+	}
+	for i, want := range []string{
+		"language: unsupported language",
+		`voice: unsupported voice "alloy" for sarvam`,
+		"avatar_pair: not supported in video mode, only reel",
+		"video_resolution: sides must be even",
+		"target_duration: must be between 1m0s and 1h0m0s",
+	} {
+		if i >= len(got) || !strings.HasPrefix(got[i], want) {
+			t.Errorf("error %d: got %q, want %q...", i, got, want)
+		}
+	}
+
+	options = &JobOptions{TargetDuration: "45s", Language: "hindi"}
+	if errs := options.validate("reel", common.TTSSarvam, nil); len(errs) != 0 {
+		t.Errorf("valid reel options rejected: %v", errs)
+	}
+}
@@ -1,6 +1,7 @@
 package poster
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -11,27 +12,43 @@ import (
 )
 
 // ProcessPosterPipeline executes the PDF to Poster workflow
-func ProcessPosterPipeline(config common.PipelineConfig) error {
+func ProcessPosterPipeline(ctx context.Context, config common.PipelineConfig) error {
 	// Ensure OutputDir exists
 	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output dir: %w", err)
 	}
-	log.Printf("Starting poster pipeline for %s -> %s", config.PDFPath, config.OutputDir)
+	log.Printf("Starting poster pipeline for %s -> %s", config.Input(), config.OutputDir)
+	progress := common.NewProgressReporter(config.Progress, 4)
 
-	// 1. Process PDF for text
-	log.Println("Step 1: Processing PDF...")
-	pdfProc, err := common.NewPDFProcessor(config.PDFPath, config.OutputDir)
+	manifest, err := common.LoadManifest(config.OutputDir)
 	if err != nil {
-		return fmt.Errorf("failed to open PDF: %w", err)
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	manifest.PDFPath = config.PDFPath
+	manifest.SourceType = config.SourceType
+	manifest.SourcePath = config.SourcePath
+	manifest.Mode = "poster"
+	manifest.LLM, manifest.LLMModel = config.LLMProvider, config.LLMModel
+	if err := manifest.Save(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if manifest.Done("poster") {
+		log.Printf("Poster already complete, nothing to resume")
+		progress.Stage(4, "poster")
+		return nil
 	}
-	defer pdfProc.Close()
 
-	// Extract text
-	text, err := pdfProc.ExtractText()
+	// 1. Process PDF for text
+	log.Println("Step 1: Processing PDF...")
+	progress.Stage(1, "pdf")
+	text, doc, err := common.LoadPaper(ctx, config)
 	if err != nil {
-		return fmt.Errorf("text extraction failed: %w", err)
+		return err
 	}
 	log.Printf("Extracted %d chars of text", len(text))
+	if doc != nil && config.Metadata == nil {
+		config.Metadata = doc.Metadata()
+	}
 
 	if text == "" {
 		return fmt.Errorf("no text extracted from PDF")
@@ -39,38 +56,93 @@ func ProcessPosterPipeline(config common.PipelineConfig) error {
 
 	// 2. Extract images using YOLO model
 	log.Println("Step 2: Extracting images using YOLO detection...")
-	var imagePaths []string
+	progress.Stage(2, "images")
+	var figures []common.DocFigure
 
-	modelPath := "yolov8n-doclaynet.onnx"
-	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
+	imagesPath := filepath.Join(config.OutputDir, "images.json")
+	settings := config.Settings.OrDefault()
+	modelPath := settings.Layout.ModelPath
+	if doc != nil {
+		// LaTeX sources have the original figure files, no cropping needed
+		figures = withImages(doc.Figures)
+	} else if figs, ok := loadImageList(manifest, "images", imagesPath, config.OutputDir); ok {
+		log.Println("Resuming: reusing extracted images")
+		figures = figs
+	} else if _, err := os.Stat(modelPath); os.IsNotExist(err) {
 		log.Printf("Warning: YOLO model not found at %s, skipping image extraction", modelPath)
 	} else {
-		extractor, err := NewImageExtractor(modelPath)
+		extractor, err := NewImageExtractor(config.LayoutModel, settings.Layout)
 		if err != nil {
 			log.Printf("Warning: Failed to initialize image extractor: %v", err)
 		} else {
 			defer extractor.Close()
+			extractor.Progress = progress
 
-			imagePaths, err = extractor.ExtractImagesFromPDF(config.PDFPath, config.OutputDir)
+			figDoc, err := extractor.ExtractFigures(ctx, config.PDFPath, config.OutputDir)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			if err != nil {
 				log.Printf("Warning: Image extraction failed: %v", err)
-				imagePaths = []string{}
+			} else {
+				figures = withImages(figDoc.Figures)
+				saveImageList(manifest, "images", imagesPath, config.OutputDir, figures)
+				manifest.Invalidate("ranking", "poster")
 			}
 		}
 	}
-	log.Printf("Extracted %d images (Pictures/Tables)", len(imagePaths))
+	log.Printf("Extracted %d images (Pictures/Tables)", len(figures))
 
 	// 3. Generate poster content with AI
-	log.Println("Step 3: Generating poster content with Gemini...")
-	gemini, err := common.NewGeminiClient(config.GeminiKey)
+	log.Println("Step 3: Generating poster content with LLM...")
+	progress.Stage(3, "content")
+	llm, err := common.NewLLM(config)
 	if err != nil {
-		return fmt.Errorf("gemini init failed: %w", err)
+		return fmt.Errorf("llm init failed: %w", err)
 	}
-	defer gemini.Close()
+	defer llm.Close()
 
-	posterContent, err := gemini.GeneratePosterContent(text)
-	if err != nil {
-		return fmt.Errorf("poster content generation failed: %w", err)
+	contentPath := filepath.Join(config.OutputDir, "poster_content.json")
+	posterContent := &common.PosterContent{}
+	if manifest.Done("content") && common.ReadJSON(contentPath, posterContent) == nil {
+		log.Println("Resuming: reusing poster content")
+	} else {
+		condensed, err := llm.CondensePaper(ctx, text)
+		if err != nil {
+			return fmt.Errorf("paper condensation failed: %w", err)
+		}
+		posterContent, err = llm.GeneratePosterContent(ctx, condensed)
+		if err != nil {
+			return fmt.Errorf("poster content generation failed: %w", err)
+		}
+		if config.Metadata != nil {
+			posterContent.Title = config.Metadata.Title
+			posterContent.Authors = config.Metadata.Authors
+		}
+		if err := common.WriteJSON(contentPath, posterContent); err == nil {
+			manifest.Checkpoint("content", contentPath)
+		}
+		manifest.Invalidate("ranking", "poster")
+	}
+
+	// Rank the figures against the results, so the poster shows the most
+	// relevant ones
+	rankingPath := filepath.Join(config.OutputDir, "figure_ranking.json")
+	if len(figures) > 1 {
+		if ranked, ok := loadImageList(manifest, "ranking", rankingPath, config.OutputDir); ok {
+			log.Println("Resuming: reusing figure ranking")
+			figures = ranked
+		} else if ranked, err := common.RankFigures(ctx, llm, figures, posterContent.Results); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("Warning: figure ranking failed, keeping paper order: %v", err)
+		} else {
+			figures = ranked
+			saveImageList(manifest, "ranking", rankingPath, config.OutputDir, figures)
+			manifest.Invalidate("poster")
+		}
+		log.Printf("Key figure: %s", figures[0].Label)
 	}
 
 	// Log generated content summary
@@ -87,22 +159,77 @@ func ProcessPosterPipeline(config common.PipelineConfig) error {
 
 	// 4. Generate poster
 	log.Println("Step 4: Generating LaTeX poster...")
+	progress.Stage(4, "poster")
 	posterDir := filepath.Join(config.OutputDir, "poster")
 	posterGen := NewPosterGenerator(posterDir)
+	posterGen.SetDimensions(settings.Poster.Width, settings.Poster.Height)
+	posterGen.SetColumns(settings.Poster.Columns)
+	posterGen.SetColorTheme(config.PosterTheme)
+	posterGen.Timeout = settings.Timeouts.SlideCompile
 
-	// Use base name of PDF as poster name
-	baseName := strings.TrimSuffix(filepath.Base(config.PDFPath), filepath.Ext(config.PDFPath))
+	// Use base name of the input as poster name
+	baseName := strings.TrimSuffix(filepath.Base(config.Input()), filepath.Ext(config.Input()))
+	baseName = strings.TrimSuffix(baseName, ".tar")
 	posterName := baseName + "_poster"
 
-	pdfPath, err := posterGen.GeneratePoster(posterContent, imagePaths, posterName)
+	pdfPath, err := posterGen.GeneratePoster(ctx, posterContent, figures, posterName)
 	if err != nil {
 		return fmt.Errorf("poster generation failed: %w", err)
 	}
+	manifest.Checkpoint("poster", pdfPath)
 
 	log.Printf("Poster Pipeline Complete! Output: %s", pdfPath)
 	return nil
 }
 
+// withImages numbers the figures as in the paper and keeps those with an
+// image file
+func withImages(figures []common.DocFigure) []common.DocFigure {
+	common.NumberFigures(figures)
+	var kept []common.DocFigure
+	for _, f := range figures {
+		if f.Path != "" {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// loadImageList returns the figures checkpointed as stage, with image paths
+// stored relative to outputDir
+func loadImageList(manifest *common.Manifest, stage, path, outputDir string) ([]common.DocFigure, bool) {
+	var figures []common.DocFigure
+	if !manifest.Done(stage) || common.ReadJSON(path, &figures) != nil {
+		return nil, false
+	}
+
+	for i := range figures {
+		figures[i].Path = filepath.Join(outputDir, figures[i].Path)
+	}
+	return figures, true
+}
+
+func saveImageList(manifest *common.Manifest, stage, path, outputDir string, figures []common.DocFigure) {
+	rel := make([]common.DocFigure, 0, len(figures))
+	artifacts := []string{path}
+	for _, f := range figures {
+		r, err := filepath.Rel(outputDir, f.Path)
+		if err != nil {
+			log.Printf("Warning: image %s is outside the output dir, not checkpointing", f.Path)
+			return
+		}
+		artifacts = append(artifacts, f.Path)
+		f.Path = r
+		rel = append(rel, f)
+	}
+
+	if err := common.WriteJSON(path, rel); err != nil {
+		log.Printf("Warning: failed to save image list: %v", err)
+		return
+	}
+	manifest.Checkpoint(stage, artifacts...)
+}
+
 // formatPosterContent formats the poster content for debugging output
 func formatPosterContent(content *common.PosterContent) string {
 	var sb strings.Builder
@@ -1,11 +1,13 @@
 package poster
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"saral_go_testing/common"
 )
@@ -14,6 +16,7 @@ import (
 type PosterGenerator struct {
 	OutputDir string
 	Template  *PosterTemplate
+	Timeout   time.Duration // Optional, bounds each pdflatex run
 }
 
 // NewPosterGenerator creates a new poster generator
@@ -37,8 +40,16 @@ func (g *PosterGenerator) SetDimensions(width, height int) {
 	g.Template.Height = height
 }
 
-// GeneratePoster creates the poster from content and images
-func (g *PosterGenerator) GeneratePoster(content *common.PosterContent, imagePaths []string, outputName string) (string, error) {
+// SetColorTheme sets the poster color theme, ignoring names not in ColorThemes
+func (g *PosterGenerator) SetColorTheme(name string) {
+	if _, ok := ColorThemes[name]; ok {
+		g.Template.ColorTheme = name
+	}
+}
+
+// GeneratePoster creates the poster from content and figures, the most
+// important first
+func (g *PosterGenerator) GeneratePoster(ctx context.Context, content *common.PosterContent, figures []common.DocFigure, outputName string) (string, error) {
 	// Ensure output directory exists
 	if err := os.MkdirAll(g.OutputDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create output directory: %w", err)
@@ -50,7 +61,7 @@ func (g *PosterGenerator) GeneratePoster(content *common.PosterContent, imagePat
 	}
 
 	// Generate LaTeX content
-	latexContent := g.Template.GenerateLatex(content, imagePaths)
+	latexContent := g.Template.GenerateLatex(content, figures)
 
 	// Write LaTeX file
 	texFile := filepath.Join(g.OutputDir, outputName+".tex")
@@ -59,7 +70,7 @@ func (g *PosterGenerator) GeneratePoster(content *common.PosterContent, imagePat
 	}
 
 	// Compile to PDF
-	pdfPath, err := g.compileLatex(texFile)
+	pdfPath, err := g.compileLatex(ctx, texFile)
 	if err != nil {
 		return "", err
 	}
@@ -154,13 +165,14 @@ func (g *PosterGenerator) setupThemeFiles() error {
 `
 
 	// Create beamercolorthemegemini.sty
-	geminiColor := `% Gemini color theme
+	palette := g.Template.palette()
+	geminiColor := fmt.Sprintf(`%% Gemini color theme
 \ProvidesPackage{beamercolorthemegemini}
 
 \mode<presentation>
 
-\definecolor{geminiblue}{HTML}{355C7D}
-\definecolor{geminiaccent}{HTML}{6C5B7B}
+\definecolor{geminiblue}{HTML}{%s}
+\definecolor{geminiaccent}{HTML}{%s}
 \definecolor{geminibg}{HTML}{FFFFFF}
 
 \setbeamercolor{background canvas}{bg=geminibg}
@@ -175,7 +187,7 @@ func (g *PosterGenerator) setupThemeFiles() error {
 \setbeamercolor{enumerate item}{fg=geminiblue}
 
 \mode<all>
-`
+`, palette.Primary, palette.Accent)
 
 	// Write theme files
 	if err := os.WriteFile(filepath.Join(g.OutputDir, "beamerthemegemini.sty"), []byte(geminiTheme), 0644); err != nil {
@@ -189,7 +201,7 @@ func (g *PosterGenerator) setupThemeFiles() error {
 }
 
 // compileLatex compiles the LaTeX file to PDF using pdflatex
-func (g *PosterGenerator) compileLatex(texFile string) (string, error) {
+func (g *PosterGenerator) compileLatex(ctx context.Context, texFile string) (string, error) {
 	// Get absolute paths
 	absOutputDir, err := filepath.Abs(g.OutputDir)
 	if err != nil {
@@ -201,15 +213,22 @@ func (g *PosterGenerator) compileLatex(texFile string) (string, error) {
 
 	// Run pdflatex twice for proper referencing
 	for i := 0; i < 2; i++ {
-		cmd := exec.Command("pdflatex",
-			"-interaction=nonstopmode",
-			"-output-directory", absOutputDir,
-			texBaseName,
-		)
-		// Run from the directory containing the tex file
-		cmd.Dir = absOutputDir
-
-		output, err := cmd.CombinedOutput()
+		output, err := common.CommandOutput(ctx, common.StageCompile, g.Timeout, func(ctx context.Context) *exec.Cmd {
+			cmd := exec.CommandContext(ctx, "pdflatex",
+				"-interaction=nonstopmode",
+				"-output-directory", absOutputDir,
+				texBaseName,
+			)
+			// Run from the directory containing the tex file
+			cmd.Dir = absOutputDir
+			return cmd
+		})
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if common.IsTimeout(err) {
+			return "", err
+		}
 		if err != nil && i == 1 {
 			// Only fail on second attempt
 			fmt.Printf("pdflatex output: %s\n", string(output))
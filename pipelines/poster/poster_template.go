@@ -13,7 +13,30 @@ type PosterTemplate struct {
 	Width      int    // Poster width in cm
 	Height     int    // Poster height in cm
 	NumColumns int    // Number of columns
-	ColorTheme string // Color theme name
+	ColorTheme string // Color theme name, one of ColorThemes
+}
+
+// ColorPalette holds the HTML hex colors of a poster color theme
+type ColorPalette struct {
+	Primary string // Headline, footline and block titles
+	Accent  string // Sub-items
+}
+
+// ColorThemes are the poster color themes by name
+var ColorThemes = map[string]ColorPalette{
+	"default":  {Primary: "355C7D", Accent: "6C5B7B"},
+	"forest":   {Primary: "2D6A4F", Accent: "52796F"},
+	"crimson":  {Primary: "9B2226", Accent: "AE2012"},
+	"ocean":    {Primary: "005F73", Accent: "0A9396"},
+	"charcoal": {Primary: "343A40", Accent: "6C757D"},
+}
+
+// palette returns the colors of the template's theme, the default if unknown
+func (t *PosterTemplate) palette() ColorPalette {
+	if palette, ok := ColorThemes[t.ColorTheme]; ok {
+		return palette
+	}
+	return ColorThemes["default"]
 }
 
 // NewPosterTemplate creates a new poster template with default settings
@@ -26,8 +49,9 @@ func NewPosterTemplate() *PosterTemplate {
 	}
 }
 
-// GenerateLatex generates the complete LaTeX document for the poster
-func (t *PosterTemplate) GenerateLatex(content *common.PosterContent, imagePaths []string) string {
+// GenerateLatex generates the complete LaTeX document for the poster. The
+// first figures are shown, with their labels and captions from the paper.
+func (t *PosterTemplate) GenerateLatex(content *common.PosterContent, figures []common.DocFigure) string {
 	var sb strings.Builder
 
 	// Preamble
@@ -45,11 +69,11 @@ func (t *PosterTemplate) GenerateLatex(content *common.PosterContent, imagePaths
 	// Column content distribution
 	switch t.NumColumns {
 	case 3:
-		sb.WriteString(t.generateThreeColumnLayout(content, imagePaths))
+		sb.WriteString(t.generateThreeColumnLayout(content, figures))
 	case 2:
-		sb.WriteString(t.generateTwoColumnLayout(content, imagePaths))
+		sb.WriteString(t.generateTwoColumnLayout(content, figures))
 	default:
-		sb.WriteString(t.generateThreeColumnLayout(content, imagePaths))
+		sb.WriteString(t.generateThreeColumnLayout(content, figures))
 	}
 
 	sb.WriteString("\\separatorcolumn\n")
@@ -109,7 +133,7 @@ func (t *PosterTemplate) generateTitleBlock(content *common.PosterContent) strin
 `, title, authors)
 }
 
-func (t *PosterTemplate) generateThreeColumnLayout(content *common.PosterContent, imagePaths []string) string {
+func (t *PosterTemplate) generateThreeColumnLayout(content *common.PosterContent, figures []common.DocFigure) string {
 	var sb strings.Builder
 
 	// Column 1: Abstract, Introduction, Methodology
@@ -133,22 +157,17 @@ func (t *PosterTemplate) generateThreeColumnLayout(content *common.PosterContent
 	sb.WriteString("\\end{column}\n\n")
 	sb.WriteString("\\separatorcolumn\n\n")
 
-	// Column 2: Results (main findings with first image)
+	// Column 2: Results (main findings with the key figure)
 	sb.WriteString("\\begin{column}{\\colwidth}\n\n")
 
 	if len(content.Results) > 0 {
-		// Pass only the first image to Results block
-		var resultsImages []string
-		if len(imagePaths) > 0 {
-			resultsImages = imagePaths[0:1]
-		}
-		sb.WriteString(t.generateResultsBlock(content.Results, resultsImages))
+		sb.WriteString(t.generateResultsBlock(content.Results, figures))
 	}
 
 	sb.WriteString("\\end{column}\n\n")
 	sb.WriteString("\\separatorcolumn\n\n")
 
-	// Column 3: Conclusion, References, and second image
+	// Column 3: Conclusion, References, and the second figure
 	sb.WriteString("\\begin{column}{\\colwidth}\n\n")
 
 	if len(content.Conclusion) > 0 {
@@ -159,9 +178,9 @@ func (t *PosterTemplate) generateThreeColumnLayout(content *common.PosterContent
 		sb.WriteString(t.generateReferencesBlock(content.References))
 	}
 
-	// Add second image after references if available
-	if len(imagePaths) > 1 {
-		sb.WriteString(t.generateSingleFigure(imagePaths[1], 2))
+	// Add the second figure after references if available
+	if len(figures) > 1 {
+		sb.WriteString(t.generateSingleFigure(figures[1]))
 	}
 
 	sb.WriteString("\\end{column}\n\n")
@@ -169,7 +188,7 @@ func (t *PosterTemplate) generateThreeColumnLayout(content *common.PosterContent
 	return sb.String()
 }
 
-func (t *PosterTemplate) generateTwoColumnLayout(content *common.PosterContent, imagePaths []string) string {
+func (t *PosterTemplate) generateTwoColumnLayout(content *common.PosterContent, figures []common.DocFigure) string {
 	var sb strings.Builder
 
 	// Column 1: Abstract, Introduction, Methodology
@@ -194,7 +213,7 @@ func (t *PosterTemplate) generateTwoColumnLayout(content *common.PosterContent,
 	sb.WriteString("\\begin{column}{\\colwidth}\n\n")
 
 	if len(content.Results) > 0 {
-		sb.WriteString(t.generateResultsBlock(content.Results, imagePaths))
+		sb.WriteString(t.generateResultsBlock(content.Results, figures))
 	}
 
 	if len(content.Conclusion) > 0 {
@@ -239,7 +258,7 @@ func (t *PosterTemplate) generateBulletBlock(title string, bullets []string) str
 	return sb.String()
 }
 
-func (t *PosterTemplate) generateResultsBlock(results []string, imagePaths []string) string {
+func (t *PosterTemplate) generateResultsBlock(results []string, figures []common.DocFigure) string {
 	var sb strings.Builder
 
 	sb.WriteString("\\begin{block}{Results}\n")
@@ -251,19 +270,10 @@ func (t *PosterTemplate) generateResultsBlock(results []string, imagePaths []str
 	}
 	sb.WriteString("\\end{itemize}\n")
 
-	// Add images if available (limit to 1 to prevent overflow)
-	if len(imagePaths) > 0 {
-		sb.WriteString("\n\\vspace{0.5em}\n")
-		// Only include the first image to save space
-		absPath, err := filepath.Abs(imagePaths[0])
-		if err == nil {
-			sb.WriteString("\\begin{figure}\n")
-			sb.WriteString("\\centering\n")
-			// Increased size: 1.75x (17.5cm height, 0.95 textwidth)
-			sb.WriteString(fmt.Sprintf("\\includegraphics[width=0.95\\textwidth,height=17.5cm,keepaspectratio]{%s}\n", absPath))
-			sb.WriteString("\\caption{Key Figure}\n")
-			sb.WriteString("\\end{figure}\n")
-		}
+	// Add the key figure if available (limit to 1 to prevent overflow)
+	if len(figures) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(t.generateSingleFigure(figures[0]))
 	}
 
 	sb.WriteString("\\end{block}\n\n")
@@ -288,11 +298,12 @@ func (t *PosterTemplate) generateReferencesBlock(refs []string) string {
 	return sb.String()
 }
 
-// generateSingleFigure generates a single figure block with the given image path
-func (t *PosterTemplate) generateSingleFigure(imagePath string, figNum int) string {
+// generateSingleFigure generates a single figure block with the figure's
+// label and caption from the paper
+func (t *PosterTemplate) generateSingleFigure(figure common.DocFigure) string {
 	var sb strings.Builder
 
-	absPath, err := filepath.Abs(imagePath)
+	absPath, err := filepath.Abs(figure.Path)
 	if err != nil {
 		return ""
 	}
@@ -302,8 +313,31 @@ func (t *PosterTemplate) generateSingleFigure(imagePath string, figNum int) stri
 	sb.WriteString("\\centering\n")
 	// Increased size: 1.75x (17.5cm height, 0.95 textwidth)
 	sb.WriteString(fmt.Sprintf("\\includegraphics[width=0.95\\textwidth,height=17.5cm,keepaspectratio]{%s}\n", absPath))
-	sb.WriteString(fmt.Sprintf("\\caption{Figure %d}\n", figNum))
+	// Beamer's \caption can't show the paper's numbering, so the label is
+	// written out
+	sb.WriteString("\n\\vspace{0.3em}{\\small \\textbf{" + common.EscapeLatex(figure.Label) + ".}")
+	if figure.Caption != "" {
+		sb.WriteString(" " + common.EscapeLatex(shortenCaption(figure.Caption)))
+	}
+	sb.WriteString("}\n")
 	sb.WriteString("\\end{figure}\n")
 
 	return sb.String()
 }
+
+// maxCaptionRunes keeps long captions from pushing the poster's blocks off
+// the page
+const maxCaptionRunes = 240
+
+// shortenCaption cuts a caption to maxCaptionRunes at a word boundary
+func shortenCaption(caption string) string {
+	runes := []rune(caption)
+	if len(runes) <= maxCaptionRunes {
+		return caption
+	}
+	cut := string(runes[:maxCaptionRunes])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:") + "..."
+}
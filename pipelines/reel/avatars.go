@@ -0,0 +1,135 @@
+package reel
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"saral_go_testing/common"
+)
+
+// AvatarsFile is the avatar registry in the assets directory
+const AvatarsFile = "avatars.json"
+
+const (
+	minAvatarSize = 64 // px, per side
+	maxAvatarSize = 2048
+)
+
+// avatarRegistry is the JSON shape of AvatarsFile
+type avatarRegistry struct {
+	Pairs []AvatarPair `json:"pairs"`
+}
+
+// LoadAvatarPairs reads the avatar registry in assetsDir and validates each
+// pair and its images. The first pair is the default.
+func LoadAvatarPairs(assetsDir string) ([]AvatarPair, error) {
+	path := filepath.Join(assetsDir, AvatarsFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read avatar registry: %w", err)
+	}
+	var registry avatarRegistry
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("invalid avatar registry %s: %w", path, err)
+	}
+	if len(registry.Pairs) == 0 {
+		return nil, fmt.Errorf("avatar registry %s has no pairs", path)
+	}
+
+	seen := make(map[string]bool)
+	for i := range registry.Pairs {
+		pair := &registry.Pairs[i]
+		if pair.ID == "" || seen[pair.ID] {
+			return nil, fmt.Errorf("avatar pair %d: missing or duplicate id %q", i, pair.ID)
+		}
+		seen[pair.ID] = true
+
+		if pair.FemalePosition == "" {
+			pair.FemalePosition = "left"
+		}
+		if pair.MalePosition == "" {
+			pair.MalePosition = "right"
+		}
+		for _, pos := range []string{pair.FemalePosition, pair.MalePosition} {
+			if pos != "left" && pos != "right" {
+				return nil, fmt.Errorf("avatar pair %s: position %q must be left or right", pair.ID, pos)
+			}
+		}
+		if pair.FemalePosition == pair.MalePosition {
+			return nil, fmt.Errorf("avatar pair %s: both avatars are on the %s", pair.ID, pair.FemalePosition)
+		}
+
+		for _, img := range []string{pair.FemaleAvatar, pair.MaleAvatar} {
+			if img == "" || !filepath.IsLocal(img) {
+				return nil, fmt.Errorf("avatar pair %s: image %q must be a file in %s", pair.ID, img, assetsDir)
+			}
+			if err := validateAvatarImage(filepath.Join(assetsDir, img)); err != nil {
+				return nil, fmt.Errorf("avatar pair %s: %w", pair.ID, err)
+			}
+		}
+	}
+	return registry.Pairs, nil
+}
+
+// validateAvatarImage checks the avatar is a PNG of a sensible size with a
+// transparent background, so it can be overlaid on the title card
+func validateAvatarImage(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("avatar image: %w", err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return fmt.Errorf("avatar image %s is not a PNG: %w", filepath.Base(path), err)
+	}
+	size := img.Bounds().Size()
+	if size.X < minAvatarSize || size.Y < minAvatarSize || size.X > maxAvatarSize || size.Y > maxAvatarSize {
+		return fmt.Errorf("avatar image %s is %dx%d, want %d to %d px per side",
+			filepath.Base(path), size.X, size.Y, minAvatarSize, maxAvatarSize)
+	}
+	if o, ok := img.(interface{ Opaque() bool }); !ok || o.Opaque() {
+		return fmt.Errorf("avatar image %s has no transparent background", filepath.Base(path))
+	}
+	return nil
+}
+
+// Voice returns the TTS voice of character ("Person1" or "Person2")
+func (p *AvatarPair) Voice(character string) string {
+	if character == "Person2" {
+		if p != nil && p.MaleVoice != "" {
+			return p.MaleVoice
+		}
+		return common.DefaultSettings().Reel.MaleVoice
+	}
+	if p != nil && p.FemaleVoice != "" {
+		return p.FemaleVoice
+	}
+	return common.DefaultSettings().Reel.FemaleVoice
+}
+
+// OnLeft reports whether character stands on the left of the reel
+func (p *AvatarPair) OnLeft(character string) bool {
+	if character == "Person2" {
+		return p.MalePosition == "left"
+	}
+	return p.FemalePosition != "right"
+}
+
+// GetAvatarPairByID finds an avatar pair by its ID, or returns the first
+// (default) pair for an empty ID
+func GetAvatarPairByID(pairs []AvatarPair, id string) *AvatarPair {
+	if id == "" && len(pairs) > 0 {
+		return &pairs[0]
+	}
+	for i := range pairs {
+		if pairs[i].ID == id {
+			return &pairs[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package reel
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadAvatarPairs(t *testing.T) {
+	pairs, err := LoadAvatarPairs("../../assets")
+	if err != nil {
+		t.Fatalf("bundled registry: %v", err)
+	}
+	if def := GetAvatarPairByID(pairs, ""); def == nil || def.ID != pairs[0].ID {
+		t.Errorf("expected the first pair as default, got %+v", def)
+	}
+	if GetAvatarPairByID(pairs, "nobody") != nil {
+		t.Error("unknown pair found")
+	}
+
+	dir := t.TempDir()
+	writePNG := func(name string, alpha uint8) {
+		img := image.NewNRGBA(image.Rect(0, 0, 100, 120))
+		for i := range img.Pix {
+			img.Pix[i] = 255
+		}
+		img.SetNRGBA(0, 0, color.NRGBA{A: alpha})
+		f, _ := os.Create(filepath.Join(dir, name))
+		png.Encode(f, img)
+		f.Close()
+	}
+	writePNG("she.png", 0)
+	writePNG("he.png", 0)
+	writePNG("opaque.png", 255)
+
+	for _, tc := range []struct{ registry, want string }{
+		{`{"pairs":[{"id":"a","female_avatar":"she.png","male_avatar":"he.png","female_position":"right","male_position":"left"}]}`, ""},
+		{`{"pairs":[{"id":"a","female_avatar":"she.png","male_avatar":"opaque.png"}]}`, "transparent"},
+		{`{"pairs":[{"id":"a","female_avatar":"she.png","male_avatar":"../he.png"}]}`, "must be a file"},
+		{`{"pairs":[{"id":"a","female_avatar":"she.png","male_avatar":"he.png","male_position":"left"}]}`, "both avatars"},
+		{`{"pairs":[{"id":"a","female_avatar":"she.png","male_avatar":"he.png"},{"id":"a","female_avatar":"she.png","male_avatar":"he.png"}]}`, "duplicate"},
+	} {
+		os.WriteFile(filepath.Join(dir, AvatarsFile), []byte(tc.registry), 0644)
+		pairs, err := LoadAvatarPairs(dir)
+		if tc.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tc.registry, err)
+			} else if !pairs[0].OnLeft("Person2") || pairs[0].OnLeft("Person1") {
+				t.Errorf("positions not honored: %+v", pairs[0])
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want error containing %q", tc.registry, err, tc.want)
+		}
+	}
+}
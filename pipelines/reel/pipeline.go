@@ -1,79 +1,170 @@
 package reel
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"saral_go_testing/common"
 )
 
 // ProcessReelPipeline executes the full PDF to Reel workflow
 // This follows the same pattern as video.ProcessVideoPipeline and poster.ProcessPosterPipeline
-func ProcessReelPipeline(config common.PipelineConfig) error {
+func ProcessReelPipeline(ctx context.Context, config common.PipelineConfig) error {
 	// Ensure OutputDir exists
 	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output dir: %w", err)
 	}
-	log.Printf("[REEL] Starting reel pipeline for %s -> %s", config.PDFPath, config.OutputDir)
+	log.Printf("[REEL] Starting reel pipeline for %s -> %s", config.Input(), config.OutputDir)
+	progress := common.NewProgressReporter(config.Progress, 4)
 
-	// 1. Process PDF (Extract Text)
-	log.Println("[REEL] Step 1: Processing PDF...")
-	pdfProc, err := common.NewPDFProcessor(config.PDFPath, config.OutputDir)
+	manifest, err := common.LoadManifest(config.OutputDir)
 	if err != nil {
-		return fmt.Errorf("failed to open PDF: %w", err)
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	manifest.PDFPath = config.PDFPath
+	manifest.SourceType = config.SourceType
+	manifest.SourcePath = config.SourcePath
+	// What was written and spoken in another language is stale
+	if manifest.Language != "" && common.NormalizeLanguage(manifest.Language) != common.NormalizeLanguage(config.Language) {
+		manifest.Invalidate("dialogue", "audio:", "final")
+	}
+	manifest.Language = config.Language
+	manifest.Mode = "reel"
+	manifest.LLM, manifest.LLMModel = config.LLMProvider, config.LLMModel
+	manifest.BurnSubtitles = config.BurnSubtitles
+	manifest.SubtitleStyle = nil
+	if config.SubtitleStyle != (common.SubtitleStyle{}) {
+		style := config.SubtitleStyle
+		manifest.SubtitleStyle = &style
 	}
-	defer pdfProc.Close()
 
-	text, err := pdfProc.ExtractText()
+	// The avatar pair picks the voices and who stands where
+	assetsDir := "./assets"
+	pairs, err := LoadAvatarPairs(assetsDir)
 	if err != nil {
-		return fmt.Errorf("text extraction failed: %w", err)
+		return err
+	}
+	avatarPair := GetAvatarPairByID(pairs, config.AvatarPairID)
+	if avatarPair == nil {
+		return fmt.Errorf("unknown avatar pair %q", config.AvatarPairID)
+	}
+	settings := config.Settings.OrDefault()
+	if avatarPair.FemaleVoice == "" {
+		avatarPair.FemaleVoice = settings.Reel.FemaleVoice
+	}
+	if avatarPair.MaleVoice == "" {
+		avatarPair.MaleVoice = settings.Reel.MaleVoice
+	}
+	if manifest.AvatarPair != "" && manifest.AvatarPair != avatarPair.ID {
+		manifest.Invalidate("audio:", "final")
+	}
+	manifest.AvatarPair = avatarPair.ID
+	if err := manifest.Save(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if manifest.Done("final") {
+		log.Printf("[REEL] Reel already complete, nothing to resume")
+		progress.Stage(4, "video")
+		return nil
+	}
+
+	// 1. Process PDF (Extract Text)
+	log.Println("[REEL] Step 1: Processing PDF...")
+	progress.Stage(1, "pdf")
+	text, doc, err := common.LoadPaper(ctx, config)
+	if err != nil {
+		return err
 	}
 	log.Printf("[REEL] Extracted %d chars of text", len(text))
+	if doc != nil && config.Metadata == nil {
+		config.Metadata = doc.Metadata()
+	}
 
 	if text == "" {
 		return fmt.Errorf("no text extracted")
 	}
 
-	// 2. Generate Dialogue Script using common GeminiClient
-	log.Println("[REEL] Step 2: Generating Dialogue Script with Gemini...")
-	gemini, err := common.NewGeminiClient(config.GeminiKey)
+	// 2. Generate Dialogue Script using the configured LLM
+	log.Println("[REEL] Step 2: Generating Dialogue Script...")
+	progress.Stage(2, "dialogue")
+	llm, err := common.NewLLM(config)
 	if err != nil {
-		return fmt.Errorf("gemini init failed: %w", err)
+		return fmt.Errorf("llm init failed: %w", err)
 	}
-	defer gemini.Close()
+	defer llm.Close()
 
 	// Extract paper metadata (title and authors)
-	log.Println("[REEL] Extracting paper metadata...")
-	paperMetadata, err := gemini.ExtractMetadata(text)
-	if err != nil {
-		log.Printf("[REEL] Warning: metadata extraction failed: %v, using defaults", err)
+	metadataPath := filepath.Join(config.OutputDir, "metadata.json")
+	paperMetadata := &common.PaperMetadata{}
+	if manifest.Done("metadata") && common.ReadJSON(metadataPath, paperMetadata) == nil {
+		log.Println("[REEL] Resuming: reusing extracted metadata")
+	} else {
+		if config.Metadata != nil {
+			log.Println("[REEL] Using known paper metadata")
+			paperMetadata = config.Metadata
+		} else {
+			log.Println("[REEL] Extracting paper metadata...")
+			paperMetadata, err = llm.ExtractMetadata(ctx, text)
+		}
+		if err != nil {
+			log.Printf("[REEL] Warning: metadata extraction failed: %v, using defaults", err)
+		} else if err := common.WriteJSON(metadataPath, paperMetadata); err == nil {
+			manifest.Checkpoint("metadata", metadataPath)
+		}
+		// The background shows the title
+		manifest.Invalidate("background", "final")
 	}
 	log.Printf("[REEL] Paper Title: %s", paperMetadata.Title)
 	log.Printf("[REEL] Paper Authors: %s", paperMetadata.Authors)
 
-	dialogue, err := GenerateReelDialogue(gemini, text)
-	if err != nil {
-		return fmt.Errorf("dialogue generation failed: %w", err)
+	dialoguePath := filepath.Join(config.OutputDir, dialogueFile)
+	var dialogueTurns []DialogueTurn
+	if manifest.Done("dialogue") && common.ReadJSON(dialoguePath, &dialogueTurns) == nil {
+		log.Println("[REEL] Resuming: reusing generated dialogue")
+	} else {
+		condensed, err := llm.CondensePaper(ctx, text)
+		if err != nil {
+			return fmt.Errorf("paper condensation failed: %w", err)
+		}
+		dialogueTurns, err = GenerateReelDialogue(ctx, llm, condensed, config.Language, config.TargetDuration)
+		if err != nil {
+			return fmt.Errorf("dialogue generation failed: %w", err)
+		}
+		if err := common.WriteJSON(dialoguePath, dialogueTurns); err == nil {
+			manifest.Checkpoint("dialogue", dialoguePath)
+		}
+		manifest.Invalidate("audio:", "final")
 	}
-	os.WriteFile(filepath.Join(config.OutputDir, "dialogue.txt"), []byte(dialogue), 0644)
+	log.Printf("[REEL] Parsed %d dialogue turns", len(dialogueTurns))
 
-	// Parse dialogue into turns
-	dialogueTurns := ParseDialogueToScript(dialogue)
-	if len(dialogueTurns) == 0 {
-		return fmt.Errorf("failed to parse dialogue into script")
+	if config.ReviewScript {
+		if !manifest.Done("dialogue") {
+			return fmt.Errorf("failed to save dialogue for review")
+		}
+		log.Println("[REEL] Dialogue ready for review")
+		return ErrScriptReady
 	}
-	log.Printf("[REEL] Parsed %d dialogue turns", len(dialogueTurns))
 
 	// 3. Generate Audio (Parallel) using existing TTS pattern
 	log.Println("[REEL] Step 3: Generating Audio (Parallel)...")
+	progress.Stage(3, "audio")
 	audioDir := filepath.Join(config.OutputDir, "audio")
-	ttsClient := NewReelTTSClient(config.SarvamKey)
+	tts, err := common.NewTTSProvider(config)
+	if err != nil {
+		return fmt.Errorf("tts init failed: %w", err)
+	}
+	ttsClient := NewReelTTSClient(tts)
+	ttsClient.Progress = progress
+	ttsClient.Manifest = manifest
+	ttsClient.Avatars = avatarPair
 
-	audioFiles, err := ttsClient.GenerateDialogueAudio(dialogueTurns, audioDir, "english")
+	audioFiles, err := ttsClient.GenerateDialogueAudio(ctx, dialogueTurns, audioDir, config.Language)
 	if err != nil {
 		return fmt.Errorf("audio generation failed: %w", err)
 	}
@@ -81,18 +172,35 @@ func ProcessReelPipeline(config common.PipelineConfig) error {
 
 	// 4. Generate Video (Title background + Avatar overlays)
 	log.Println("[REEL] Step 4: Creating Video...")
-	assetsDir := "./assets"
+	progress.Stage(4, "video")
 	videoDir := filepath.Join(config.OutputDir, "video")
 	videoGen := NewReelVideoGenerator(videoDir, assetsDir)
+	videoGen.Progress = progress
+	videoGen.TitleCard = config.TitleCard
+	videoGen.Timeout = settings.Timeouts.SegmentEncode
 
 	// Use extracted metadata for video title
 	metadata := &PaperMetadata{
 		Title:   paperMetadata.Title,
 		Authors: paperMetadata.Authors,
+		Date:    paperMetadata.Date,
+		Venue:   paperMetadata.Venue,
 	}
 
 	// Generate title background
-	bgPath, err := videoGen.GenerateTitleBackground(metadata, 120)
+	bgPath := filepath.Join(videoDir, "title_bg.mp4")
+	if manifest.Done("background") {
+		log.Println("[REEL] Resuming: reusing title background")
+	} else {
+		bgPath, err = videoGen.GenerateTitleBackground(ctx, metadata, 120)
+		if err == nil {
+			manifest.Checkpoint("background", bgPath)
+			manifest.Invalidate("final")
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		// Try default background
 		defaultBg := filepath.Join(assetsDir, "bg3.mp4")
@@ -104,67 +212,128 @@ func ProcessReelPipeline(config common.PipelineConfig) error {
 		}
 	}
 
-	// Use default avatar pair
-	avatarPair := &AvailableAvatarPairs[0]
-
-	// Create avatar overlay videos
-	person1Video, person2Video, err := videoGen.CreateAvatarVideos(bgPath, avatarPair)
-	if err != nil {
-		return fmt.Errorf("avatar video creation failed: %w", err)
-	}
-
 	// Composite final video
-	finalPath, err := videoGen.CompositeReelVideo(person1Video, person2Video, audioFiles, dialogueTurns)
+	finalPath, narration, err := videoGen.CompositeReelVideo(ctx, bgPath, avatarPair, audioFiles, dialogueTurns)
 	if err != nil {
 		return fmt.Errorf("video composition failed: %w", err)
 	}
+	style := config.SubtitleStyle.ForLanguage(config.Language)
+	subtitles, err := common.CreateSubtitles(ctx, finalPath, narration, config.BurnSubtitles, style, settings.Timeouts.SegmentEncode)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if common.IsTimeout(err) {
+			return fmt.Errorf("subtitle burn-in failed: %w", err)
+		}
+		log.Printf("[REEL] Warning: subtitles failed: %v", err)
+	}
+	manifest.Checkpoint("final", append([]string{finalPath}, subtitles...)...)
 
 	log.Printf("[REEL] Reel Pipeline Complete! Video: %s", finalPath)
 	return nil
 }
 
-// GenerateReelDialogue generates short-form dialogue using common GeminiClient
-func GenerateReelDialogue(gemini *common.GeminiClient, text string) (string, error) {
-	// Limit text to prevent token overflow
-	if len(text) > 6000 {
-		text = text[:6000]
+// reelDialogue is the JSON shape of GenerateReelDialogue responses
+type reelDialogue struct {
+	Turns []DialogueTurn `json:"turns"`
+}
+
+var reelDialogueSchema = &common.Schema{
+	Type: "object",
+	Properties: map[string]*common.Schema{
+		"turns": {
+			Type: "array",
+			Items: &common.Schema{
+				Type: "object",
+				Properties: map[string]*common.Schema{
+					"character": {Type: "string", Enum: []string{"Person1", "Person2"}},
+					"dialogue":  {Type: "string", Description: "15-25 spoken words"},
+				},
+				Required: []string{"character", "dialogue"},
+			},
+		},
+	},
+	Required: []string{"turns"},
+}
+
+func (d *reelDialogue) Validate() error {
+	if len(d.Turns) < 2 {
+		return fmt.Errorf("turns must have at least 2 items, got %d", len(d.Turns))
 	}
+	for i, turn := range d.Turns {
+		if turn.Character != "Person1" && turn.Character != "Person2" {
+			return fmt.Errorf("turns[%d].character must be Person1 or Person2, got %q", i, turn.Character)
+		}
+		if strings.TrimSpace(turn.Dialogue) == "" {
+			return fmt.Errorf("turns[%d].dialogue must not be empty", i)
+		}
+	}
+	return nil
+}
+
+// dialogueLineDuration is roughly how long one 15-25 word line takes to speak
+const dialogueLineDuration = 8 * time.Second
+
+// dialogueLengthRequirement asks for enough exchanges to fill length, or the
+// usual 30-60 second reel if length is zero
+func dialogueLengthRequirement(length time.Duration) string {
+	if length <= 0 {
+		return "exactly 6-8 exchanges between speakers (perfect for 30-60 second reels)"
+	}
+	exchanges := max(int(length/dialogueLineDuration), 2)
+	return fmt.Sprintf("about %d exchanges between speakers (for a %d second reel)", exchanges, int(length.Seconds()))
+}
 
+// GenerateReelDialogue generates short-form dialogue in language using the
+// configured LLM, aiming for length if it is set. Long papers should be
+// condensed with CondensePaper first.
+func GenerateReelDialogue(ctx context.Context, llm common.LLM, text, language string, length time.Duration) ([]DialogueTurn, error) {
 	prompt := fmt.Sprintf(`You are a skilled content creator specializing in short-form educational content for social media reels.
 
 Your task is to generate a quick, engaging, and punchy dialogue between two speakers — 
 Person1 and Person2 — as they discuss the key highlights of a research paper in a reel format.
 
 Dialogue Requirements:
-- Generate a SHORT dialogue with exactly 6-8 exchanges between speakers (perfect for 30-60 second reels)
+- Generate a SHORT dialogue with %s
 - Each dialogue line should be 15-25 words maximum (for quick delivery)
-- Use alternating lines with clear speaker tags (Person1:, Person2:)
+- Alternate speakers, starting with Person1
 - Make it conversational, energetic, and hook-focused
 - Start with an attention-grabbing hook
 - Focus on the most interesting/surprising finding from the paper
 - End with a strong takeaway or call-to-action
 - Use simple, accessible language - no jargon
 - Make each line punchy and quotable
+%s
+Return a JSON object with a "turns" array; each turn has a "character" (Person1 or Person2)
+and the spoken "dialogue" without a speaker tag.
 
 Output Example:
-Person1: Did you know scientists just figured out how to make batteries charge in 10 seconds?
-Person2: Wait, what? That's impossible!
-Person1: Not anymore! They used a new material that changes everything.
-Person2: So my phone could charge fully in seconds?
-Person1: Exactly! And it could last 10 times longer too.
-Person2: This is going to revolutionize everything we use!
+{"turns": [
+  {"character": "Person1", "dialogue": "Did you know scientists just figured out how to make batteries charge in 10 seconds?"},
+  {"character": "Person2", "dialogue": "Wait, what? That's impossible!"},
+  {"character": "Person1", "dialogue": "Not anymore! They used a new material that changes everything."},
+  {"character": "Person2", "dialogue": "So my phone could charge fully in seconds?"},
+  {"character": "Person1", "dialogue": "Exactly! And it could last 10 times longer too."},
+  {"character": "Person2", "dialogue": "This is going to revolutionize everything we use!"}
+]}
 
 Here is the research paper content:
 
 %s
 
 Generate a short, engaging reel dialogue between Person1 and Person2 about the most interesting aspect of this paper.
-`, text)
+`, dialogueLengthRequirement(length), common.LanguageInstruction(language, "the dialogue"), text)
 
-	return gemini.GenerateText(prompt)
+	var result reelDialogue
+	if err := common.GenerateStructured(ctx, llm, prompt, reelDialogueSchema, &result); err != nil {
+		return nil, err
+	}
+	return result.Turns, nil
 }
 
-// ParseDialogueToScript converts raw dialogue text to structured DialogueTurns
+// ParseDialogueToScript converts "Person1: ..." dialogue text to structured
+// DialogueTurns, for scripts written or edited by hand
 func ParseDialogueToScript(dialogue string) []DialogueTurn {
 	var script []DialogueTurn
 
@@ -213,12 +382,12 @@ type DialogueAudioResult struct {
 }
 
 // GenerateDialogueAudio generates audio for all dialogue turns concurrently
-func (c *ReelTTSClient) GenerateDialogueAudio(dialogue []DialogueTurn, outputDir, language string) (map[int]string, error) {
+func (c *ReelTTSClient) GenerateDialogueAudio(ctx context.Context, dialogue []DialogueTurn, outputDir, language string) (map[int]string, error) {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create output dir: %w", err)
 	}
 
-	languageCode := GetLanguageCode(language)
+	languageCode := common.GetLanguageCode(language)
 
 	results := make(chan DialogueAudioResult, len(dialogue))
 	var wg sync.WaitGroup
@@ -232,20 +401,25 @@ func (c *ReelTTSClient) GenerateDialogueAudio(dialogue []DialogueTurn, outputDir
 		go func(index int, t DialogueTurn) {
 			defer wg.Done()
 
-			// Determine voice based on character
-			voice := "vidya" // Person1 = female
-			if t.Character == "Person2" {
-				voice = "karun" // Person2 = male
-			}
+			voice := c.Avatars.Voice(t.Character)
 
 			filename := fmt.Sprintf("%02d_%s.wav", index, t.Character)
 			outputPath := filepath.Join(outputDir, filename)
 
+			stage := fmt.Sprintf("audio:%02d", index)
+			if c.Manifest.Done(stage) {
+				results <- DialogueAudioResult{Index: index, Character: t.Character, AudioPath: outputPath}
+				return
+			}
+
 			log.Printf("[TTS] Generating audio for turn %d: character=%s, voice=%s", index, t.Character, voice)
 
-			err := c.synthesizeText(t.Dialogue, outputPath, languageCode, voice)
+			err := c.Provider.Synthesize(ctx, t.Dialogue, outputPath, common.TTSOptions{Voice: voice, Language: languageCode})
 			if err != nil {
 				log.Printf("[TTS] Error generating audio for turn %d: %v", index, err)
+			} else {
+				c.Manifest.Checkpoint(stage, outputPath)
+				c.Manifest.Invalidate("final")
 			}
 
 			results <- DialogueAudioResult{
@@ -264,16 +438,34 @@ func (c *ReelTTSClient) GenerateDialogueAudio(dialogue []DialogueTurn, outputDir
 
 	audioMap := make(map[int]string)
 	var errors []string
+	var timeoutErr error
+	done := 0
 
 	for res := range results {
+		done++
+		c.Progress.Item("audio", done, len(dialogue))
+		if ctx.Err() != nil {
+			continue
+		}
 		if res.Error != nil {
 			errors = append(errors, fmt.Sprintf("turn %d: %v", res.Index, res.Error))
+			if common.IsTimeout(res.Error) && timeoutErr == nil {
+				timeoutErr = res.Error
+			}
 		} else {
 			audioMap[res.Index] = res.AudioPath
 			log.Printf("[TTS] ✓ Generated audio: %s", filepath.Base(res.AudioPath))
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// A timed out turn fails the reel rather than leaving a gap in the dialogue
+	if timeoutErr != nil {
+		return nil, timeoutErr
+	}
+
 	if len(audioMap) == 0 && len(errors) > 0 {
 		return nil, fmt.Errorf("all audio generation failed: %s", strings.Join(errors, "; "))
 	}
@@ -0,0 +1,88 @@
+package reel
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"saral_go_testing/common"
+)
+
+// ErrScriptReady is returned by ProcessReelPipeline when
+// PipelineConfig.ReviewScript is set, once the dialogue is ready for review.
+// Running the pipeline again without ReviewScript renders the reel.
+var ErrScriptReady = errors.New("reel dialogue ready for review")
+
+const (
+	dialogueFile    = "dialogue.json"
+	maxDialogueLen  = 40  // turns
+	maxTurnRuneSize = 600 // characters per turn, well within one TTS request
+)
+
+// LoadDialogue returns the dialogue checkpointed in a reel's output directory
+func LoadDialogue(outputDir string) ([]DialogueTurn, error) {
+	var turns []DialogueTurn
+	if err := common.ReadJSON(filepath.Join(outputDir, dialogueFile), &turns); err != nil {
+		return nil, fmt.Errorf("no dialogue in %s: %w", outputDir, err)
+	}
+	return turns, nil
+}
+
+// SaveDialogue replaces the dialogue of a reel with an edited one. Audio of
+// turns that changed is invalidated, so the next run only regenerates those.
+func SaveDialogue(outputDir string, turns []DialogueTurn) error {
+	if err := ValidateDialogue(turns); err != nil {
+		return err
+	}
+	manifest, err := common.LoadManifest(outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	old, _ := LoadDialogue(outputDir)
+
+	path := filepath.Join(outputDir, dialogueFile)
+	if err := common.WriteJSON(path, turns); err != nil {
+		return err
+	}
+	manifest.Checkpoint("dialogue", path)
+
+	for i := 0; i < max(len(old), len(turns)); i++ {
+		if i >= len(old) || i >= len(turns) || old[i] != turns[i] {
+			manifest.Invalidate(fmt.Sprintf("audio:%02d", i))
+		}
+	}
+	manifest.Invalidate("final")
+	return nil
+}
+
+// ValidateDialogue checks an edited dialogue can be rendered
+func ValidateDialogue(turns []DialogueTurn) error {
+	if len(turns) == 0 {
+		return fmt.Errorf("dialogue has no turns")
+	}
+	if len(turns) > maxDialogueLen {
+		return fmt.Errorf("dialogue has %d turns, at most %d allowed", len(turns), maxDialogueLen)
+	}
+	for i, t := range turns {
+		if t.Character != "Person1" && t.Character != "Person2" {
+			return fmt.Errorf("turn %d: character %q must be Person1 or Person2", i, t.Character)
+		}
+		if strings.TrimSpace(t.Dialogue) == "" {
+			return fmt.Errorf("turn %d: empty dialogue", i)
+		}
+		if n := len([]rune(t.Dialogue)); n > maxTurnRuneSize {
+			return fmt.Errorf("turn %d: %d characters, at most %d allowed", i, n, maxTurnRuneSize)
+		}
+	}
+	return nil
+}
+
+// FormatDialogue renders turns as "Person1: ..." lines
+func FormatDialogue(turns []DialogueTurn) string {
+	var sb strings.Builder
+	for _, t := range turns {
+		fmt.Fprintf(&sb, "%s: %s\n", t.Character, t.Dialogue)
+	}
+	return sb.String()
+}
@@ -0,0 +1,183 @@
+package reel
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"saral_go_testing/common"
+)
+
+const (
+	clipFPS       = 24
+	avatarWidth   = 220 // box each avatar is fitted into, half the reel width
+	avatarHeight  = 320
+	speakerScale  = 1.12     // the speaking avatar is drawn larger
+	listenerDim   = 0.55     // and the listening one darker
+	avatarBaseY   = "H-h-24" // avatars stand 24px above the bottom edge
+	bounceHeight  = 14       // px the speaker rises at full loudness
+	envelopeGate  = 0.08     // quieter frames count as silence
+	envelopeRise  = 0.6      // smoothing towards louder frames
+	envelopeFall  = 0.25     // and quieter ones, so the bounce settles gently
+	envelopePeakQ = 0.95     // quantile of frame loudness treated as full volume
+)
+
+// audioEnvelope returns the loudness of a WAV file for each video frame at
+// fps, normalized to [0, 1] and smoothed
+func audioEnvelope(path string, fps int) ([]float64, error) {
+	samples, rate, err := common.ReadWAV(path)
+	if err != nil {
+		return nil, err
+	}
+	if rate < fps {
+		return nil, fmt.Errorf("sample rate %d too low", rate)
+	}
+
+	// Frame i covers samples [i*rate/fps, (i+1)*rate/fps)
+	rms := make([]float64, (len(samples)*fps+rate-1)/rate)
+	for i := range rms {
+		chunk := samples[i*rate/fps : min((i+1)*rate/fps, len(samples))]
+		var sum float64
+		for _, s := range chunk {
+			sum += s * s
+		}
+		rms[i] = math.Sqrt(sum / float64(len(chunk)))
+	}
+	if len(rms) == 0 {
+		return rms, nil
+	}
+
+	// A high quantile rather than the maximum, so one click doesn't flatten the rest
+	sorted := append([]float64(nil), rms...)
+	sort.Float64s(sorted)
+	peak := sorted[int(float64(len(sorted)-1)*envelopePeakQ)]
+	if peak == 0 {
+		return make([]float64, len(rms)), nil
+	}
+
+	env := make([]float64, len(rms))
+	var level float64
+	for i, r := range rms {
+		target := min(r/peak, 1)
+		if target < envelopeGate {
+			target = 0
+		}
+		rate := envelopeFall
+		if target > level {
+			rate = envelopeRise
+		}
+		level += (target - level) * rate
+		env[i] = level
+	}
+	return env, nil
+}
+
+// writeBounceCommands writes an ffmpeg sendcmd script that moves the
+// overlay named target up by the envelope, one frame at a time
+func writeBounceCommands(path, target string, env []float64, fps int) error {
+	var sb strings.Builder
+	last := -1
+	for i, level := range env {
+		offset := int(math.Round(level * bounceHeight))
+		if offset == last {
+			continue
+		}
+		fmt.Fprintf(&sb, "%.3f %s y %s-%d;\n", float64(i)/float64(fps), target, avatarBaseY, offset)
+		last = offset
+	}
+	if sb.Len() == 0 {
+		fmt.Fprintf(&sb, "0 %s y %s;\n", target, avatarBaseY)
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// speakerFilter builds the filter graph for one turn: both avatars over the
+// background, the speaker enlarged and bouncing (through commands read from
+// cmdFile, if any), the listener dimmed. Inputs are the background, the
+// speaker image and the listener image.
+func speakerFilter(speakerLeft bool, cmdFile string) string {
+	speakerX, listenerX := "W/4-w/2", "3*W/4-w/2"
+	if !speakerLeft {
+		speakerX, listenerX = listenerX, speakerX
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[1:v]scale=w=%.0f:h=%.0f:force_original_aspect_ratio=decrease,format=rgba[speaker];",
+		avatarWidth*speakerScale, avatarHeight*speakerScale)
+	fmt.Fprintf(&sb, "[2:v]scale=w=%d:h=%d:force_original_aspect_ratio=decrease,format=rgba,"+
+		"colorchannelmixer=rr=%.2f:gg=%.2f:bb=%.2f[listener];",
+		avatarWidth, avatarHeight, listenerDim, listenerDim, listenerDim)
+	fmt.Fprintf(&sb, "[0:v][listener]overlay=x=%s:y=%s[bg];", listenerX, avatarBaseY)
+	if cmdFile != "" {
+		fmt.Fprintf(&sb, "[bg]sendcmd=f=%s[cmd];", cmdFile)
+	} else {
+		sb.WriteString("[bg]null[cmd];")
+	}
+	fmt.Fprintf(&sb, "[cmd][speaker]overlay@speaker=x=%s:y=%s,format=yuv420p[v]", speakerX, avatarBaseY)
+	return sb.String()
+}
+
+// createSpeakerClip renders one dialogue turn: the background with both
+// avatars, the speaker highlighted and bouncing with the loudness of the
+// turn's audio
+func (v *ReelVideoGenerator) createSpeakerClip(ctx context.Context, bgPath, speakerImg, listenerImg string, speakerLeft bool, audioPath string, duration float64, outputPath string) error {
+	inputs := make([]string, 4)
+	for i, p := range []string{bgPath, speakerImg, listenerImg, audioPath} {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		inputs[i] = abs
+	}
+
+	// ffmpeg runs in the clip's directory so the filter can name the
+	// command file without path escaping
+	var cmdFile string
+	if env, err := audioEnvelope(audioPath, clipFPS); err != nil {
+		log.Printf("[VIDEO] No amplitude envelope for %s, avatar won't bounce: %v", filepath.Base(audioPath), err)
+	} else {
+		cmdFile = strings.TrimSuffix(filepath.Base(outputPath), filepath.Ext(outputPath)) + "_bounce.txt"
+		if err := writeBounceCommands(filepath.Join(filepath.Dir(outputPath), cmdFile), "overlay@speaker", env, clipFPS); err != nil {
+			return err
+		}
+	}
+
+	absOutput, err := filepath.Abs(outputPath)
+	if err != nil {
+		return err
+	}
+	output, err := common.CommandOutput(ctx, common.StageEncode, v.Timeout, func(ctx context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "ffmpeg",
+			"-y",
+			"-t", fmt.Sprintf("%.2f", duration),
+			"-i", inputs[0],
+			"-loop", "1", "-i", inputs[1],
+			"-loop", "1", "-i", inputs[2],
+			"-i", inputs[3],
+			"-filter_complex", speakerFilter(speakerLeft, cmdFile),
+			"-map", "[v]",
+			"-map", "3:a",
+			"-r", fmt.Sprint(clipFPS),
+			"-c:v", "libx264",
+			"-c:a", "aac",
+			"-preset", "ultrafast",
+			"-threads", "8",
+			"-shortest",
+			absOutput,
+		)
+		cmd.Dir = filepath.Dir(outputPath)
+		return cmd
+	})
+	if common.IsTimeout(err) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("ffmpeg error: %s, output: %s", err, string(output))
+	}
+	return nil
+}
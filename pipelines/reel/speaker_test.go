@@ -0,0 +1,88 @@
+package reel
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestWAV writes 16-bit mono PCM: silence, then a loud tone
+func writeTestWAV(t *testing.T, path string, rate int, silence, tone float64) {
+	var pcm []byte
+	for i := 0; i < int(float64(rate)*(silence+tone)); i++ {
+		var v int16
+		if float64(i) >= float64(rate)*silence {
+			v = int16(20000 * math.Sin(2*math.Pi*220*float64(i)/float64(rate)))
+		}
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(v))
+	}
+
+	header := []byte("RIFF\x00\x00\x00\x00WAVEfmt ")
+	header = binary.LittleEndian.AppendUint32(header, 16)
+	header = binary.LittleEndian.AppendUint16(header, 1) // PCM
+	header = binary.LittleEndian.AppendUint16(header, 1) // mono
+	header = binary.LittleEndian.AppendUint32(header, uint32(rate))
+	header = binary.LittleEndian.AppendUint32(header, uint32(rate*2))
+	header = binary.LittleEndian.AppendUint16(header, 2)
+	header = binary.LittleEndian.AppendUint16(header, 16)
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(pcm)))
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(header)-8+len(pcm)))
+
+	if err := os.WriteFile(path, append(header, pcm...), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAudioEnvelope(t *testing.T) {
+	dir := t.TempDir()
+	wav := filepath.Join(dir, "turn.wav")
+	writeTestWAV(t, wav, 22050, 0.5, 1)
+
+	env, err := audioEnvelope(wav, clipFPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env) != 36 {
+		t.Fatalf("expected 36 frames for 1.5s, got %d", len(env))
+	}
+	if env[5] != 0 {
+		t.Errorf("silence should not bounce, got %.2f", env[5])
+	}
+	if env[30] < 0.9 {
+		t.Errorf("tone should bounce fully, got %.2f", env[30])
+	}
+
+	cmds := filepath.Join(dir, "bounce.txt")
+	if err := writeBounceCommands(cmds, "overlay@speaker", env, clipFPS); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(cmds)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if lines[0] != "0.000 overlay@speaker y H-h-24-0;" {
+		t.Errorf("unexpected first command %q", lines[0])
+	}
+	if last := lines[len(lines)-1]; !strings.HasSuffix(last, "y H-h-24-14;") {
+		t.Errorf("unexpected last command %q", last)
+	}
+}
+
+func TestSpeakerFilter(t *testing.T) {
+	f := speakerFilter(false, "clip_01_bounce.txt")
+	for _, want := range []string{
+		"[0:v][listener]overlay=x=W/4-w/2",
+		"sendcmd=f=clip_01_bounce.txt",
+		"overlay@speaker=x=3*W/4-w/2",
+		"colorchannelmixer=rr=0.55",
+	} {
+		if !strings.Contains(f, want) {
+			t.Errorf("filter %q lacks %q", f, want)
+		}
+	}
+	if f := speakerFilter(true, ""); strings.Contains(f, "sendcmd") {
+		t.Errorf("filter without envelope sends commands: %q", f)
+	}
+}
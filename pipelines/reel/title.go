@@ -0,0 +1,303 @@
+package reel
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"strconv"
+	"strings"
+
+	"saral_go_testing/common"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	maxTitleSize = 44 // px
+	minTitleSize = 18
+	maxTextLines = 3 // authors beyond this are cut with an ellipsis
+)
+
+// titleLayout is the text of the title card, wrapped for one title size
+type titleLayout struct {
+	titleSize, textSize float64
+	title, authors      []string
+	details             string // date and venue on one line
+	height              int
+}
+
+// createTitleImage renders the title, authors, date and venue (and the
+// card's logo) onto the reel background. The text sits in the upper part of
+// the image, above the avatars, and the title is shrunk until it fits.
+func createTitleImage(metadata *PaperMetadata, card common.TitleCard, outputPath string, width, height int) error {
+	bg := hexColor(card.Background, color.RGBA{255, 255, 255, 255})
+	titleColor := hexColor(card.TitleColor, color.RGBA{20, 24, 31, 255})
+	textColor := hexColor(card.TextColor, color.RGBA{85, 90, 100, 255})
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+
+	titleFont, err := loadFont(card.TitleFont, gobold.TTF)
+	if err != nil {
+		return err
+	}
+	textFont, err := loadFont(card.TextFont, goregular.TTF)
+	if err != nil {
+		return err
+	}
+
+	margin := width / 12
+	maxWidth := width - 2*margin
+	top, bottom := margin, height*11/20 // the avatars cover the rest
+
+	if card.Logo != "" {
+		logoBottom, err := drawLogo(img, card.Logo, image.Rect(margin, top, width-margin, top+(bottom-top)/4))
+		if err != nil {
+			return err
+		}
+		top = logoBottom + margin/2
+	}
+
+	venue := metadata.Venue
+	if card.Venue != "" {
+		venue = card.Venue
+	}
+	var details []string
+	for _, part := range []string{venue, metadata.Date} {
+		if part != "" {
+			details = append(details, part)
+		}
+	}
+
+	layout, err := fitTitle(titleFont, textFont, metadata, strings.Join(details, " · "), maxWidth, bottom-top)
+	if err != nil {
+		return err
+	}
+
+	// Center the text block vertically in the space left
+	y := top + (bottom-top-layout.height)/2
+	if y < top {
+		y = top
+	}
+	for _, block := range []struct {
+		f     *opentype.Font
+		size  float64
+		lines []string
+		col   color.Color
+	}{
+		{titleFont, layout.titleSize, layout.title, titleColor},
+		{textFont, layout.textSize, layout.authors, textColor},
+		{textFont, layout.textSize * 0.85, nonEmpty(layout.details), textColor},
+	} {
+		if len(block.lines) == 0 {
+			continue
+		}
+		face, err := newFace(block.f, block.size)
+		if err != nil {
+			return err
+		}
+		y = drawCentered(img, face, block.lines, block.col, width, y) + int(block.size*0.6)
+		face.Close()
+	}
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return png.Encode(f, img)
+}
+
+// fitTitle picks the largest title size at which the wrapped title,
+// authors and details fit in maxWidth x maxHeight
+func fitTitle(titleFont, textFont *opentype.Font, metadata *PaperMetadata, details string, maxWidth, maxHeight int) (*titleLayout, error) {
+	var layout *titleLayout
+	for size := float64(maxTitleSize); size >= minTitleSize; size -= 2 {
+		l := &titleLayout{titleSize: size, textSize: max(size*0.5, 14), details: details}
+
+		titleFace, err := newFace(titleFont, l.titleSize)
+		if err != nil {
+			return nil, err
+		}
+		l.title = wrapLines(titleFace, metadata.Title, maxWidth)
+		l.height = lineHeight(titleFace)*len(l.title) + int(l.titleSize*0.6)
+		titleFace.Close()
+
+		textFace, err := newFace(textFont, l.textSize)
+		if err != nil {
+			return nil, err
+		}
+		l.authors = wrapLines(textFace, metadata.Authors, maxWidth)
+		if len(l.authors) > maxTextLines {
+			l.authors = l.authors[:maxTextLines]
+			l.authors[maxTextLines-1] = ellipsize(textFace, l.authors[maxTextLines-1], maxWidth)
+		}
+		l.height += lineHeight(textFace) * len(l.authors)
+		if details != "" {
+			l.height += int(l.textSize*0.6) + lineHeight(textFace)
+		}
+		textFace.Close()
+
+		layout = l
+		if l.height <= maxHeight {
+			return layout, nil
+		}
+	}
+
+	// Still too tall at the smallest size: cut the title short
+	titleFace, err := newFace(titleFont, layout.titleSize)
+	if err != nil {
+		return nil, err
+	}
+	defer titleFace.Close()
+	for len(layout.title) > 1 && layout.height > maxHeight {
+		layout.title = layout.title[:len(layout.title)-1]
+		layout.height -= lineHeight(titleFace)
+	}
+	last := len(layout.title) - 1
+	layout.title[last] = ellipsize(titleFace, layout.title[last], maxWidth)
+	return layout, nil
+}
+
+// wrapLines breaks text into lines no wider than maxWidth, splitting words
+// that are too long on their own
+func wrapLines(face font.Face, text string, maxWidth int) []string {
+	limit := fixed.I(maxWidth)
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		for _, piece := range splitWord(face, word, limit) {
+			candidate := piece
+			if line != "" {
+				candidate = line + " " + piece
+			}
+			if line != "" && font.MeasureString(face, candidate) > limit {
+				lines = append(lines, line)
+				candidate = piece
+			}
+			line = candidate
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitWord breaks a word wider than limit into pieces that fit
+func splitWord(face font.Face, word string, limit fixed.Int26_6) []string {
+	var pieces []string
+	runes := []rune(word)
+	for len(runes) > 0 && font.MeasureString(face, string(runes)) > limit {
+		n := 1
+		for n < len(runes) && font.MeasureString(face, string(runes[:n+1])) <= limit {
+			n++
+		}
+		pieces = append(pieces, string(runes[:n]))
+		runes = runes[n:]
+	}
+	if len(runes) > 0 {
+		pieces = append(pieces, string(runes))
+	}
+	return pieces
+}
+
+// ellipsize shortens line until it fits maxWidth with a trailing ellipsis
+func ellipsize(face font.Face, line string, maxWidth int) string {
+	runes := []rune(line)
+	for len(runes) > 0 && font.MeasureString(face, string(runes)+"…") > fixed.I(maxWidth) {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimRight(string(runes), " ,") + "…"
+}
+
+// drawCentered draws lines centered horizontally from y, returning the y
+// below the last line
+func drawCentered(img *image.RGBA, face font.Face, lines []string, col color.Color, width, y int) int {
+	metrics := face.Metrics()
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(col), Face: face}
+	for _, line := range lines {
+		x := (fixed.I(width) - d.MeasureString(line)) / 2
+		d.Dot = fixed.Point26_6{X: x, Y: fixed.I(y) + metrics.Ascent}
+		d.DrawString(line)
+		y += lineHeight(face)
+	}
+	return y
+}
+
+// drawLogo scales the logo into box, keeping its aspect ratio, centered at
+// the top, and returns the y below it
+func drawLogo(img *image.RGBA, path string, box image.Rectangle) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open logo: %w", err)
+	}
+	defer f.Close()
+	logo, _, err := image.Decode(f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode logo %s: %w", path, err)
+	}
+
+	src := logo.Bounds()
+	w, h := box.Dx(), src.Dy()*box.Dx()/src.Dx()
+	if h > box.Dy() {
+		w, h = src.Dx()*box.Dy()/src.Dy(), box.Dy()
+	}
+	x := box.Min.X + (box.Dx()-w)/2
+	dst := image.Rect(x, box.Min.Y, x+w, box.Min.Y+h)
+	draw.CatmullRom.Scale(img, dst, logo, src, draw.Over, nil)
+	return dst.Max.Y, nil
+}
+
+// loadFont parses the font file at path, or fallback if path is empty
+func loadFont(path string, fallback []byte) (*opentype.Font, error) {
+	data := fallback
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read font: %w", err)
+		}
+	}
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// newFace returns a face of f that is size pixels high
+func newFace(f *opentype.Font, size float64) (font.Face, error) {
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+func lineHeight(face font.Face) int {
+	return face.Metrics().Height.Ceil()
+}
+
+func nonEmpty(line string) []string {
+	if line == "" {
+		return nil
+	}
+	return []string{line}
+}
+
+// hexColor parses #RRGGBB, returning def for an empty or invalid value
+func hexColor(hex string, def color.RGBA) color.RGBA {
+	if len(hex) != 7 || hex[0] != '#' {
+		return def
+	}
+	v, err := strconv.ParseUint(hex[1:], 16, 32)
+	if err != nil {
+		return def
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}
+}
@@ -0,0 +1,84 @@
+package reel
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"saral_go_testing/common"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestWrapLines(t *testing.T) {
+	f, err := loadFont("", gobold.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, err := newFace(f, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer face.Close()
+
+	text := "Pneumonoultramicroscopicsilicovolcanoconiosis Detection with Very Deep Convolutional Networks"
+	lines := wrapLines(face, text, 300)
+	if len(lines) < 3 {
+		t.Fatalf("expected several lines, got %q", lines)
+	}
+	for _, line := range lines {
+		if font.MeasureString(face, line) > fixed.I(300) {
+			t.Errorf("line %q wider than 300px", line)
+		}
+	}
+	if got := strings.ReplaceAll(strings.Join(lines, ""), " ", ""); got != strings.ReplaceAll(text, " ", "") {
+		t.Errorf("wrapping lost text: %q", lines)
+	}
+}
+
+func TestCreateTitleImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "title.png")
+	metadata := &PaperMetadata{
+		Title:   strings.Repeat("A Remarkably Long Title About Attention ", 20),
+		Authors: "Ada Lovelace, Alan Turing, Grace Hopper",
+		Date:    "2023-01-03",
+	}
+	card := common.TitleCard{Background: "#000000", TitleColor: "#FFFFFF", Venue: "NeurIPS 2023"}
+	if err := createTitleImage(metadata, card, path, 480, 850); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 480, 850) {
+		t.Fatalf("unexpected size %v", img.Bounds())
+	}
+
+	// Text is drawn, and kept clear of the avatars in the lower part
+	var text, low int
+	for y := 0; y < 850; y++ {
+		for x := 0; x < 480; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r != 0 {
+				text++
+				if y > 850*11/20 {
+					low++
+				}
+			}
+		}
+	}
+	if text == 0 || low != 0 {
+		t.Errorf("expected text only above the avatars, got %d pixels (%d low)", text, low)
+	}
+}
@@ -21,13 +21,18 @@ type ReelScript struct {
 	EditedScript     []DialogueTurn `json:"edited_script,omitempty"`
 }
 
-// AvatarPair represents a pair of avatars for the reel
+// AvatarPair represents a pair of avatars for the reel, as listed in
+// AvatarsFile. Images are PNGs in the assets directory.
 type AvatarPair struct {
-	ID           string `json:"id"`
-	Name         string `json:"name"`
-	MaleAvatar   string `json:"male_avatar"`   // Person2
-	FemaleAvatar string `json:"female_avatar"` // Person1
-	Description  string `json:"description"`
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	MaleAvatar     string `json:"male_avatar"`               // Person2
+	FemaleAvatar   string `json:"female_avatar"`             // Person1
+	MaleVoice      string `json:"male_voice,omitempty"`      // TTS voice of Person2, reel.male_voice setting if empty
+	FemaleVoice    string `json:"female_voice,omitempty"`    // TTS voice of Person1, reel.female_voice setting if empty
+	MalePosition   string `json:"male_position,omitempty"`   // "left" or "right" (default)
+	FemalePosition string `json:"female_position,omitempty"` // "left" (default) or "right"
+	Description    string `json:"description"`
 }
 
 // AvatarSelection stores the selected avatar pair
@@ -60,6 +65,7 @@ type PaperMetadata struct {
 	Title   string `json:"title"`
 	Authors string `json:"authors"`
 	Date    string `json:"date,omitempty"`
+	Venue   string `json:"venue,omitempty"`
 }
 
 // ReelConfig holds configuration for the reel pipeline
@@ -73,48 +79,6 @@ type ReelConfig struct {
 	AssetsDir    string
 }
 
-// Available avatar pairs
-var AvailableAvatarPairs = []AvatarPair{
-	{
-		ID:           "male1_female1",
-		Name:         "Male 1 & Female 1",
-		MaleAvatar:   "prof1.png",
-		FemaleAvatar: "student1.png",
-		Description:  "Two person avatar pair",
-	},
-	{
-		ID:           "male1_female2",
-		Name:         "Male 1 & Female 2",
-		MaleAvatar:   "prof1.png",
-		FemaleAvatar: "student2.png",
-		Description:  "Two person avatar pair",
-	},
-	{
-		ID:           "male2_female1",
-		Name:         "Male 2 & Female 1",
-		MaleAvatar:   "prof2.png",
-		FemaleAvatar: "student1.png",
-		Description:  "Two person avatar pair",
-	},
-	{
-		ID:           "male2_female2",
-		Name:         "Male 2 & Female 2",
-		MaleAvatar:   "prof2.png",
-		FemaleAvatar: "student2.png",
-		Description:  "Two person avatar pair",
-	},
-}
-
-// GetAvatarPairByID finds an avatar pair by its ID
-func GetAvatarPairByID(id string) *AvatarPair {
-	for _, pair := range AvailableAvatarPairs {
-		if pair.ID == id {
-			return &pair
-		}
-	}
-	return nil
-}
-
 // JobStatusManager handles reading/writing job status files
 type JobStatusManager struct {
 	StatusDir string
@@ -198,26 +162,3 @@ func (m *JobStatusManager) loadStatusUnsafe(paperID string) (*ReelJobStatus, err
 
 	return &status, nil
 }
-
-// LanguageCodes maps language names to Sarvam TTS codes
-var LanguageCodes = map[string]string{
-	"english":   "en-IN",
-	"hindi":     "hi-IN",
-	"tamil":     "ta-IN",
-	"bengali":   "bn-IN",
-	"telugu":    "te-IN",
-	"kannada":   "kn-IN",
-	"malayalam": "ml-IN",
-	"marathi":   "mr-IN",
-	"gujarati":  "gu-IN",
-	"punjabi":   "pa-IN",
-	"odia":      "od-IN",
-}
-
-// GetLanguageCode returns the Sarvam TTS language code
-func GetLanguageCode(language string) string {
-	if code, ok := LanguageCodes[language]; ok {
-		return code
-	}
-	return "en-IN" // Default to English
-}
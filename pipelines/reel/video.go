@@ -1,23 +1,26 @@
 package reel
 
 import (
+	"context"
 	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"image/png"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+
+	"saral_go_testing/common"
 )
 
 // ReelVideoGenerator handles video composition for reels
 type ReelVideoGenerator struct {
 	OutputDir string
 	AssetsDir string
+	Progress  *common.ProgressReporter // Optional, receives per-clip progress
+	TitleCard common.TitleCard         // Optional, fonts, colors and logo of the title background
+	Timeout   time.Duration            // Optional, bounds each ffmpeg run
 }
 
 // NewReelVideoGenerator creates a new video generator
@@ -30,30 +33,33 @@ func NewReelVideoGenerator(outputDir, assetsDir string) *ReelVideoGenerator {
 }
 
 // GenerateTitleBackground creates a white background video with title and author
-func (v *ReelVideoGenerator) GenerateTitleBackground(metadata *PaperMetadata, duration int) (string, error) {
+func (v *ReelVideoGenerator) GenerateTitleBackground(ctx context.Context, metadata *PaperMetadata, duration int) (string, error) {
 	// Create title image
 	imgPath := filepath.Join(v.OutputDir, "title_bg.png")
 	videoPath := filepath.Join(v.OutputDir, "title_bg.mp4")
 
-	if err := createTitleImage(metadata, imgPath, 480, 850); err != nil {
+	if err := createTitleImage(metadata, v.TitleCard, imgPath, 480, 850); err != nil {
 		return "", fmt.Errorf("failed to create title image: %w", err)
 	}
 
 	// Convert image to video using ffmpeg
-	cmd := exec.Command("ffmpeg",
-		"-y",
-		"-loop", "1",
-		"-i", imgPath,
-		"-c:v", "libx264",
-		"-t", strconv.Itoa(duration),
-		"-pix_fmt", "yuv420p",
-		"-vf", "scale=480:850",
-		"-preset", "medium",
-		"-r", "24",
-		videoPath,
-	)
-
-	output, err := cmd.CombinedOutput()
+	output, err := common.CommandOutput(ctx, common.StageEncode, v.Timeout, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "ffmpeg",
+			"-y",
+			"-loop", "1",
+			"-i", imgPath,
+			"-c:v", "libx264",
+			"-t", strconv.Itoa(duration),
+			"-pix_fmt", "yuv420p",
+			"-vf", "scale=480:850",
+			"-preset", "medium",
+			"-r", "24",
+			videoPath,
+		)
+	})
+	if common.IsTimeout(err) {
+		return "", err
+	}
 	if err != nil {
 		return "", fmt.Errorf("ffmpeg error: %s, output: %s", err, string(output))
 	}
@@ -64,120 +70,54 @@ func (v *ReelVideoGenerator) GenerateTitleBackground(metadata *PaperMetadata, du
 	return videoPath, nil
 }
 
-// createTitleImage creates a white background image with title and author text
-func createTitleImage(metadata *PaperMetadata, outputPath string, width, height int) error {
-	img := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	// Fill with white
-	white := color.RGBA{255, 255, 255, 255}
-	draw.Draw(img, img.Bounds(), &image.Uniform{white}, image.Point{}, draw.Src)
-
-	// For now, we create a simple white image
-	// Text rendering requires additional dependencies (golang.org/x/image/font)
-	// The title will be shown in the video overlay instead
-
-	f, err := os.Create(outputPath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	return png.Encode(f, img)
-}
-
-// OverlayAvatarOnBackground overlays an avatar on the background video
-func (v *ReelVideoGenerator) OverlayAvatarOnBackground(bgPath, avatarPath, position, outputPath string) error {
-	// Determine overlay position
-	var overlayFilter string
-	switch position {
-	case "bottom-left":
-		overlayFilter = "[0:v][1:v] overlay=0:H-h:enable='between(t,0,60)'"
-	case "bottom-right":
-		overlayFilter = "[0:v][1:v] overlay=W-w:H-h:enable='between(t,0,60)'"
-	default:
-		overlayFilter = "[0:v][1:v] overlay=0:H-h:enable='between(t,0,60)'"
-	}
-
-	cmd := exec.Command("ffmpeg",
-		"-y",
-		"-i", bgPath,
-		"-i", avatarPath,
-		"-filter_complex", overlayFilter,
-		"-pix_fmt", "yuv420p",
-		"-c:a", "copy",
-		outputPath,
-	)
+// CompositeReelVideo creates the final reel, one clip per dialogue turn with
+// both avatars over the background and the speaker highlighted. It also
+// returns the narration of each clip that made it in, for subtitles.
+func (v *ReelVideoGenerator) CompositeReelVideo(
+	ctx context.Context,
+	bgPath string,
+	avatarPair *AvatarPair,
+	audioFiles map[int]string,
+	dialogueTurns []DialogueTurn,
+) (string, []common.SubtitleSegment, error) {
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ffmpeg overlay error: %s, output: %s", err, string(output))
+	if len(audioFiles) == 0 {
+		return "", nil, fmt.Errorf("no audio files provided")
 	}
 
-	return nil
-}
-
-// CreateAvatarVideos creates two videos with each avatar overlaid on background
-func (v *ReelVideoGenerator) CreateAvatarVideos(bgPath string, avatarPair *AvatarPair) (person1Video, person2Video string, err error) {
 	femaleAvatarPath := filepath.Join(v.AssetsDir, avatarPair.FemaleAvatar)
 	maleAvatarPath := filepath.Join(v.AssetsDir, avatarPair.MaleAvatar)
-
-	person1Video = filepath.Join(v.OutputDir, "Person1_video.mp4")
-	person2Video = filepath.Join(v.OutputDir, "Person2_video.mp4")
-
-	// Check avatar files exist
 	if _, err := os.Stat(femaleAvatarPath); os.IsNotExist(err) {
-		return "", "", fmt.Errorf("female avatar not found: %s", femaleAvatarPath)
+		return "", nil, fmt.Errorf("female avatar not found: %s", femaleAvatarPath)
 	}
 	if _, err := os.Stat(maleAvatarPath); os.IsNotExist(err) {
-		return "", "", fmt.Errorf("male avatar not found: %s", maleAvatarPath)
-	}
-
-	// Create Person1 (female) video - bottom left
-	log.Println("[VIDEO] Creating Person1 (female) avatar video...")
-	if err := v.OverlayAvatarOnBackground(bgPath, femaleAvatarPath, "bottom-left", person1Video); err != nil {
-		return "", "", fmt.Errorf("failed to create Person1 video: %w", err)
-	}
-
-	// Create Person2 (male) video - bottom right
-	log.Println("[VIDEO] Creating Person2 (male) avatar video...")
-	if err := v.OverlayAvatarOnBackground(bgPath, maleAvatarPath, "bottom-right", person2Video); err != nil {
-		return "", "", fmt.Errorf("failed to create Person2 video: %w", err)
-	}
-
-	return person1Video, person2Video, nil
-}
-
-// CompositeReelVideo creates the final reel by combining avatar videos with audio
-func (v *ReelVideoGenerator) CompositeReelVideo(
-	person1Video, person2Video string,
-	audioFiles map[int]string,
-	dialogueTurns []DialogueTurn,
-) (string, error) {
-
-	if len(audioFiles) == 0 {
-		return "", fmt.Errorf("no audio files provided")
+		return "", nil, fmt.Errorf("male avatar not found: %s", maleAvatarPath)
 	}
 
 	// Create video clips for each dialogue turn
 	var clipPaths []string
+	var narration []common.SubtitleSegment
 
 	for i, turn := range dialogueTurns {
+		if err := ctx.Err(); err != nil {
+			return "", nil, err
+		}
+
 		audioPath, ok := audioFiles[i]
 		if !ok {
 			log.Printf("[VIDEO] No audio for turn %d, skipping", i)
 			continue
 		}
 
-		// Determine which avatar video to use
-		var avatarVideo string
+		// Determine who speaks
+		speaker, listener := maleAvatarPath, femaleAvatarPath
 		if turn.Character == "Person1" {
-			avatarVideo = person1Video
-		} else {
-			avatarVideo = person2Video
+			speaker, listener = listener, speaker
 		}
+		speakerLeft := avatarPair.OnLeft(turn.Character)
 
 		// Get audio duration
-		duration, err := getAudioDuration(audioPath)
+		duration, err := getAudioDuration(ctx, audioPath)
 		if err != nil {
 			log.Printf("[VIDEO] Error getting audio duration for turn %d: %v", i, err)
 			continue
@@ -185,56 +125,36 @@ func (v *ReelVideoGenerator) CompositeReelVideo(
 
 		// Create clip with audio
 		clipPath := filepath.Join(v.OutputDir, fmt.Sprintf("clip_%02d.mp4", i))
-		if err := v.createClipWithAudio(avatarVideo, audioPath, duration, clipPath); err != nil {
+		if err := v.createSpeakerClip(ctx, bgPath, speaker, listener, speakerLeft, audioPath, duration, clipPath); err != nil {
+			if common.IsTimeout(err) {
+				return "", nil, err
+			}
 			log.Printf("[VIDEO] Error creating clip for turn %d: %v", i, err)
 			continue
 		}
 
 		clipPaths = append(clipPaths, clipPath)
+		narration = append(narration, common.SubtitleSegment{Text: turn.Dialogue, Duration: duration})
+		v.Progress.Item("clips", i+1, len(dialogueTurns))
 		log.Printf("[VIDEO] ✓ Created clip %d: %s (%.2fs)", i, filepath.Base(clipPath), duration)
 	}
 
 	if len(clipPaths) == 0 {
-		return "", fmt.Errorf("no video clips created")
+		return "", nil, fmt.Errorf("no video clips created")
 	}
 
 	// Concatenate all clips
 	finalPath := filepath.Join(v.OutputDir, "reel_output.mp4")
-	if err := v.concatenateClips(clipPaths, finalPath); err != nil {
-		return "", fmt.Errorf("failed to concatenate clips: %w", err)
+	if err := v.concatenateClips(ctx, clipPaths, finalPath); err != nil {
+		return "", nil, fmt.Errorf("failed to concatenate clips: %w", err)
 	}
 
 	log.Printf("[VIDEO] ✓ Created final reel: %s", finalPath)
-	return finalPath, nil
-}
-
-// createClipWithAudio creates a video clip from avatar video with synced audio
-func (v *ReelVideoGenerator) createClipWithAudio(videoPath, audioPath string, duration float64, outputPath string) error {
-	cmd := exec.Command("ffmpeg",
-		"-y",
-		"-ss", "0",
-		"-t", fmt.Sprintf("%.2f", duration),
-		"-i", videoPath,
-		"-i", audioPath,
-		"-c:v", "libx264",
-		"-c:a", "aac",
-		"-pix_fmt", "yuv420p",
-		"-preset", "ultrafast",
-		"-threads", "8",
-		"-shortest",
-		outputPath,
-	)
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ffmpeg error: %s, output: %s", err, string(output))
-	}
-
-	return nil
+	return finalPath, narration, nil
 }
 
 // concatenateClips concatenates video clips into a final video
-func (v *ReelVideoGenerator) concatenateClips(clipPaths []string, outputPath string) error {
+func (v *ReelVideoGenerator) concatenateClips(ctx context.Context, clipPaths []string, outputPath string) error {
 	// Create concat list file
 	listContent := ""
 	for _, path := range clipPaths {
@@ -247,16 +167,19 @@ func (v *ReelVideoGenerator) concatenateClips(clipPaths []string, outputPath str
 		return err
 	}
 
-	cmd := exec.Command("ffmpeg",
-		"-y",
-		"-f", "concat",
-		"-safe", "0",
-		"-i", listPath,
-		"-c", "copy",
-		outputPath,
-	)
-
-	output, err := cmd.CombinedOutput()
+	output, err := common.CommandOutput(ctx, common.StageEncode, v.Timeout, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "ffmpeg",
+			"-y",
+			"-f", "concat",
+			"-safe", "0",
+			"-i", listPath,
+			"-c", "copy",
+			outputPath,
+		)
+	})
+	if common.IsTimeout(err) {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("ffmpeg concat error: %s, output: %s", err, string(output))
 	}
@@ -265,8 +188,8 @@ func (v *ReelVideoGenerator) concatenateClips(clipPaths []string, outputPath str
 }
 
 // getAudioDuration gets the duration of an audio file using ffprobe
-func getAudioDuration(path string) (float64, error) {
-	cmd := exec.Command("ffprobe",
+func getAudioDuration(ctx context.Context, path string) (float64, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe",
 		"-v", "error",
 		"-show_entries", "format=duration",
 		"-of", "default=noprint_wrappers=1:nokey=1",
@@ -0,0 +1,84 @@
+package video
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+
+	"saral_go_testing/common"
+	"saral_go_testing/pipelines/poster"
+)
+
+// extractSectionFigures crops the figures of a PDF paper with the poster's
+// ImageExtractor and picks one for each video section. Figures are optional:
+// without the layout model, or if extraction fails, slides have none.
+func extractSectionFigures(ctx context.Context, config common.PipelineConfig, manifest *common.Manifest, progress *common.ProgressReporter) (map[string]common.DocFigure, error) {
+	figuresPath := filepath.Join(config.OutputDir, "figures.json")
+	if figures, ok := loadSectionFigures(manifest, figuresPath, config.OutputDir); ok {
+		log.Println("Resuming: reusing extracted figures")
+		return figures, nil
+	}
+
+	layout := config.Settings.OrDefault().Layout
+	if _, err := os.Stat(layout.ModelPath); os.IsNotExist(err) {
+		log.Printf("Warning: YOLO model not found at %s, slides will have no figures", layout.ModelPath)
+		return nil, nil
+	}
+	extractor, err := poster.NewImageExtractor(config.LayoutModel, layout)
+	if err != nil {
+		log.Printf("Warning: Failed to initialize image extractor: %v", err)
+		return nil, nil
+	}
+	defer extractor.Close()
+	extractor.Progress = progress
+
+	doc, err := extractor.ExtractFigures(ctx, config.PDFPath, config.OutputDir)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		log.Printf("Warning: Figure extraction failed: %v", err)
+		return nil, nil
+	}
+
+	figures := doc.SectionFigures()
+	saveSectionFigures(manifest, figuresPath, config.OutputDir, figures)
+	manifest.Invalidate("slides", "segment:", "final")
+	return figures, nil
+}
+
+// loadSectionFigures returns the checkpointed figures, stored relative to outputDir
+func loadSectionFigures(manifest *common.Manifest, path, outputDir string) (map[string]common.DocFigure, bool) {
+	var figures map[string]common.DocFigure
+	if !manifest.Done("figures") || common.ReadJSON(path, &figures) != nil {
+		return nil, false
+	}
+
+	for name, f := range figures {
+		f.Path = filepath.Join(outputDir, f.Path)
+		figures[name] = f
+	}
+	return figures, true
+}
+
+func saveSectionFigures(manifest *common.Manifest, path, outputDir string, figures map[string]common.DocFigure) {
+	rel := make(map[string]common.DocFigure, len(figures))
+	artifacts := []string{path}
+	for name, f := range figures {
+		r, err := filepath.Rel(outputDir, f.Path)
+		if err != nil {
+			log.Printf("Warning: figure %s is outside the output dir, not checkpointing", f.Path)
+			return
+		}
+		artifacts = append(artifacts, f.Path)
+		f.Path = r
+		rel[name] = f
+	}
+
+	if err := common.WriteJSON(path, rel); err != nil {
+		log.Printf("Warning: failed to save figure list: %v", err)
+		return
+	}
+	manifest.Checkpoint("figures", artifacts...)
+}
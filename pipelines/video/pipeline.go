@@ -1,6 +1,7 @@
 package video
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -12,91 +13,213 @@ import (
 )
 
 // ProcessVideoPipeline executes the full PDF to Video workflow
-func ProcessVideoPipeline(config common.PipelineConfig) error {
+func ProcessVideoPipeline(ctx context.Context, config common.PipelineConfig) error {
 	// Ensure OutputDir exists
 	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output dir: %w", err)
 	}
-	log.Printf("Starting video pipeline for %s -> %s", config.PDFPath, config.OutputDir)
+	log.Printf("Starting video pipeline for %s -> %s", config.Input(), config.OutputDir)
+	progress := common.NewProgressReporter(config.Progress, 6)
 
-	// 1. Processing PDF (Text & Images)
-	log.Println("Step 1: Processing PDF...")
-	pdfProc, err := common.NewPDFProcessor(config.PDFPath, config.OutputDir)
+	// Completed stages are recorded in the manifest so a rerun over the same
+	// OutputDir resumes where the last one stopped
+	manifest, err := common.LoadManifest(config.OutputDir)
 	if err != nil {
-		return fmt.Errorf("failed to open PDF: %w", err)
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	manifest.PDFPath = config.PDFPath
+	manifest.SourceType = config.SourceType
+	manifest.SourcePath = config.SourcePath
+	// What was written and spoken in another language is stale
+	if manifest.Language != "" && common.NormalizeLanguage(manifest.Language) != common.NormalizeLanguage(config.Language) {
+		manifest.Invalidate("script", "bullets", "audio:", "slides", "segment:", "final")
+	}
+	manifest.Language = config.Language
+	manifest.Mode = "video"
+	manifest.LLM, manifest.LLMModel = config.LLMProvider, config.LLMModel
+	manifest.BurnSubtitles = config.BurnSubtitles
+	manifest.SubtitleStyle = nil
+	if config.SubtitleStyle != (common.SubtitleStyle{}) {
+		style := config.SubtitleStyle
+		manifest.SubtitleStyle = &style
+	}
+	if err := manifest.Save(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
 	}
-	defer pdfProc.Close()
 
-	text, err := pdfProc.ExtractText()
+	finalPath := filepath.Join(config.OutputDir, "video", "final_video.mp4")
+	if manifest.Done("final") {
+		log.Printf("Video already complete, nothing to resume: %s", finalPath)
+		progress.Stage(6, "concat")
+		return nil
+	}
+
+	// 1. Processing PDF (Text & Images)
+	log.Println("Step 1: Processing PDF...")
+	progress.Stage(1, "pdf")
+	text, doc, err := common.LoadPaper(ctx, config)
 	if err != nil {
-		return fmt.Errorf("text extraction failed: %w", err)
+		return err
 	}
 	log.Printf("Extracted %d chars of text", len(text))
 
+	// LaTeX sources give exact metadata and the original figures, PDFs
+	// have theirs cropped by the layout model
+	var sectionFigures map[string]common.DocFigure
+	if doc != nil {
+		if config.Metadata == nil {
+			config.Metadata = doc.Metadata()
+		}
+		sectionFigures = doc.SectionFigures()
+		log.Printf("Using LaTeX source: %d sections, %d figures for slides", len(doc.Sections), len(sectionFigures))
+	} else {
+		sectionFigures, err = extractSectionFigures(ctx, config, manifest, progress)
+		if err != nil {
+			return err
+		}
+		log.Printf("Matched %d figures to slides", len(sectionFigures))
+	}
+
 	if text == "" {
 		return fmt.Errorf("no text extracted")
 	}
 
-	// 2. Gemini: Script Generation
-	log.Println("Step 2: Generating Script with Gemini...")
-	gemini, err := common.NewGeminiClient(config.GeminiKey)
+	// 2. LLM: Script Generation
+	log.Println("Step 2: Generating Script with LLM...")
+	progress.Stage(2, "script")
+	llm, err := common.NewLLM(config)
 	if err != nil {
-		return fmt.Errorf("gemini init failed: %w", err)
+		return fmt.Errorf("llm init failed: %w", err)
 	}
-	defer gemini.Close()
+	defer llm.Close()
 
 	// Extract paper metadata (title and authors)
-	log.Println("Extracting paper metadata...")
-	paperMetadata, err := gemini.ExtractMetadata(text)
-	if err != nil {
-		log.Printf("Warning: metadata extraction failed: %v, using defaults", err)
+	metadataPath := filepath.Join(config.OutputDir, "metadata.json")
+	paperMetadata := &common.PaperMetadata{}
+	if manifest.Done("metadata") && common.ReadJSON(metadataPath, paperMetadata) == nil {
+		log.Println("Resuming: reusing extracted metadata")
+	} else {
+		if config.Metadata != nil {
+			log.Println("Using known paper metadata")
+			paperMetadata = config.Metadata
+		} else {
+			log.Println("Extracting paper metadata...")
+			paperMetadata, err = llm.ExtractMetadata(ctx, text)
+		}
+		if err != nil {
+			log.Printf("Warning: metadata extraction failed: %v, using defaults", err)
+		} else if err := common.WriteJSON(metadataPath, paperMetadata); err == nil {
+			manifest.Checkpoint("metadata", metadataPath)
+		}
+		// The title slide shows the metadata
+		manifest.Invalidate("slides", "segment:", "final")
 	}
 	log.Printf("Paper Title: %s", paperMetadata.Title)
 	log.Printf("Paper Authors: %s", paperMetadata.Authors)
 
-	fullScript, err := gemini.GenerateScript(text)
-	if err != nil {
-		return fmt.Errorf("script generation failed: %w", err)
+	scriptPath := filepath.Join(config.OutputDir, "script.txt")
+	var fullScript string
+	if manifest.Done("script") {
+		log.Println("Resuming: reusing generated script")
+		data, err := os.ReadFile(scriptPath)
+		if err != nil {
+			return fmt.Errorf("failed to read script: %w", err)
+		}
+		fullScript = string(data)
+	} else {
+		condensed, err := llm.CondensePaper(ctx, text)
+		if err != nil {
+			return fmt.Errorf("paper condensation failed: %w", err)
+		}
+		fullScript, err = llm.GenerateScript(ctx, condensed)
+		if err != nil {
+			return fmt.Errorf("script generation failed: %w", err)
+		}
+		if err := os.WriteFile(scriptPath, []byte(fullScript), 0644); err == nil {
+			manifest.Checkpoint("script", scriptPath)
+		}
+		manifest.Invalidate("bullets", "audio:", "slides", "segment:", "final")
 	}
-	os.WriteFile(filepath.Join(config.OutputDir, "script.txt"), []byte(fullScript), 0644)
 
 	// Parse Script into Sections
 	sections := common.ParseScriptToSections(fullScript)
 
 	// 3. Generate Bullet Points (Parallelized)
 	log.Println("Step 3: Generating Bullet Points (Parallel)...")
-	var bulletWg sync.WaitGroup
-	var sectionMutex sync.Mutex
-
-	for name, data := range sections {
-		bulletWg.Add(1)
-		go func(n string, d common.SectionData) {
-			defer bulletWg.Done()
-
-			bullets, err := gemini.GenerateBulletPoints(d.Script)
-			if err != nil {
-				log.Printf("Bullet gen failed for %s: %v", n, err)
-				bullets = []string{"Key points unavailable"}
-			}
+	progress.Stage(3, "bullets")
+	bulletsPath := filepath.Join(config.OutputDir, "bullets.json")
+	var resumedSections map[string]common.SectionData
+	if manifest.Done("bullets") && common.ReadJSON(bulletsPath, &resumedSections) == nil {
+		log.Println("Resuming: reusing bullet points")
+		sections = resumedSections
+		progress.Item("sections", len(sections), len(sections))
+	} else {
+		var bulletWg sync.WaitGroup
+		var sectionMutex sync.Mutex
+		var bulletsErr error // a timeout or cancellation fails the job rather than leaving placeholders
+		bulletsDone := 0
+
+		for name, data := range sections {
+			bulletWg.Add(1)
+			go func(n string, d common.SectionData) {
+				defer bulletWg.Done()
+
+				bullets, err := llm.GenerateBulletPoints(ctx, d.Script)
+				if err != nil {
+					log.Printf("Bullet gen failed for %s: %v", n, err)
+					bullets = []string{"Key points unavailable"}
+				}
+
+				sectionMutex.Lock()
+				if err != nil && (common.IsTimeout(err) || ctx.Err() != nil) && bulletsErr == nil {
+					bulletsErr = err
+				}
+				sections[n] = common.SectionData{
+					Title:   n,
+					Script:  d.Script,
+					Bullets: bullets,
+				}
+				bulletsDone++
+				progress.Item("sections", bulletsDone, len(sections))
+				sectionMutex.Unlock()
+			}(name, data)
+		}
+		bulletWg.Wait()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if bulletsErr != nil {
+			return bulletsErr
+		}
 
-			sectionMutex.Lock()
-			sections[n] = common.SectionData{
-				Title:   n,
-				Script:  d.Script,
-				Bullets: bullets,
-				Image:   "",
-			}
-			sectionMutex.Unlock()
-		}(name, data)
+		if err := common.WriteJSON(bulletsPath, sections); err == nil {
+			manifest.Checkpoint("bullets", bulletsPath)
+		}
+		manifest.Invalidate("slides", "segment:", "final")
+	}
+
+	// Figures are matched apart from the bullets, so a resumed run picks up
+	// newly extracted ones
+	for name, d := range sections {
+		d.Image, d.Caption = sectionFigures[name].Path, sectionFigures[name].Caption
+		sections[name] = d
 	}
-	bulletWg.Wait()
 
 	// 4. Parallel Asset Generation (Slides & Audio)
 	log.Println("Step 4: Generating Assets (Slides & Audio)...")
+	progress.Stage(4, "assets")
 
+	settings := config.Settings.OrDefault()
 	slideGen := NewSlideGenerator(filepath.Join(config.OutputDir, "slides"))
-	sarvam := NewSarvamClient(config.SarvamKey)
+	slideGen.Font = common.LanguageFonts[common.NormalizeLanguage(config.Language)]
+	slideGen.Timeout = settings.Timeouts.SlideCompile
+	tts, err := common.NewTTSProvider(config)
+	if err != nil {
+		return fmt.Errorf("tts init failed: %w", err)
+	}
 	videoGen := NewVideoGenerator(filepath.Join(config.OutputDir, "video"))
+	videoGen.Resolution = settings.Video.Resolution
+	videoGen.Timeout = settings.Timeouts.SegmentEncode
 	os.MkdirAll(videoGen.OutputDir, 0755)
 
 	type AssetResult struct {
@@ -107,22 +230,30 @@ func ProcessVideoPipeline(config common.PipelineConfig) error {
 
 	var titleSlide string
 	var sectionSlides map[string][]string
+	var slidesErr error
 
 	var assetWg sync.WaitGroup
 
 	// A. Slides
-	assetWg.Add(1)
-	go func() {
-		defer assetWg.Done()
-		var err error
-		// Use extracted paper metadata for title slide
-		titleSlide, sectionSlides, _, err = slideGen.GenerateSlides(paperMetadata.Title, paperMetadata.Title, paperMetadata.Authors, sections)
-		if err != nil {
-			log.Printf("Slide generation failed: %v", err)
-		} else {
+	slidesPath := filepath.Join(slideGen.OutputDir, "slides.json")
+	if set, ok := loadSlideSet(manifest, slidesPath, slideGen.OutputDir); ok {
+		log.Println("Resuming: reusing rendered slides")
+		titleSlide, sectionSlides = set.Title, set.Sections
+	} else {
+		assetWg.Add(1)
+		go func() {
+			defer assetWg.Done()
+			// Use extracted paper metadata for title slide
+			titleSlide, sectionSlides, _, slidesErr = slideGen.GenerateSlides(ctx, paperMetadata.Title, paperMetadata.Title, paperMetadata.Authors, sections)
+			if slidesErr != nil {
+				log.Printf("Slide generation failed: %v", slidesErr)
+				return
+			}
 			log.Println("Slides generated.")
-		}
-	}()
+			saveSlideSet(manifest, slidesPath, slideGen.OutputDir, titleSlide, sectionSlides)
+			manifest.Invalidate("segment:", "final")
+		}()
+	}
 
 	// B. Audio (Parallel per section)
 	sem := make(chan struct{}, 5)
@@ -143,10 +274,26 @@ func ProcessVideoPipeline(config common.PipelineConfig) error {
 		audioWg.Add(1)
 		go func(n string, s string) {
 			defer audioWg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				audioResults <- AssetResult{Name: n, Err: ctx.Err()}
+				return
+			}
+
+			stage := "audio:" + n
+			if manifest.Done(stage) {
+				audioResults <- AssetResult{Name: n, AudioPath: filepath.Join(config.OutputDir, "audio", n+".wav")}
+				return
+			}
 
-			path, err := sarvam.GenerateAudio(s, filepath.Join(config.OutputDir, "audio"), n, "English")
+			path := filepath.Join(config.OutputDir, "audio", n+".wav")
+			err := tts.Synthesize(ctx, s, path, common.TTSOptions{Voice: settings.Video.Voice, Language: common.GetLanguageCode(config.Language)})
+			if err == nil {
+				manifest.Checkpoint(stage, path)
+				manifest.Invalidate("segment:"+n, "final")
+			}
 			audioResults <- AssetResult{Name: n, AudioPath: path, Err: err}
 		}(name, data.Script)
 	}
@@ -157,10 +304,23 @@ func ProcessVideoPipeline(config common.PipelineConfig) error {
 	}()
 
 	// Collect Audio
+	audioTotal := 0
+	for _, name := range common.SectionOrder() {
+		if _, ok := sections[name]; ok {
+			audioTotal++
+		}
+	}
+	audioDone := 0
 	audioMap := make(map[string]string)
+	var timeoutErr error // a stage timeout fails the job rather than dropping a section
 	for res := range audioResults {
+		audioDone++
+		progress.Item("audio", audioDone, audioTotal)
 		if res.Err != nil {
 			log.Printf("Audio gen failed for %s: %v", res.Name, res.Err)
+			if common.IsTimeout(res.Err) && timeoutErr == nil {
+				timeoutErr = res.Err
+			}
 		} else {
 			audioMap[res.Name] = res.AudioPath
 		}
@@ -168,6 +328,15 @@ func ProcessVideoPipeline(config common.PipelineConfig) error {
 
 	// Wait for slides
 	assetWg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if timeoutErr != nil {
+		return timeoutErr
+	}
+	if common.IsTimeout(slidesErr) {
+		return slidesErr
+	}
 
 	if sectionSlides == nil {
 		return fmt.Errorf("slides failed to generate, cannot proceed to video")
@@ -175,20 +344,44 @@ func ProcessVideoPipeline(config common.PipelineConfig) error {
 
 	// 5. Combine into Segments (Parallel)
 	log.Println("Step 5: Creating Video Segments...")
+	progress.Stage(5, "segments")
 
 	segmentMap := make(map[int]string)
 	var segMutex sync.Mutex
 	var segWg sync.WaitGroup
+	segTotal, segDone := 0, 0
 
-	processSegment := func(index int, imgs []string, audio string, segName string) {
+	processSegment := func(index int, section string, imgs []string, audio string, segName string) {
 		defer segWg.Done()
-		segPath, err := videoGen.CreateSegment(imgs, audio, segName)
+		stage := "segment:" + section
+		segPath := filepath.Join(videoGen.OutputDir, segName)
+		var err error
+		if !manifest.Done(stage) {
+			segPath, err = videoGen.CreateSegment(ctx, imgs, audio, segName)
+			if err == nil {
+				manifest.Checkpoint(stage, segPath)
+			}
+		}
+		segMutex.Lock()
 		if err == nil {
-			segMutex.Lock()
 			segmentMap[index] = segPath
-			segMutex.Unlock()
 		} else {
 			log.Printf("Failed to create segment %s: %v", segName, err)
+			if common.IsTimeout(err) && timeoutErr == nil {
+				timeoutErr = err
+			}
+		}
+		segDone++
+		progress.Item("segments", segDone, segTotal)
+		segMutex.Unlock()
+	}
+
+	// Count segments up front so per-item progress has a stable total
+	for _, name := range common.SectionOrder() {
+		_, haveAudio := audioMap[name]
+		_, haveSlides := sectionSlides[name]
+		if haveAudio && (haveSlides || name == "Introduction") {
+			segTotal++
 		}
 	}
 
@@ -199,7 +392,7 @@ func ProcessVideoPipeline(config common.PipelineConfig) error {
 			imgs = append(imgs, sSlides...)
 		}
 		segWg.Add(1)
-		go processSegment(0, imgs, introAudio, "01_intro_seg.mp4")
+		go processSegment(0, "Introduction", imgs, introAudio, "01_intro_seg.mp4")
 	}
 
 	// Other sections
@@ -215,30 +408,99 @@ func ProcessVideoPipeline(config common.PipelineConfig) error {
 			segName := fmt.Sprintf("%02d_%s_seg.mp4", i+1, strings.ToLower(name))
 			segIdx := i
 			segWg.Add(1)
-			go processSegment(segIdx, slides, audioPath, segName)
+			go processSegment(segIdx, name, slides, audioPath, segName)
 		}
 	}
 
 	segWg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if timeoutErr != nil {
+		return timeoutErr
+	}
 
 	// 6. Final Concat
 	log.Println("Step 6: Final Concatenation...")
+	progress.Stage(6, "concat")
 	var segments []string
+	var narration []common.SubtitleSegment
 	for i := 0; i < len(sectionOrder); i++ {
-		if path, ok := segmentMap[i]; ok {
-			segments = append(segments, path)
+		path, ok := segmentMap[i]
+		if !ok {
+			continue
 		}
+		segments = append(segments, path)
+
+		// Segments last as long as their narration
+		name := sectionOrder[i]
+		duration, err := getAudioDuration(ctx, audioMap[name])
+		if err != nil {
+			log.Printf("Warning: no duration for %s audio, subtitles will drift: %v", name, err)
+		}
+		narration = append(narration, common.SubtitleSegment{Text: sections[name].Script, Duration: duration})
 	}
 
 	if len(segments) == 0 {
 		return fmt.Errorf("no video segments created")
 	}
 
-	finalVideo, err := videoGen.ConcatSegments(segments, "final_video.mp4")
+	finalVideo, err := videoGen.ConcatSegments(ctx, segments, "final_video.mp4")
 	if err != nil {
 		return fmt.Errorf("final video creation failed: %w", err)
 	}
+	style := config.SubtitleStyle.ForLanguage(config.Language)
+	subtitles, err := common.CreateSubtitles(ctx, finalVideo, narration, config.BurnSubtitles, style, settings.Timeouts.SegmentEncode)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if common.IsTimeout(err) {
+			return fmt.Errorf("subtitle burn-in failed: %w", err)
+		}
+		log.Printf("Warning: subtitles failed: %v", err)
+	}
+	manifest.Checkpoint("final", append([]string{finalVideo}, subtitles...)...)
 
 	log.Printf("Video Pipeline Complete! Video: %s", finalVideo)
 	return nil
 }
+
+// slideSet is the checkpointed slide mapping, stored with paths relative to the slides dir
+type slideSet struct {
+	Title    string              `json:"title"`
+	Sections map[string][]string `json:"sections"`
+}
+
+func loadSlideSet(manifest *common.Manifest, path, slidesDir string) (*slideSet, bool) {
+	var set slideSet
+	if !manifest.Done("slides") || common.ReadJSON(path, &set) != nil {
+		return nil, false
+	}
+
+	set.Title = filepath.Join(slidesDir, set.Title)
+	for name, imgs := range set.Sections {
+		for i, img := range imgs {
+			imgs[i] = filepath.Join(slidesDir, img)
+		}
+		set.Sections[name] = imgs
+	}
+	return &set, true
+}
+
+func saveSlideSet(manifest *common.Manifest, path, slidesDir, titleSlide string, sectionSlides map[string][]string) {
+	set := slideSet{Title: filepath.Base(titleSlide), Sections: make(map[string][]string)}
+	artifacts := []string{path, titleSlide}
+	for name, imgs := range sectionSlides {
+		for _, img := range imgs {
+			set.Sections[name] = append(set.Sections[name], filepath.Base(img))
+			artifacts = append(artifacts, img)
+		}
+	}
+
+	if err := common.WriteJSON(path, set); err != nil {
+		log.Printf("Warning: failed to save slide mapping: %v", err)
+		return
+	}
+	manifest.Checkpoint("slides", artifacts...)
+}
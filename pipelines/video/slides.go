@@ -1,11 +1,13 @@
 package video
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"saral_go_testing/common"
 
@@ -14,13 +16,15 @@ import (
 
 type SlideGenerator struct {
 	OutputDir string
+	Font      string        // Optional, sets the slides in this font with XeLaTeX, for non-Latin scripts
+	Timeout   time.Duration // Optional, bounds each LaTeX run
 }
 
 func NewSlideGenerator(outputDir string) *SlideGenerator {
 	return &SlideGenerator{OutputDir: outputDir}
 }
 
-func (s *SlideGenerator) GenerateSlides(paperID string, title string, authors string, sections map[string]common.SectionData) (string, map[string][]string, string, error) {
+func (s *SlideGenerator) GenerateSlides(ctx context.Context, paperID string, title string, authors string, sections map[string]common.SectionData) (string, map[string][]string, string, error) {
 	// 1. Generate LaTeX
 	latexContent := s.generateLatex(title, authors, sections)
 
@@ -35,7 +39,7 @@ func (s *SlideGenerator) GenerateSlides(paperID string, title string, authors st
 	}
 
 	// 3. Compile
-	pdfPath, err := s.compileLatex(texFile)
+	pdfPath, err := s.compileLatex(ctx, texFile)
 	if err != nil {
 		return "", nil, "", err
 	}
@@ -54,7 +58,7 @@ func (s *SlideGenerator) generateLatex(title string, author string, sections map
 \usecolortheme{whale}
 \usepackage{graphicx}
 \usepackage{ragged2e}
-
+` + s.fontSetup() + `
 \title{` + common.EscapeLatex(title) + `}
 \author{` + common.EscapeLatex(author) + `}
 \date{\today}
@@ -89,7 +93,12 @@ func (s *SlideGenerator) generateLatex(title string, author string, sections map
 			sb.WriteString("\\begin{frame}{" + name + " - Visualization}\n")
 			sb.WriteString("\\begin{center}\n")
 			absImg, _ := filepath.Abs(data.Image)
-			sb.WriteString(fmt.Sprintf("\\includegraphics[width=0.8\\textwidth,height=0.8\\textheight,keepaspectratio]{%s}\n", absImg))
+			if data.Caption == "" {
+				sb.WriteString(fmt.Sprintf("\\includegraphics[width=0.8\\textwidth,height=0.8\\textheight,keepaspectratio]{%s}\n", absImg))
+			} else {
+				sb.WriteString(fmt.Sprintf("\\includegraphics[width=0.8\\textwidth,height=0.65\\textheight,keepaspectratio]{%s}\n", absImg))
+				sb.WriteString("\\\\[0.5em]{\\footnotesize " + common.EscapeLatex(data.Caption) + "}\n")
+			}
 			sb.WriteString("\\end{center}\n")
 			sb.WriteString("\\end{frame}\n")
 		}
@@ -99,12 +108,34 @@ func (s *SlideGenerator) generateLatex(title string, author string, sections map
 	return sb.String()
 }
 
-func (s *SlideGenerator) compileLatex(texFile string) (string, error) {
-	cmd := exec.Command("pdflatex", "-interaction=nonstopmode", "-output-directory", s.OutputDir, texFile)
-	output, err := cmd.CombinedOutput()
+// fontSetup selects s.Font for the slide text, which needs XeLaTeX
+func (s *SlideGenerator) fontSetup() string {
+	if s.Font == "" {
+		return ""
+	}
+	return `\usepackage{fontspec}
+\setmainfont{` + s.Font + `}
+\setsansfont{` + s.Font + `}
+`
+}
+
+func (s *SlideGenerator) compileLatex(ctx context.Context, texFile string) (string, error) {
+	engine := "pdflatex"
+	if s.Font != "" {
+		engine = "xelatex"
+	}
+	output, err := common.CommandOutput(ctx, common.StageCompile, s.Timeout, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, engine, "-interaction=nonstopmode", "-output-directory", s.OutputDir, texFile)
+	})
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if common.IsTimeout(err) {
+		return "", err
+	}
 	if err != nil {
-		fmt.Printf("pdflatex output: %s\n", string(output))
-		return "", fmt.Errorf("pdflatex failed: %w", err)
+		fmt.Printf("%s output: %s\n", engine, string(output))
+		return "", fmt.Errorf("%s failed: %w", engine, err)
 	}
 
 	baseName := strings.TrimSuffix(filepath.Base(texFile), ".tex")
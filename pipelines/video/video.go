@@ -1,27 +1,39 @@
 package video
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+
+	"saral_go_testing/common"
 )
 
 type VideoGenerator struct {
-	OutputDir string
+	OutputDir  string
+	Resolution common.Resolution // Optional, frame size (1920x1080 if zero)
+	Timeout    time.Duration     // Optional, bounds each ffmpeg run
 }
 
 // Global semaphore to limit concurrent ffmpeg processes
-var ffmpegSem = make(chan struct{}, 4)
+var ffmpegSem = make(chan struct{}, common.DefaultSettings().Video.FFmpegConcurrency)
+
+// SetFFmpegConcurrency sets how many segments may be encoded at once across
+// all pipelines. Call it at startup, before any job runs.
+func SetFFmpegConcurrency(n int) {
+	ffmpegSem = make(chan struct{}, n)
+}
 
 func NewVideoGenerator(outputDir string) *VideoGenerator {
 	return &VideoGenerator{OutputDir: outputDir}
 }
 
 // CreateSegment creates a video file from a list of images and one audio file.
-func (v *VideoGenerator) CreateSegment(images []string, audioPath, outputName string) (string, error) {
+func (v *VideoGenerator) CreateSegment(ctx context.Context, images []string, audioPath, outputName string) (string, error) {
 	if len(images) == 0 {
 		return "", fmt.Errorf("no images for segment")
 	}
@@ -29,7 +41,7 @@ func (v *VideoGenerator) CreateSegment(images []string, audioPath, outputName st
 	outputPath := filepath.Join(v.OutputDir, outputName)
 
 	// 1. Get Audio Duration
-	duration, err := getAudioDuration(audioPath)
+	duration, err := getAudioDuration(ctx, audioPath)
 	if err != nil {
 		return "", err
 	}
@@ -53,22 +65,38 @@ func (v *VideoGenerator) CreateSegment(images []string, audioPath, outputName st
 
 	// 4. FFmpeg command
 	// Acquire semaphore
-	ffmpegSem <- struct{}{}
-	defer func() { <-ffmpegSem }()
-
-	cmd := exec.Command("ffmpeg",
-		"-y",
-		"-f", "concat", "-safe", "0", "-i", demuxerPath,
-		"-i", audioPath,
-		"-c:v", "libx264",
-		"-pix_fmt", "yuv420p",
-		"-vf", "scale=1920:1080:force_original_aspect_ratio=decrease,pad=1920:1080:(ow-iw)/2:(oh-ih)/2",
-		"-c:a", "aac",
-		"-shortest",
-		outputPath,
-	)
+	select {
+	case ffmpegSem <- struct{}{}:
+		defer func() { <-ffmpegSem }()
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
 
-	output, err := cmd.CombinedOutput()
+	res := v.Resolution
+	if res.Width == 0 || res.Height == 0 {
+		res = common.DefaultSettings().Video.Resolution
+	}
+	scale := fmt.Sprintf("scale=%[1]d:%[2]d:force_original_aspect_ratio=decrease,pad=%[1]d:%[2]d:(ow-iw)/2:(oh-ih)/2", res.Width, res.Height)
+
+	output, err := common.CommandOutput(ctx, common.StageEncode, v.Timeout, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "ffmpeg",
+			"-y",
+			"-f", "concat", "-safe", "0", "-i", demuxerPath,
+			"-i", audioPath,
+			"-c:v", "libx264",
+			"-pix_fmt", "yuv420p",
+			"-vf", scale,
+			"-c:a", "aac",
+			"-shortest",
+			outputPath,
+		)
+	})
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if common.IsTimeout(err) {
+		return "", err
+	}
 	if err != nil {
 		return "", fmt.Errorf("ffmpeg video creation failed: %s, output: %s", err, string(output))
 	}
@@ -76,7 +104,7 @@ func (v *VideoGenerator) CreateSegment(images []string, audioPath, outputName st
 	return outputPath, nil
 }
 
-func (v *VideoGenerator) ConcatSegments(segments []string, finalOutputName string) (string, error) {
+func (v *VideoGenerator) ConcatSegments(ctx context.Context, segments []string, finalOutputName string) (string, error) {
 	if len(segments) == 0 {
 		return "", fmt.Errorf("no segments to concat")
 	}
@@ -92,14 +120,20 @@ func (v *VideoGenerator) ConcatSegments(segments []string, finalOutputName strin
 	listPath := filepath.Join(v.OutputDir, "concat_list.txt")
 	os.WriteFile(listPath, []byte(listContent), 0644)
 
-	cmd := exec.Command("ffmpeg",
-		"-y",
-		"-f", "concat", "-safe", "0", "-i", listPath,
-		"-c", "copy",
-		outputPath,
-	)
-
-	output, err := cmd.CombinedOutput()
+	output, err := common.CommandOutput(ctx, common.StageEncode, v.Timeout, func(ctx context.Context) *exec.Cmd {
+		return exec.CommandContext(ctx, "ffmpeg",
+			"-y",
+			"-f", "concat", "-safe", "0", "-i", listPath,
+			"-c", "copy",
+			outputPath,
+		)
+	})
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if common.IsTimeout(err) {
+		return "", err
+	}
 	if err != nil {
 		return "", fmt.Errorf("ffmpeg concat failed: %s, output: %s", err, string(output))
 	}
@@ -107,8 +141,8 @@ func (v *VideoGenerator) ConcatSegments(segments []string, finalOutputName strin
 	return outputPath, nil
 }
 
-func getAudioDuration(path string) (float64, error) {
-	cmd := exec.Command("ffprobe",
+func getAudioDuration(ctx context.Context, path string) (float64, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe",
 		"-v", "error",
 		"-show_entries", "format=duration",
 		"-of", "default=noprint_wrappers=1:nokey=1",
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+
+	"saral_go_testing/common"
+	"saral_go_testing/pipelines/reel"
+)
+
+// errNotInReview is returned for review actions on a reel that isn't paused
+// for review
+var errNotInReview = errors.New("reel is not awaiting review")
+
+// isReviewStatus reports whether a reel is paused after its dialogue, waiting
+// for edits and a render request
+func isReviewStatus(status string) bool {
+	return status == "script_ready" || status == "script_edited" || status == "avatars_selected"
+}
+
+// syncReelStatus mirrors a reel job's status into its reel status file, with
+// the generated dialogue once it's ready and the video once it's rendered.
+// Callers hold p.mu.
+func (p *WorkerPool) syncReelStatus(job *JobStatus) {
+	if p.reelJobs == nil || job.Mode != "reel" {
+		return
+	}
+	status := &reel.ReelJobStatus{
+		PaperID:      job.ID,
+		Status:       job.Status,
+		Language:     job.Language,
+		Filename:     filepath.Base(job.input()),
+		SourceType:   job.SourceType,
+		ErrorMessage: job.Error,
+		CreatedAt:    job.StartedAt,
+		CompletedAt:  job.DoneAt,
+	}
+	if job.Progress != nil {
+		status.Stage = job.Progress.Stage
+	}
+
+	switch job.Status {
+	case "script_ready":
+		status.Stage = "dialogue"
+		if turns, err := reel.LoadDialogue(job.OutputDir); err == nil {
+			status.ScriptData = &reel.ReelScript{
+				OriginalDialogue: reel.FormatDialogue(turns),
+				ParsedScript:     turns,
+			}
+		}
+		var metadata reel.PaperMetadata
+		if common.ReadJSON(filepath.Join(job.OutputDir, "metadata.json"), &metadata) == nil {
+			status.Metadata = &metadata
+		}
+	case "completed":
+		if manifest, err := common.LoadManifest(job.OutputDir); err == nil {
+			if final := manifest.Stages["final"]; len(final.Artifacts) > 0 {
+				status.VideoPath = final.Artifacts[0].Path
+			}
+		}
+	}
+
+	if err := p.reelJobs.UpdateStatus(status); err != nil {
+		log.Printf("[Job %s] Failed to save reel status: %v", job.ID, err)
+	}
+}
+
+// UpdateReview applies an edit to a reel paused for review and moves it to
+// status. update runs under the pool lock, so it cannot race a render.
+func (p *WorkerPool) UpdateReview(jobID, status string, update func(job *JobStatus, review *reel.ReelJobStatus) error) (*JobStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job, err := p.store.Get(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if !isReviewStatus(job.Status) {
+		return job, fmt.Errorf("%w, job is %s", errNotInReview, job.Status)
+	}
+	review, err := p.reelJobs.GetStatus(jobID)
+	if err != nil {
+		review = &reel.ReelJobStatus{PaperID: jobID}
+	}
+	if err := update(job, review); err != nil {
+		return job, err
+	}
+
+	job.Status = status
+	review.Status = status
+	if err := p.reelJobs.UpdateStatus(review); err != nil {
+		return nil, err
+	}
+	if err := p.store.Save(job); err != nil {
+		return nil, err
+	}
+	p.events.Publish(JobEvent{Type: "status", JobID: jobID, Status: job})
+	return job, nil
+}
+
+// Render queues a reel paused for review. The pipeline resumes after the
+// dialogue, regenerating only the audio of edited turns.
+func (p *WorkerPool) Render(jobID string, buildJob func(status *JobStatus) *Job) (string, error) {
+	p.mu.Lock()
+	status, err := p.store.Get(jobID)
+	if err != nil {
+		p.mu.Unlock()
+		return "", err
+	}
+	if !isReviewStatus(status.Status) {
+		p.mu.Unlock()
+		return status.Status, fmt.Errorf("%w, job is %s", errNotInReview, status.Status)
+	}
+
+	status.Status = "queued"
+	status.ReviewScript = false
+	status.Progress = nil
+	if err := p.store.Save(status); err != nil {
+		p.mu.Unlock()
+		return "", err
+	}
+	p.syncReelStatus(status)
+	job := buildJob(status)
+	p.mu.Unlock()
+	p.events.Publish(JobEvent{Type: "status", JobID: jobID, Status: status})
+
+	if err := p.enqueue(job); err != nil {
+		return "", err
+	}
+	return status.Status, nil
+}
+
+// reelJob looks up a reel job for the review endpoints, writing a 404 if
+// there is none
+func (s *Server) reelJob(w http.ResponseWriter, r *http.Request) (*JobStatus, bool) {
+	status, ok := s.pool.GetStatus(r.PathValue("id"))
+	if !ok || status.Mode != "reel" {
+		http.Error(w, "Reel job not found", http.StatusNotFound)
+		return nil, false
+	}
+	return status, true
+}
+
+// writeReviewError maps errors of review actions to HTTP statuses
+func writeReviewError(w http.ResponseWriter, err error) {
+	switch {
+	case err == ErrJobNotFound:
+		http.Error(w, "Reel job not found", http.StatusNotFound)
+	case errors.Is(err, errNotInReview):
+		http.Error(w, "Cannot change reel: "+err.Error(), http.StatusConflict)
+	case err == ErrPoolClosed:
+		http.Error(w, "Cannot render reel: "+err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, "Failed to update reel: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleGetReelScript returns the generated dialogue of a reel and any edits
+func (s *Server) handleGetReelScript(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.reelJob(w, r)
+	if !ok {
+		return
+	}
+	review, err := s.pool.reelJobs.GetStatus(status.ID)
+	if err != nil || review.ScriptData == nil {
+		http.Error(w, "Dialogue not generated yet, job is "+status.Status, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review.ScriptData)
+}
+
+// handlePutReelScript replaces the dialogue of a reel awaiting review
+func (s *Server) handlePutReelScript(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.reelJob(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Turns []reel.DialogueTurn `json:"turns"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := reel.ValidateDialogue(body.Turns); err != nil {
+		http.Error(w, "Invalid dialogue: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, err := s.pool.UpdateReview(status.ID, "script_edited", func(job *JobStatus, review *reel.ReelJobStatus) error {
+		if err := reel.SaveDialogue(job.OutputDir, body.Turns); err != nil {
+			return err
+		}
+		if review.ScriptData == nil {
+			review.ScriptData = &reel.ReelScript{}
+		}
+		review.ScriptData.EditedScript = body.Turns
+		return nil
+	})
+	if err != nil {
+		writeReviewError(w, err)
+		return
+	}
+
+	log.Printf("[Job %s] Dialogue edited (%d turns)", status.ID, len(body.Turns))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job_id": status.ID,
+		"status": status.Status,
+		"turns":  len(body.Turns),
+	})
+}
+
+// handlePutReelAvatars selects the avatar pair of a reel awaiting review
+func (s *Server) handlePutReelAvatars(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.reelJob(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		AvatarPair string `json:"avatar_pair"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(s.avatars) == 0 {
+		http.Error(w, "No reel avatar pairs loaded", http.StatusInternalServerError)
+		return
+	}
+	pair := reel.GetAvatarPairByID(s.avatars, body.AvatarPair)
+	if body.AvatarPair == "" || pair == nil {
+		http.Error(w, fmt.Sprintf("Invalid avatar_pair: unknown pair %q, see GET /avatars", body.AvatarPair), http.StatusBadRequest)
+		return
+	}
+
+	status, err := s.pool.UpdateReview(status.ID, "avatars_selected", func(job *JobStatus, review *reel.ReelJobStatus) error {
+		job.AvatarPair = pair.ID
+		review.AvatarSelection = &reel.AvatarSelection{
+			AvatarPairID: pair.ID,
+			MaleAvatar:   pair.MaleAvatar,
+			FemaleAvatar: pair.FemaleAvatar,
+		}
+		return nil
+	})
+	if err != nil {
+		writeReviewError(w, err)
+		return
+	}
+
+	log.Printf("[Job %s] Avatar pair %s selected", status.ID, pair.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"job_id":      status.ID,
+		"status":      status.Status,
+		"avatar_pair": pair.ID,
+	})
+}
+
+// handleRenderReel continues a reel awaiting review with the edited dialogue
+// and the selected avatars
+func (s *Server) handleRenderReel(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.reelJob(w, r)
+	if !ok {
+		return
+	}
+
+	newStatus, err := s.pool.Render(status.ID, s.jobFromStatus)
+	if err != nil {
+		writeReviewError(w, err)
+		return
+	}
+
+	log.Printf("[Job %s] Render requested", status.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"job_id": status.ID,
+		"status": newStatus,
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"saral_go_testing/common"
+	"saral_go_testing/pipelines/reel"
+)
+
+func TestReelReview(t *testing.T) {
+	dir := t.TempDir()
+	outputDir := filepath.Join(dir, "output")
+	pdfPath := filepath.Join(dir, "paper.pdf")
+
+	// A reel whose pipeline stopped at its dialogue
+	dialogue := []reel.DialogueTurn{
+		{Character: "Person1", Dialogue: "What is this paper about?"},
+		{Character: "Person2", Dialogue: "Faster sorting."},
+	}
+	manifest, _ := common.LoadManifest(outputDir)
+	dialoguePath := filepath.Join(outputDir, "dialogue.json")
+	common.WriteJSON(dialoguePath, dialogue)
+	manifest.Checkpoint("dialogue", dialoguePath)
+	manifest.Checkpoint("audio:00")
+	manifest.Checkpoint("audio:01")
+
+	store := NewMemoryJobStore()
+	store.Save(&JobStatus{ID: "r", Status: "processing", Mode: "reel", PDFPath: pdfPath, OutputDir: outputDir, ReviewScript: true})
+	store.Save(&JobStatus{ID: "v", Status: "script_ready", Mode: "video", PDFPath: pdfPath, OutputDir: outputDir})
+
+	pool := NewWorkerPool(0, 10, store)
+	pool.reelJobs = reel.NewJobStatusManager(filepath.Join(dir, "reel_jobs"))
+	pool.updateStatus("r", "script_ready", "")
+
+	s := &Server{pool: pool, avatars: []reel.AvatarPair{{ID: "a"}, {ID: "b", MaleAvatar: "b_m.png"}}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /reel/{id}/script", s.handleGetReelScript)
+	mux.HandleFunc("PUT /reel/{id}/script", s.handlePutReelScript)
+	mux.HandleFunc("PUT /reel/{id}/avatars", s.handlePutReelAvatars)
+	mux.HandleFunc("POST /reel/{id}/render", s.handleRenderReel)
+
+	do := func(method, path, body string, wantCode int) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if w.Code != wantCode {
+			t.Fatalf("%s %s: got %d (%s), want %d", method, path, w.Code, w.Body, wantCode)
+		}
+		return w.Body.String()
+	}
+
+	if body := do("GET", "/reel/r/script", "", 200); !strings.Contains(body, "Person2: Faster sorting.") {
+		t.Errorf("script lacks the generated dialogue: %s", body)
+	}
+	do("GET", "/reel/v/script", "", 404)
+	do("PUT", "/reel/r/script", `{"turns":[{"character":"Person3","dialogue":"Hi"}]}`, 400)
+	do("PUT", "/reel/r/script", `{"turns":[{"character":"Person1","dialogue":"What is this paper about?"},{"character":"Person2","dialogue":"Sorting, twice as fast."}]}`, 200)
+	avatars := s.avatars
+	s.avatars = nil // the registry failed to load, which isn't the client's fault
+	do("PUT", "/reel/r/avatars", `{"avatar_pair":"b"}`, 500)
+	s.avatars = avatars
+	do("PUT", "/reel/r/avatars", `{"avatar_pair":"nobody"}`, 400)
+	do("PUT", "/reel/r/avatars", `{"avatar_pair":"b"}`, 200)
+
+	manifest, _ = common.LoadManifest(outputDir)
+	if !manifest.Done("audio:00") || manifest.Done("audio:01") {
+		t.Errorf("only the edited turn's audio should be invalidated: %+v", manifest.Stages)
+	}
+	review, err := pool.reelJobs.GetStatus("r")
+	if err != nil || review.Status != "avatars_selected" || len(review.ScriptData.EditedScript) != 2 ||
+		review.ScriptData.ParsedScript[1].Dialogue != "Faster sorting." || review.AvatarSelection.MaleAvatar != "b_m.png" {
+		t.Errorf("unexpected reel status %+v (%v)", review, err)
+	}
+
+	if body := do("POST", "/reel/r/render", "", 200); !strings.Contains(body, "queued") {
+		t.Errorf("render not queued: %s", body)
+	}
+	select {
+	case job := <-pool.jobs:
+		if job.Config.ReviewScript || job.Config.AvatarPairID != "b" {
+			t.Errorf("render job should skip review with pair b, got %+v", job.Config)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("render was not queued")
+	}
+	do("PUT", "/reel/r/script", `{"turns":[{"character":"Person1","dialogue":"Too late"}]}`, 409)
+	do("POST", "/reel/r/render", "", 409)
+}
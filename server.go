@@ -3,52 +3,98 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"sync"
+	"syscall"
 	"time"
 
 	"saral_go_testing/common"
+	"saral_go_testing/common/layout"
 	"saral_go_testing/pipelines/poster"
 	"saral_go_testing/pipelines/reel"
 	"saral_go_testing/pipelines/video"
+
+	"github.com/gorilla/websocket"
 )
 
 type JobStatus struct {
-	ID        string     `json:"id"`
-	Status    string     `json:"status"`
-	Mode      string     `json:"mode"`
-	OutputDir string     `json:"output_dir,omitempty"`
-	Error     string     `json:"error,omitempty"`
-	StartedAt time.Time  `json:"started_at"`
-	DoneAt    *time.Time `json:"done_at,omitempty"`
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Mode       string     `json:"mode"`
+	LLM        string     `json:"llm,omitempty"`
+	LLMModel   string     `json:"llm_model,omitempty"`
+	TTS        string     `json:"tts,omitempty"`
+	Language   string     `json:"language,omitempty"`
+	PDFPath    string     `json:"pdf_path,omitempty"`
+	ArxivID    string     `json:"arxiv_id,omitempty"`
+	SourceType string     `json:"source_type,omitempty"`
+	SourcePath string     `json:"source_path,omitempty"` // LaTeX source file or archive
+	OutputDir  string     `json:"output_dir,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	DoneAt     *time.Time `json:"done_at,omitempty"`
+
+	BurnSubtitles bool                  `json:"burn_subtitles,omitempty"`
+	SubtitleStyle *common.SubtitleStyle `json:"subtitle_style,omitempty"`
+	AvatarPair    string                `json:"avatar_pair,omitempty"`   // reel only
+	ReviewScript  bool                  `json:"review_script,omitempty"` // reel only, pause at script_ready
+
+	Options  *JobOptions           `json:"options,omitempty"`  // From the upload, reapplied on retry and render
+	Metadata *common.PaperMetadata `json:"metadata,omitempty"` // Known before the run, e.g. from arXiv
+	Progress *common.ProgressEvent `json:"progress,omitempty"`
 }
 
+// input is the file the job reads the paper from
+func (s *JobStatus) input() string {
+	if s.PDFPath == "" {
+		return s.SourcePath
+	}
+	return s.PDFPath
+}
+
+// ErrPoolClosed is returned for jobs queued after the pool shut down. Their
+// status stays queued, so they are recovered on the next start.
+var ErrPoolClosed = errors.New("server is shutting down")
+
 type WorkerPool struct {
 	jobs       chan *Job
-	results    map[string]*JobStatus
-	mu         sync.RWMutex
+	done       chan struct{} // closed by Shutdown
+	closeOnce  sync.Once
+	store      JobStore
+	cancels    map[string]context.CancelFunc
+	events     *EventBroker
+	reelJobs   *reel.JobStatusManager // Optional, mirrors reel jobs for review
+	mu         sync.Mutex
 	wg         sync.WaitGroup
 	numWorkers int
 }
 
 type Job struct {
 	ID        string
+	ArxivID   string // Set for papers fetched from arXiv
 	PDFPath   string
 	OutputDir string
 	Mode      string
+	Options   *JobOptions // Optional, already applied to Config
 	Config    common.PipelineConfig
 }
 
-func NewWorkerPool(numWorkers int, bufferSize int) *WorkerPool {
+func NewWorkerPool(numWorkers int, bufferSize int, store JobStore) *WorkerPool {
 	pool := &WorkerPool{
 		jobs:       make(chan *Job, bufferSize),
-		results:    make(map[string]*JobStatus),
+		done:       make(chan struct{}),
+		store:      store,
+		cancels:    make(map[string]context.CancelFunc),
+		events:     NewEventBroker(),
 		numWorkers: numWorkers,
 	}
 	pool.Start()
@@ -65,102 +111,489 @@ func (p *WorkerPool) Start() {
 
 func (p *WorkerPool) worker(id int) {
 	defer p.wg.Done()
-	for job := range p.jobs {
-		log.Printf("[Worker %d] Processing job %s (mode: %s)", id, job.ID, job.Mode)
-		p.processJob(job)
+	for {
+		select {
+		case job := <-p.jobs:
+			p.logf(job.ID, "[Worker %d] Processing job %s (mode: %s)", id, job.ID, job.Mode)
+			p.processJob(job)
+		case <-p.done:
+			log.Printf("[Worker %d] Shutting down", id)
+			return
+		}
+	}
+}
+
+// enqueue hands a job to the workers, waiting for room in the queue, unless
+// the pool has shut down
+func (p *WorkerPool) enqueue(job *Job) error {
+	select {
+	case <-p.done:
+		return ErrPoolClosed
+	default:
+	}
+	select {
+	case p.jobs <- job:
+		return nil
+	case <-p.done:
+		return ErrPoolClosed
 	}
-	log.Printf("[Worker %d] Shutting down", id)
 }
 
 func (p *WorkerPool) processJob(job *Job) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Register the cancel func and flip to processing atomically, so a job
+	// cancelled while still queued is skipped here
+	p.mu.Lock()
+	status, err := p.store.Get(job.ID)
+	if err == nil && status.Status == "cancelled" {
+		p.mu.Unlock()
+		p.logf(job.ID, "[Job %s] Skipping cancelled job", job.ID)
+		return
+	}
+	p.cancels[job.ID] = cancel
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.cancels, job.ID)
+		p.mu.Unlock()
+	}()
+
 	p.updateStatus(job.ID, "processing", "")
 
-	var err error
+	job.Config.Progress = func(e common.ProgressEvent) {
+		p.updateProgress(job.ID, e)
+	}
+
 	switch job.Mode {
 	case "video":
-		err = video.ProcessVideoPipeline(job.Config)
+		err = common.RunJob(ctx, job.Config, video.ProcessVideoPipeline)
 	case "poster":
-		err = poster.ProcessPosterPipeline(job.Config)
+		err = common.RunJob(ctx, job.Config, poster.ProcessPosterPipeline)
 	case "reel":
-		err = reel.ProcessReelPipeline(job.Config)
+		err = common.RunJob(ctx, job.Config, reel.ProcessReelPipeline)
 	default:
 		err = fmt.Errorf("unknown mode: %s", job.Mode)
 	}
 
-	if err != nil {
+	if err != nil && ctx.Err() == context.Canceled {
+		p.logf(job.ID, "[Job %s] Cancelled", job.ID)
+		p.updateStatus(job.ID, "cancelled", "")
+	} else if errors.Is(err, reel.ErrScriptReady) {
+		p.logf(job.ID, "[Job %s] Dialogue ready for review", job.ID)
+		p.updateStatus(job.ID, "script_ready", "")
+	} else if err != nil {
+		p.logf(job.ID, "[Job %s] Failed: %v", job.ID, err)
 		p.updateStatus(job.ID, "failed", err.Error())
-		log.Printf("[Job %s] Failed: %v", job.ID, err)
 	} else {
+		p.logf(job.ID, "[Job %s] Completed successfully", job.ID)
 		p.updateStatus(job.ID, "completed", "")
-		log.Printf("[Job %s] Completed successfully", job.ID)
 	}
 }
 
 func (p *WorkerPool) updateStatus(jobID, status, errMsg string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if job, exists := p.results[jobID]; exists {
-		job.Status = status
-		job.Error = errMsg
-		if status == "completed" || status == "failed" {
-			now := time.Now()
-			job.DoneAt = &now
+	job, err := p.store.Get(jobID)
+	if err != nil {
+		log.Printf("[Job %s] Failed to load status: %v", jobID, err)
+		return
+	}
+	job.Status = status
+	job.Error = errMsg
+	if isTerminalStatus(status) {
+		now := time.Now()
+		job.DoneAt = &now
+	}
+	if err := p.store.Save(job); err != nil {
+		log.Printf("[Job %s] Failed to save status: %v", jobID, err)
+	}
+	p.syncReelStatus(job)
+	p.events.Publish(JobEvent{Type: "status", JobID: jobID, Status: job})
+}
+
+func (p *WorkerPool) updateProgress(jobID string, event common.ProgressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	job, err := p.store.Get(jobID)
+	if err != nil {
+		return
+	}
+	job.Progress = &event
+	if err := p.store.Save(job); err != nil {
+		log.Printf("[Job %s] Failed to save progress: %v", jobID, err)
+	}
+	p.events.Publish(JobEvent{Type: "progress", JobID: jobID, Progress: &event})
+}
+
+// logf writes a log line and streams it to subscribers of the job
+func (p *WorkerPool) logf(jobID, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Print(msg)
+	p.events.Publish(JobEvent{Type: "log", JobID: jobID, Message: msg})
+}
+
+func (p *WorkerPool) Submit(job *Job) error {
+	status := &JobStatus{
+		ID:         job.ID,
+		Status:     "queued",
+		Mode:       job.Mode,
+		LLM:        job.Config.LLMProvider,
+		LLMModel:   job.Config.LLMModel,
+		TTS:        job.Config.TTSProvider,
+		Language:   job.Config.Language,
+		PDFPath:    job.PDFPath,
+		ArxivID:    job.ArxivID,
+		SourceType: job.Config.SourceType,
+		SourcePath: job.Config.SourcePath,
+		OutputDir:  job.OutputDir,
+		StartedAt:  time.Now(),
+		Options:    job.Options,
+		Metadata:   job.Config.Metadata,
+
+		BurnSubtitles: job.Config.BurnSubtitles,
+		AvatarPair:    job.Config.AvatarPairID,
+		ReviewScript:  job.Config.ReviewScript,
+	}
+	if job.Config.SubtitleStyle != (common.SubtitleStyle{}) {
+		style := job.Config.SubtitleStyle
+		status.SubtitleStyle = &style
+	}
+	p.mu.Lock()
+	err := p.store.Save(status)
+	p.syncReelStatus(status)
+	p.mu.Unlock()
+	p.events.Publish(JobEvent{Type: "status", JobID: job.ID, Status: status})
+	if err != nil {
+		log.Printf("[Job %s] Failed to save status: %v", job.ID, err)
+	}
+
+	return p.enqueue(job)
+}
+
+// Requeue puts a previously persisted job back on the queue, keeping its history
+func (p *WorkerPool) Requeue(job *Job) error {
+	p.updateStatus(job.ID, "queued", "")
+	return p.enqueue(job)
+}
+
+// Recover re-queues jobs that were queued or processing when the server stopped
+func (p *WorkerPool) Recover(buildJob func(status *JobStatus) *Job) int {
+	statuses, err := p.store.List()
+	if err != nil {
+		log.Printf("Failed to list jobs for recovery: %v", err)
+		return 0
+	}
+
+	var pending []*Job
+	for _, status := range statuses {
+		if status.Status != "queued" && status.Status != "processing" {
+			continue
+		}
+		if _, err := os.Stat(status.input()); err != nil {
+			p.updateStatus(status.ID, "failed", "input file missing after restart")
+			continue
 		}
+		pending = append(pending, buildJob(status))
+	}
+
+	// Enqueue in the background so a full queue cannot block startup
+	go func() {
+		for _, job := range pending {
+			p.logf(job.ID, "[Job %s] Recovered after restart, re-queuing", job.ID)
+			if err := p.Requeue(job); err != nil {
+				return
+			}
+		}
+	}()
+	return len(pending)
+}
+
+// Cancel stops a queued or running job. Queued jobs are marked cancelled
+// immediately; running jobs have their context cancelled, which kills any
+// child processes, and are marked cancelled once the pipeline returns.
+func (p *WorkerPool) Cancel(jobID string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status, err := p.store.Get(jobID)
+	if err != nil {
+		return "", err
+	}
+
+	if cancel, running := p.cancels[jobID]; running {
+		cancel()
+		return "cancelling", nil
+	}
+
+	if isTerminalStatus(status.Status) {
+		return status.Status, fmt.Errorf("job already %s", status.Status)
+	}
+
+	now := time.Now()
+	status.Status = "cancelled"
+	status.DoneAt = &now
+	if err := p.store.Save(status); err != nil {
+		return "", err
 	}
+	p.events.Publish(JobEvent{Type: "status", JobID: jobID, Status: status})
+	return status.Status, nil
 }
 
-func (p *WorkerPool) Submit(job *Job) {
+// Retry re-queues a failed or cancelled job into its original output dir, so
+// the pipeline resumes from the stages checkpointed in its manifest
+func (p *WorkerPool) Retry(job *Job) (string, error) {
 	p.mu.Lock()
-	p.results[job.ID] = &JobStatus{
-		ID:        job.ID,
-		Status:    "queued",
-		Mode:      job.Mode,
-		OutputDir: job.OutputDir,
-		StartedAt: time.Now(),
+	status, err := p.store.Get(job.ID)
+	if err != nil {
+		p.mu.Unlock()
+		return "", err
+	}
+	if status.Status != "failed" && status.Status != "cancelled" {
+		p.mu.Unlock()
+		return status.Status, fmt.Errorf("only failed or cancelled jobs can be retried, job is %s", status.Status)
 	}
+	if _, err := os.Stat(status.input()); err != nil {
+		p.mu.Unlock()
+		return status.Status, fmt.Errorf("input file no longer available")
+	}
+
+	status.Status = "queued"
+	status.Error = ""
+	status.DoneAt = nil
+	status.Progress = nil
+	err = p.store.Save(status)
 	p.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	p.events.Publish(JobEvent{Type: "status", JobID: job.ID, Status: status})
+
+	if err := p.enqueue(job); err != nil {
+		return "", err
+	}
+	return status.Status, nil
+}
 
-	p.jobs <- job
+func isTerminalStatus(status string) bool {
+	return status == "completed" || status == "failed" || status == "cancelled"
 }
 
 func (p *WorkerPool) GetStatus(jobID string) (*JobStatus, bool) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	status, ok := p.results[jobID]
-	return status, ok
+	status, err := p.store.Get(jobID)
+	if err != nil {
+		if err != ErrJobNotFound {
+			log.Printf("[Job %s] Failed to load status: %v", jobID, err)
+		}
+		return nil, false
+	}
+	return status, true
 }
 
+// Shutdown stops the workers once their running jobs finish and closes the
+// store. Jobs still queued keep their status and are recovered on restart.
 func (p *WorkerPool) Shutdown() {
-	close(p.jobs)
+	p.closeOnce.Do(func() { close(p.done) })
 	p.wg.Wait()
+	p.store.Close()
+}
+
+// ServerOptions configures StartServer
+type ServerOptions struct {
+	Addr         string
+	Workers      int
+	JobStore     string // "file", "sqlite" or "memory"
+	JobStorePath string
+	Cache        *common.Cache // Optional, shared by all jobs
+
+	// Default LLM for jobs that don't choose one; its model is Settings.LLM.Model
+	LLMProvider   string
+	OpenAIBaseURL string
+	TTSProvider   string // Default TTS for jobs that don't choose one
+	Language      string // Default narration language for jobs that don't choose one
+	ArxivBaseURL  string // Optional, for a local arXiv stand-in
+
+	// Optional, browser origins besides the server's own that may open
+	// WebSocket event streams, e.g. "https://app.example.com" ("*" for any)
+	AllowedOrigins []string
+
+	TitleCard common.TitleCard // Optional, look of every reel's title background
+	Settings  common.Settings  // Knobs of every job, from LoadSettings
 }
 
 type Server struct {
 	pool      *WorkerPool
 	geminiKey string
 	sarvamKey string
+	openAIKey string
 	uploadDir string
+	cache     *common.Cache
+	arxiv     *common.ArxivClient
+
+	llmProvider   string
+	llmModel      string
+	openAIBaseURL string
+	ttsProvider   string
+	language      string
+	titleCard     common.TitleCard
+	settings      common.Settings
+	avatars       []reel.AvatarPair // from the assets' avatar registry
+
+	allowedOrigins []string
 }
 
-func NewServer(numWorkers int) *Server {
-	if err := common.LoadEnv(".env"); err != nil {
-		log.Println("No .env file found")
+func NewServer(opts ServerOptions) *Server {
+	if opts.LLMProvider == "" {
+		opts.LLMProvider = common.LLMGemini
+	}
+	if opts.TTSProvider == "" {
+		opts.TTSProvider = common.TTSSarvam
+	}
+	if err := common.ValidateLanguage(opts.Language); err != nil {
+		log.Fatal(err)
 	}
-
 	geminiKey := os.Getenv("GEMINI_API_KEY")
-	if geminiKey == "" {
+	if geminiKey == "" && opts.LLMProvider == common.LLMGemini {
 		log.Fatal("GEMINI_API_KEY not set")
 	}
 
 	uploadDir := "./uploads"
 	os.MkdirAll(uploadDir, 0755)
 
-	return &Server{
-		pool:      NewWorkerPool(numWorkers, 100),
+	store, err := NewJobStore(opts.JobStore, opts.JobStorePath)
+	if err != nil {
+		log.Fatalf("Failed to open job store: %v", err)
+	}
+
+	// Every upload may ask for a reel, so a broken registry stops startup
+	avatars, err := reel.LoadAvatarPairs("./assets")
+	if err != nil {
+		log.Fatalf("Failed to load reel avatars: %v", err)
+	}
+
+	pool := NewWorkerPool(opts.Workers, 100, store)
+	pool.reelJobs = reel.NewJobStatusManager("./reel_jobs")
+
+	server := &Server{
+		pool:      pool,
 		geminiKey: geminiKey,
 		sarvamKey: os.Getenv("SARVAM_API_KEY"),
+		openAIKey: os.Getenv("OPENAI_API_KEY"),
 		uploadDir: uploadDir,
+		cache:     opts.Cache,
+		arxiv:     common.NewArxivClient(opts.ArxivBaseURL),
+
+		llmProvider:   opts.LLMProvider,
+		llmModel:      opts.Settings.LLM.Model,
+		openAIBaseURL: opts.OpenAIBaseURL,
+		ttsProvider:   opts.TTSProvider,
+		language:      common.NormalizeLanguage(opts.Language),
+		titleCard:     opts.TitleCard,
+		settings:      opts.Settings,
+		avatars:       avatars,
+
+		allowedOrigins: opts.AllowedOrigins,
 	}
+
+	if n := server.pool.Recover(server.jobFromStatus); n > 0 {
+		log.Printf("Recovered %d unfinished jobs", n)
+	}
+
+	return server
+}
+
+// pipelineConfig builds the config for a job with the server's keys and defaults
+func (s *Server) pipelineConfig(pdfPath, outputDir, mode string) common.PipelineConfig {
+	return common.PipelineConfig{
+		PDFPath:       pdfPath,
+		OutputDir:     outputDir,
+		GeminiKey:     s.geminiKey,
+		SarvamKey:     s.sarvamKey,
+		OpenAIKey:     s.openAIKey,
+		Mode:          mode,
+		LLMProvider:   s.llmProvider,
+		LLMModel:      s.llmModel,
+		OpenAIBaseURL: s.openAIBaseURL,
+		TTSProvider:   s.ttsProvider,
+		Language:      s.language,
+		TitleCard:     s.titleCard,
+		Settings:      &s.settings,
+		LayoutModel:   layout.Load,
+		Cache:         s.cache,
+	}
+}
+
+// jobFromStatus rebuilds a runnable job from its persisted status
+func (s *Server) jobFromStatus(status *JobStatus) *Job {
+	config := s.pipelineConfig(status.PDFPath, status.OutputDir, status.Mode)
+	if status.LLM != "" {
+		config.LLMProvider = status.LLM
+		config.LLMModel = status.LLMModel
+	}
+	if status.TTS != "" {
+		config.TTSProvider = status.TTS
+	}
+	if status.Language != "" {
+		config.Language = status.Language
+	}
+	config.BurnSubtitles = status.BurnSubtitles
+	config.AvatarPairID = status.AvatarPair
+	config.ReviewScript = status.ReviewScript
+	if status.SubtitleStyle != nil {
+		config.SubtitleStyle = *status.SubtitleStyle
+	}
+	config.SourceType = status.SourceType
+	config.SourcePath = status.SourcePath
+	config.Metadata = status.Metadata
+	if status.Options != nil {
+		status.Options.applyTo(&config)
+	}
+	return &Job{
+		ID:        status.ID,
+		ArxivID:   status.ArxivID,
+		PDFPath:   status.PDFPath,
+		OutputDir: status.OutputDir,
+		Mode:      status.Mode,
+		Options:   status.Options,
+		Config:    config,
+	}
+}
+
+// checkLLM reports whether the server can run jobs with the given provider
+func (s *Server) checkLLM(provider string) error {
+	switch provider {
+	case common.LLMGemini:
+		if s.geminiKey == "" {
+			return fmt.Errorf("GEMINI_API_KEY not configured")
+		}
+	case common.LLMOpenAI:
+		if s.openAIKey == "" && s.openAIBaseURL == "" {
+			return fmt.Errorf("OPENAI_API_KEY not configured")
+		}
+	case common.LLMFake:
+	default:
+		return fmt.Errorf("unknown llm %q, use 'gemini', 'openai' or 'fake'", provider)
+	}
+	return nil
+}
+
+// checkTTS reports whether the server can run jobs with the given TTS provider
+func (s *Server) checkTTS(provider string) error {
+	switch provider {
+	case common.TTSSarvam:
+		if s.sarvamKey == "" {
+			return fmt.Errorf("SARVAM_API_KEY not configured")
+		}
+	case common.TTSEspeak:
+		if _, err := common.NewEspeakTTS(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown tts %q, use 'sarvam' or 'espeak'", provider)
+	}
+	return nil
 }
 
 func (s *Server) handlePDFUpload(w http.ResponseWriter, r *http.Request) {
@@ -185,65 +618,248 @@ func (s *Server) handlePDFUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if (mode == "video" || mode == "reel") && s.sarvamKey == "" {
-		http.Error(w, "SARVAM_API_KEY not configured for "+mode+" mode", http.StatusInternalServerError)
+	r.ParseMultipartForm(100 << 20)
+
+	// Every invalid option and form field is reported at once
+	var errs []FieldError
+	invalid := func(field, problem string) {
+		errs = append(errs, FieldError{Field: field, Message: problem})
+	}
+
+	tts := r.FormValue("tts")
+	if mode == "video" || mode == "reel" {
+		if tts == "" {
+			// The server's own default is misconfigured, not the request
+			if err := s.checkTTS(s.ttsProvider); err != nil {
+				http.Error(w, err.Error()+" for "+mode+" mode", http.StatusInternalServerError)
+				return
+			}
+		} else if err := s.checkTTS(tts); err != nil {
+			invalid("tts", err.Error())
+		}
+	}
+	if tts == "" {
+		tts = s.ttsProvider
+	}
+	if mode == "reel" && len(s.avatars) == 0 {
+		http.Error(w, "No reel avatar pairs loaded", http.StatusInternalServerError)
 		return
 	}
 
-	r.ParseMultipartForm(100 << 20)
+	// Per-job options, which win over the plain form fields they overlap
+	options, errs := s.jobOptions(r, mode, tts)
 
-	file, header, err := r.FormFile("pdf")
-	if err != nil {
-		http.Error(w, "Failed to get PDF file: "+err.Error(), http.StatusBadRequest)
-		return
+	llm := r.FormValue("llm")
+	if llm == "" {
+		llm = s.llmProvider
+	}
+	if err := s.checkLLM(llm); err != nil {
+		invalid("llm", err.Error())
 	}
-	defer file.Close()
 
-	if filepath.Ext(header.Filename) != ".pdf" {
-		http.Error(w, "Only PDF files are accepted", http.StatusBadRequest)
+	language := r.FormValue("language")
+	if options.Language != "" {
+		language = options.Language
+	}
+	if language == "" {
+		language = s.language
+	}
+	if err := common.ValidateLanguage(language); err != nil {
+		invalid("language", err.Error())
+	}
+
+	var burnSubtitles bool
+	if v := r.FormValue("burn_subtitles"); v != "" {
+		var err error
+		if burnSubtitles, err = strconv.ParseBool(v); err != nil {
+			invalid("burn_subtitles", "expected true or false")
+		}
+	}
+	if options.BurnSubtitles != nil {
+		burnSubtitles = *options.BurnSubtitles
+	}
+	var subtitleStyle common.SubtitleStyle
+	if v := r.FormValue("subtitle_style"); v != "" {
+		var err error
+		if subtitleStyle, err = common.ParseSubtitleStyle(v); err != nil {
+			invalid("subtitle_style", err.Error())
+		}
+	}
+
+	avatarPair := r.FormValue("avatar_pair")
+	if options.AvatarPair != "" {
+		avatarPair = options.AvatarPair
+	}
+	if mode == "reel" && options.AvatarPair == "" && reel.GetAvatarPairByID(s.avatars, avatarPair) == nil {
+		invalid("avatar_pair", fmt.Sprintf("unknown pair %q, see GET /avatars", avatarPair))
+	}
+	var review bool
+	if v := r.FormValue("review"); v != "" {
+		var err error
+		if review, err = strconv.ParseBool(v); err != nil || (review && mode != "reel") {
+			invalid("review", "expected true or false, for reels only")
+		}
+	}
+	if len(errs) > 0 {
+		writeFieldErrors(w, errs)
 		return
 	}
 
 	jobID := fmt.Sprintf("%d", time.Now().UnixNano())
-	pdfPath := filepath.Join(s.uploadDir, jobID+"_"+header.Filename)
 	outputDir := "./output/output_" + jobID
 
-	dst, err := os.Create(pdfPath)
-	if err != nil {
-		http.Error(w, "Failed to save file: "+err.Error(), http.StatusInternalServerError)
-		return
+	var pdfPath, sourcePath string
+	var paper *common.ArxivPaper
+	if input := r.FormValue("arxiv"); input != "" {
+		id, ok := common.ParseArxivID(input)
+		if !ok {
+			http.Error(w, "Invalid arxiv: expected an arXiv ID or arxiv.org URL", http.StatusBadRequest)
+			return
+		}
+		withSource, _ := strconv.ParseBool(r.FormValue("arxiv_source"))
+
+		var err error
+		paper, err = s.arxiv.Fetch(r.Context(), id, s.uploadDir, jobID+"_", withSource)
+		if err != nil {
+			http.Error(w, "Failed to fetch from arXiv: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		pdfPath, sourcePath = paper.PDFPath, paper.SourcePath
+	} else if file, header, err := r.FormFile("latex"); err == nil {
+		defer file.Close()
+
+		if !common.IsLatexSource(header.Filename) {
+			http.Error(w, "Only .tex, .zip and .tar.gz LaTeX sources are accepted", http.StatusBadRequest)
+			return
+		}
+
+		// A directory of its own, so a lone .tex cannot reach other uploads' files
+		sourcePath = filepath.Join(s.uploadDir, jobID, filepath.Base(header.Filename))
+		if err := saveUpload(sourcePath, file); err != nil {
+			http.Error(w, "Failed to save file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		file, header, err := r.FormFile("pdf")
+		if err != nil {
+			http.Error(w, "Failed to get PDF file: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		if filepath.Ext(header.Filename) != ".pdf" {
+			http.Error(w, "Only PDF files are accepted", http.StatusBadRequest)
+			return
+		}
+
+		pdfPath = filepath.Join(s.uploadDir, jobID+"_"+header.Filename)
+		if err := saveUpload(pdfPath, file); err != nil {
+			http.Error(w, "Failed to save file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, file); err != nil {
-		http.Error(w, "Failed to save file: "+err.Error(), http.StatusInternalServerError)
-		return
+	config := s.pipelineConfig(pdfPath, outputDir, mode)
+	if llm != config.LLMProvider {
+		// The server's default model belongs to its default provider
+		config.LLMProvider = llm
+		config.LLMModel = ""
+	}
+	if model := r.FormValue("llm_model"); model != "" {
+		config.LLMModel = model
 	}
+	config.TTSProvider = tts
+	config.Language = common.NormalizeLanguage(language)
+	config.BurnSubtitles = burnSubtitles
+	config.SubtitleStyle = subtitleStyle
+	config.AvatarPairID = avatarPair
+	config.ReviewScript = review
+	if sourcePath != "" {
+		config.SourceType = common.SourceLatex
+		config.SourcePath = sourcePath
+	}
+	if paper != nil {
+		config.Metadata = paper.Metadata
+	}
+	options.applyTo(&config)
 
 	job := &Job{
 		ID:        jobID,
 		PDFPath:   pdfPath,
 		OutputDir: outputDir,
 		Mode:      mode,
-		Config: common.PipelineConfig{
-			PDFPath:   pdfPath,
-			OutputDir: outputDir,
-			GeminiKey: s.geminiKey,
-			SarvamKey: s.sarvamKey,
-			Mode:      mode,
-		},
+		Config:    config,
+	}
+	if *options != (JobOptions{}) {
+		job.Options = options
 	}
 
-	s.pool.Submit(job)
+	message := "PDF uploaded and queued for processing"
+	if paper == nil && sourcePath != "" {
+		message = "LaTeX source uploaded and queued for processing"
+	}
+	if paper != nil {
+		job.ArxivID = paper.ID
+		message = "arXiv " + paper.ID + " fetched and queued for processing"
+	}
+
+	if err := s.pool.Submit(job); err != nil {
+		http.Error(w, "Cannot queue job: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"job_id":  jobID,
 		"status":  "queued",
-		"message": "PDF uploaded and queued for processing",
+		"message": message,
+	})
+}
+
+// jobOptions reads and validates the "options" form field of an upload. If
+// any field is invalid, the options are empty and errs says why.
+func (s *Server) jobOptions(r *http.Request, mode, tts string) (options *JobOptions, errs []FieldError) {
+	v := r.FormValue("options")
+	if v == "" {
+		return &JobOptions{}, nil
+	}
+	options, errs = parseJobOptions(v)
+	if errs == nil {
+		errs = options.validate(mode, tts, s.avatars)
+	}
+	if len(errs) > 0 {
+		return &JobOptions{}, errs
+	}
+	return options, nil
+}
+
+// writeFieldErrors rejects a request with the problems of each invalid
+// option or form field
+func writeFieldErrors(w http.ResponseWriter, errs []FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  "Invalid options",
+		"fields": errs,
 	})
 }
 
+// saveUpload writes an uploaded file to path, creating its directory
+func saveUpload(path string, file io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, file); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	jobID := r.URL.Query().Get("id")
 	if jobID == "" {
@@ -261,6 +877,142 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(status)
 }
 
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if jobID == "" {
+		jobID = r.URL.Query().Get("id")
+	}
+	if jobID == "" {
+		http.Error(w, "Missing job id", http.StatusBadRequest)
+		return
+	}
+
+	status, err := s.pool.Cancel(jobID)
+	if err == ErrJobNotFound {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil && status == "" {
+		http.Error(w, "Failed to cancel job: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Cannot cancel job: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	log.Printf("[Job %s] Cancel requested (%s)", jobID, status)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"job_id": jobID,
+		"status": status,
+	})
+}
+
+func (s *Server) handleRetry(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	status, ok := s.pool.GetStatus(jobID)
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	newStatus, err := s.pool.Retry(s.jobFromStatus(status))
+	if err == ErrJobNotFound {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if err == ErrPoolClosed {
+		http.Error(w, "Cannot retry job: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil && newStatus == "" {
+		http.Error(w, "Failed to retry job: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Cannot retry job: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	log.Printf("[Job %s] Retry requested, resuming in %s", jobID, status.OutputDir)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"job_id": jobID,
+		"status": newStatus,
+	})
+}
+
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+
+	// Subscribe before reading the current status so no transition is missed
+	events, unsubscribe := s.pool.events.Subscribe(jobID)
+	defer unsubscribe()
+
+	status, ok := s.pool.GetStatus(jobID)
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	var stream eventStream
+	var err error
+	if websocket.IsWebSocketUpgrade(r) {
+		stream, err = newWebSocketStream(w, r, s.allowedOrigins)
+	} else {
+		stream, err = newSSEStream(w, r)
+	}
+	if err != nil {
+		log.Printf("[Job %s] Failed to open event stream: %v", jobID, err)
+		return
+	}
+	defer stream.Close()
+
+	if err := stream.Send(JobEvent{Type: "status", JobID: jobID, Status: status, Time: time.Now()}); err != nil {
+		return
+	}
+	if isTerminalStatus(status.Status) {
+		return
+	}
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-stream.Done():
+			return
+		case event := <-events:
+			if err := stream.Send(event); err != nil {
+				return
+			}
+			if event.Type == "status" && isTerminalStatus(event.Status.Status) {
+				return
+			}
+		case <-heartbeat.C:
+			// A terminal event may have been dropped for a slow client; re-check
+			if status, ok := s.pool.GetStatus(jobID); ok && isTerminalStatus(status.Status) {
+				stream.Send(JobEvent{Type: "status", JobID: jobID, Status: status, Time: time.Now()})
+				return
+			}
+			if err := stream.Ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleAvatars lists the reel avatar pairs jobs can choose with avatar_pair
+func (s *Server) handleAvatars(w http.ResponseWriter, r *http.Request) {
+	pairs := s.avatars
+	if pairs == nil {
+		pairs = []reel.AvatarPair{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"pairs": pairs})
+}
+
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -280,6 +1032,12 @@ func (s *Server) handlePosterDirect(w http.ResponseWriter, r *http.Request) {
 	// Parse multipart form
 	r.ParseMultipartForm(100 << 20)
 
+	options, errs := s.jobOptions(r, "poster", s.ttsProvider)
+	if len(errs) > 0 {
+		writeFieldErrors(w, errs)
+		return
+	}
+
 	file, header, err := r.FormFile("pdf")
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -327,16 +1085,11 @@ func (s *Server) handlePosterDirect(w http.ResponseWriter, r *http.Request) {
 	dst.Close() // Close before processing
 
 	// Process poster pipeline synchronously
-	config := common.PipelineConfig{
-		PDFPath:   pdfPath,
-		OutputDir: outputDir,
-		GeminiKey: s.geminiKey,
-		SarvamKey: s.sarvamKey,
-		Mode:      "poster",
-	}
+	config := s.pipelineConfig(pdfPath, outputDir, "poster")
+	options.applyTo(&config)
 
 	log.Printf("[Direct Poster] Processing %s", header.Filename)
-	err = poster.ProcessPosterPipeline(config)
+	err = poster.ProcessPosterPipeline(r.Context(), config)
 
 	if err != nil {
 		log.Printf("[Direct Poster] Failed: %v", err)
@@ -391,9 +1144,14 @@ func (s *Server) catchAllHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "PDF Processing Server",
-		"status":  "GET /status?id=<job_id>",
-		"health":  "GET /health",
+		"message":   "PDF Processing Server",
+		"status":    "GET /status?id=<job_id>",
+		"cancel":    "DELETE /jobs/<job_id> or POST /cancel?id=<job_id>",
+		"retry":     "POST /jobs/<job_id>/retry",
+		"events":    "GET /jobs/<job_id>/events (SSE or WebSocket)",
+		"artifacts": "GET /jobs/<job_id>/artifacts[/<name>]",
+		"review":    "GET|PUT /reel/<job_id>/script, PUT /reel/<job_id>/avatars, POST /reel/<job_id>/render",
+		"health":    "GET /health",
 	})
 }
 
@@ -401,28 +1159,58 @@ func (s *Server) Shutdown(ctx context.Context) {
 	s.pool.Shutdown()
 }
 
-func StartServer(addr string, numWorkers int) {
-	server := NewServer(numWorkers)
+func StartServer(opts ServerOptions) {
+	server := NewServer(opts)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", server.handleHealth)
 	mux.HandleFunc("/status", server.handleStatus)
+	mux.HandleFunc("GET /avatars", server.handleAvatars)
+	mux.HandleFunc("GET /reel/{id}/script", server.handleGetReelScript)
+	mux.HandleFunc("PUT /reel/{id}/script", server.handlePutReelScript)
+	mux.HandleFunc("PUT /reel/{id}/avatars", server.handlePutReelAvatars)
+	mux.HandleFunc("POST /reel/{id}/render", server.handleRenderReel)
+	mux.HandleFunc("DELETE /jobs/{id}", server.handleCancel)
+	mux.HandleFunc("POST /jobs/{id}/retry", server.handleRetry)
+	mux.HandleFunc("GET /jobs/{id}/events", server.handleJobEvents)
+	mux.HandleFunc("GET /jobs/{id}/artifacts", server.handleListArtifacts)
+	mux.HandleFunc("GET /jobs/{id}/artifacts/{name...}", server.handleDownloadArtifact)
+	mux.HandleFunc("POST /cancel", server.handleCancel)
 	// mux.HandleFunc("/video", server.catchAllHandler)
 	mux.HandleFunc("/poster", server.handlePosterDirect) // Direct PDF response
 	// mux.HandleFunc("/reel", server.catchAllHandler)
 	mux.HandleFunc("/", server.catchAllHandler)
 
 	httpServer := &http.Server{
-		Addr:         addr,
+		Addr:         opts.Addr,
 		Handler:      mux,
 		ReadTimeout:  5 * time.Minute,
 		WriteTimeout: 5 * time.Minute,
 	}
 
-	log.Printf("Server starting on %s with %d workers", addr, numWorkers)
+	// On SIGINT or SIGTERM stop taking requests, let running jobs finish and
+	// close the job store; queued jobs are recovered on the next start
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	idle := make(chan struct{})
+	go func() {
+		defer close(idle)
+		<-ctx.Done()
+		log.Printf("Shutting down, waiting for running jobs...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("HTTP shutdown: %v", err)
+		}
+	}()
+
+	log.Printf("Server starting on %s with %d workers", opts.Addr, opts.Workers)
 	log.Printf("POST to any route with 'pdf' form field and ?mode=video|poster|reel to process")
 
 	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Server failed: %v", err)
 	}
+	<-idle
+	server.pool.Shutdown()
+	log.Printf("Server stopped")
 }